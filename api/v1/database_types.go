@@ -19,18 +19,424 @@ package v1
 import (
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 type DatabaseStorage struct {
 	Size resource.Quantity `json:"size"`
+	// VolumeSnapshotClassName used for VolumeSnapshots of the data volume taken through the
+	// libsql.ahti.io/snapshot annotation. Uses the cluster default class when empty.
+	// +optional
+	VolumeSnapshotClassName *string `json:"volumeSnapshotClassName,omitempty"`
+	// RestoreFromSnapshot is the name of a VolumeSnapshot in the same namespace the data
+	// volume is restored from. Only used when the data volume is first created.
+	// +optional
+	RestoreFromSnapshot string `json:"restoreFromSnapshot,omitempty"`
+	// CloneFrom is the name of a Database in the same namespace whose data is copied into the
+	// data volume when it is first created. The source is copied through a VolumeSnapshot, so
+	// it keeps running undisturbed. Cannot be combined with RestoreFromSnapshot.
+	// +optional
+	CloneFrom string `json:"cloneFrom,omitempty"`
+	// PVCLabels are added to the data volume claim.
+	// The volume claim template of a StatefulSet is immutable, so changes only apply to
+	// Databases created afterwards, or after the StatefulSet is deleted and recreated.
+	// +optional
+	PVCLabels map[string]string `json:"pvcLabels,omitempty"`
+	// PVCAnnotations are added to the data volume claim.
+	// Like PVCLabels, they are only applied when the StatefulSet is created.
+	// +optional
+	PVCAnnotations map[string]string `json:"pvcAnnotations,omitempty"`
+	// StorageClassName of the data volume claim, the cluster default class is used when unset.
+	// Set it to "" to bind a pre-provisioned PersistentVolume without a class.
+	// Like PVCLabels, it is only applied when the StatefulSet is created.
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+	// VolumeName binds the data volume claim to an existing PersistentVolume, e.g. a disk of a
+	// database migrated from a VM. The reclaim policy of the volume is kept as it was provisioned.
+	// Cannot be combined with Selector, RestoreFromSnapshot or CloneFrom.
+	// Like PVCLabels, it is only applied when the StatefulSet is created.
+	// +optional
+	VolumeName string `json:"volumeName,omitempty"`
+	// Selector binds the data volume claim to an existing PersistentVolume with matching labels.
+	// Cannot be combined with VolumeName, RestoreFromSnapshot or CloneFrom.
+	// Like PVCLabels, it is only applied when the StatefulSet is created.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+	// PressureThreshold is the usage of the data volume, in percent of its capacity, from which
+	// the Database gets a StoragePressure condition. The usage is only read, from the kubelet
+	// of the node of the pod every five minutes, when it is set and the operator runs with
+	// --enable-storage-pressure.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	PressureThreshold *int32 `json:"pressureThreshold,omitempty"`
 }
 
 type AhtiDatabaseIngressSpec struct {
 	IngressClassName *string                   `json:"ingressClassName,omitempty" protobuf:"bytes,4,opt,name=ingressClassName"`
 	Host             string                    `json:"host,omitempty" protobuf:"bytes,1,opt,name=host"`
 	TLS              []networkingv1.IngressTLS `json:"tls,omitempty" protobuf:"bytes,2,rep,name=tls"`
+	// Path matched against the path of incoming requests, for example when the
+	// database is served under a subpath of a shared domain.
+	// +kubebuilder:default="/"
+	// +optional
+	Path string `json:"path,omitempty"`
+	// PathType determines the interpretation of the Path matching.
+	// +kubebuilder:default="Prefix"
+	// +kubebuilder:validation:Enum=Exact;Prefix;ImplementationSpecific
+	// +optional
+	PathType *networkingv1.PathType `json:"pathType,omitempty"`
+	// WaitForReady delays the creation of the Ingress until the database is Available, so that
+	// clients do not get errors from a database that is still being provisioned.
+	// +optional
+	WaitForReady bool `json:"waitForReady,omitempty"`
+	// ExternalDNS sets the external-dns annotations of the Ingress.
+	// +optional
+	ExternalDNS *DatabaseExternalDNS `json:"externalDNS,omitempty"`
+	// WebSocketTimeout is how long the Ingress keeps an idle Hrana websocket connection open,
+	// e.g. 1h. ingress-nginx closes them after 60s by default. It is set as the proxy read and
+	// send timeouts of ingress-nginx, other ingress controllers are not supported.
+	// +optional
+	WebSocketTimeout *metav1.Duration `json:"webSocketTimeout,omitempty"`
+	// Annotations of the Ingress, e.g. to configure the ingress controller. Annotations removed
+	// from the spec are removed from the Ingress, those added by others are kept.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// AhtiDatabaseAdditionalIngressSpec is an Ingress of the Database besides spec.ingress, named
+// <name>-ingress-<entry name>.
+type AhtiDatabaseAdditionalIngressSpec struct {
+	// Name of the entry, e.g. internal or external.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name                    string `json:"name"`
+	AhtiDatabaseIngressSpec `json:",inline"`
+}
+
+// DatabaseServiceSpec configures the ClusterIP Service of a Database.
+type DatabaseServiceSpec struct {
+	// NameOverride is used as the name of the Service instead of <name>-svc, e.g. to keep the
+	// service name existing clients are configured with. The headless service keeps its name.
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z]([-a-z0-9]*[a-z0-9])?$`
+	// +optional
+	NameOverride string `json:"nameOverride,omitempty"`
+	// GRPC moves the gRPC port out of the Service into a separate <name>-svc-grpc Service, e.g.
+	// to expose it through a load balancer while the HTTP API stays internal.
+	// +optional
+	GRPC *DatabaseGRPCServiceSpec `json:"grpc,omitempty"`
+	// HTTPAppProtocol is the appProtocol of the HTTP port of the Services, http by default.
+	// Set it to an empty string to leave it unset.
+	// +optional
+	HTTPAppProtocol *string `json:"httpAppProtocol,omitempty"`
+	// GRPCAppProtocol is the appProtocol of the gRPC port of the Services, grpc by default.
+	// Set it to an empty string to leave it unset.
+	// +optional
+	GRPCAppProtocol *string `json:"grpcAppProtocol,omitempty"`
+	// ExternalDNS sets the external-dns annotations of the Service. external-dns only publishes
+	// ClusterIP Services when it runs with --publish-internal-services.
+	// +optional
+	ExternalDNS *DatabaseExternalDNS `json:"externalDNS,omitempty"`
+	// IPFamilies of the Services of the Database, e.g. [IPv6, IPv4] on IPv6 first dual-stack
+	// clusters. The cluster default is used when unset.
+	// More info: https://kubernetes.io/docs/concepts/services-networking/dual-stack/#services
+	// +kubebuilder:validation:MaxItems=2
+	// +listType=atomic
+	// +optional
+	IPFamilies []corev1.IPFamily `json:"ipFamilies,omitempty"`
+	// IPFamilyPolicy of the Services of the Database, SingleStack by default.
+	// +kubebuilder:validation:Enum=SingleStack;PreferDualStack;RequireDualStack
+	// +optional
+	IPFamilyPolicy *corev1.IPFamilyPolicy `json:"ipFamilyPolicy,omitempty"`
+	// PublishNotReadyAddresses makes the headless service resolve the pods before they are ready,
+	// e.g. for replicas to connect to the primary while it starts up. The client facing Service
+	// only routes to ready pods.
+	// +optional
+	PublishNotReadyAddresses bool `json:"publishNotReadyAddresses,omitempty"`
+}
+
+// DatabaseGRPCServiceSpec configures the separate gRPC Service of a Database.
+type DatabaseGRPCServiceSpec struct {
+	// Type of the Service.
+	// +kubebuilder:validation:Enum=ClusterIP;NodePort;LoadBalancer
+	// +kubebuilder:default="ClusterIP"
+	// +optional
+	Type corev1.ServiceType `json:"type,omitempty"`
+	// Annotations of the Service, e.g. to configure the load balancer.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// DatabaseExternalDNS configures the DNS records external-dns creates for a generated resource.
+// More info: https://github.com/kubernetes-sigs/external-dns/blob/master/docs/annotations/annotations.md
+type DatabaseExternalDNS struct {
+	// Hostname is set as the external-dns.alpha.kubernetes.io/hostname annotation.
+	// Multiple hostnames are separated by commas.
+	// +optional
+	Hostname string `json:"hostname,omitempty"`
+	// TTL of the DNS records in seconds, set as the external-dns.alpha.kubernetes.io/ttl annotation.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TTL *int32 `json:"ttl,omitempty"`
+}
+
+// DatabaseProbeType selects how the database container is health checked.
+// +kubebuilder:validation:Enum=HTTP;TCP;Exec
+type DatabaseProbeType string
+
+const (
+	// DatabaseProbeTypeHTTP calls GET /health on the HTTP port.
+	DatabaseProbeTypeHTTP DatabaseProbeType = "HTTP"
+	// DatabaseProbeTypeTCP opens a TCP socket on the HTTP port, for images that do not serve /health.
+	DatabaseProbeTypeTCP DatabaseProbeType = "TCP"
+	// DatabaseProbeTypeExec runs a command inside the database container.
+	DatabaseProbeTypeExec DatabaseProbeType = "Exec"
+)
+
+// DatabaseAntiAffinityScope selects the pods the database pods are kept apart from.
+// +kubebuilder:validation:Enum=Database;AllDatabases;Tenant
+type DatabaseAntiAffinityScope string
+
+const (
+	// DatabaseAntiAffinityScopeDatabase only spreads the pods of the Database itself.
+	DatabaseAntiAffinityScopeDatabase DatabaseAntiAffinityScope = "Database"
+	// DatabaseAntiAffinityScopeAllDatabases avoids the pods of every Database, in all namespaces.
+	DatabaseAntiAffinityScopeAllDatabases DatabaseAntiAffinityScope = "AllDatabases"
+	// DatabaseAntiAffinityScopeTenant avoids the pods of the Databases with the same value of the
+	// tenant label, in all namespaces.
+	DatabaseAntiAffinityScopeTenant DatabaseAntiAffinityScope = "Tenant"
+)
+
+// DatabasePodAntiAffinity configures the anti-affinity of the database pods to other databases.
+type DatabasePodAntiAffinity struct {
+	// Scope of the databases the pods are kept apart from.
+	// +kubebuilder:default="AllDatabases"
+	// +optional
+	Scope DatabaseAntiAffinityScope `json:"scope,omitempty"`
+	// TenantLabel is the label of the Database whose value groups it with the other Databases of
+	// its tenant, required with the Tenant scope. It is copied onto the database pods.
+	// +optional
+	TenantLabel string `json:"tenantLabel,omitempty"`
+	// TopologyKey is the node label of the domain the pods are spread across, defaults to
+	// kubernetes.io/hostname.
+	// +optional
+	TopologyKey string `json:"topologyKey,omitempty"`
+	// Required makes the anti-affinity a hard scheduling constraint, the pods stay pending when
+	// no node is free of other databases. By default it is only preferred.
+	// +optional
+	Required bool `json:"required,omitempty"`
+}
+
+// DatabaseLogLevel is the verbosity of the libsql-server logs.
+// +kubebuilder:validation:Enum=trace;debug;info;warn;error
+type DatabaseLogLevel string
+
+const (
+	DatabaseLogLevelTrace DatabaseLogLevel = "trace"
+	DatabaseLogLevelDebug DatabaseLogLevel = "debug"
+	DatabaseLogLevelInfo  DatabaseLogLevel = "info"
+	DatabaseLogLevelWarn  DatabaseLogLevel = "warn"
+	DatabaseLogLevelError DatabaseLogLevel = "error"
+)
+
+type DatabaseProbe struct {
+	// +kubebuilder:default="HTTP"
+	// +optional
+	Type DatabaseProbeType `json:"type,omitempty"`
+	// Command to run inside the container, required when type is Exec.
+	// +optional
+	Command []string `json:"command,omitempty"`
+	// Port the HTTP and TCP probes check, for images that serve their health endpoint on a
+	// separate management port. Defaults to spec.httpPort.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	Port *int32 `json:"port,omitempty"`
+	// Scheme of the HTTP probe, HTTPS when sqld serves TLS.
+	// +kubebuilder:validation:Enum=HTTP;HTTPS
+	// +kubebuilder:default="HTTP"
+	// +optional
+	Scheme corev1.URIScheme `json:"scheme,omitempty"`
+	// DisableLiveness removes the liveness probe, so that a database replaying a large WAL on
+	// startup is not killed mid-recovery.
+	// +optional
+	DisableLiveness bool `json:"disableLiveness,omitempty"`
+	// StartupTimeoutSeconds adds a startup probe that gives the database this long to become
+	// healthy before the liveness probe takes over.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	StartupTimeoutSeconds *int32 `json:"startupTimeoutSeconds,omitempty"`
+	// SlowStart tunes the probes for databases that take minutes to open:
+	// a startup probe allowing 30 minutes, unless StartupTimeoutSeconds is set,
+	// a liveness probe tolerating 2 minutes of failed checks once started,
+	// and a minReadySeconds of 30 on the StatefulSet, unless spec.minReadySeconds is set.
+	// +optional
+	SlowStart bool `json:"slowStart,omitempty"`
+	// GRPCReadiness checks the gRPC replication port with a native gRPC probe for readiness, so
+	// that a pod is not ready until gRPC serves. The image has to implement the gRPC health
+	// checking protocol. Liveness and startup keep the probe selected by type.
+	// +optional
+	GRPCReadiness bool `json:"grpcReadiness,omitempty"`
+}
+
+// DatabaseDrain configures the draining of connections before a database pod is terminated.
+type DatabaseDrain struct {
+	// PeriodSeconds the preStop hook waits before libsql-server is stopped. A terminating pod is
+	// removed from the endpoints of the Services right away, so no new connections reach it and
+	// the queries in flight have this long to finish. The image has to provide sleep.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=15
+	// +optional
+	PeriodSeconds int32 `json:"periodSeconds,omitempty"`
+}
+
+// DatabaseInitSQL is a SQL script given inline or read from a ConfigMap.
+// +kubebuilder:validation:XValidation:rule="has(self.sql) != has(self.configMapKeyRef)",message="exactly one of sql and configMapKeyRef must be set"
+type DatabaseInitSQL struct {
+	// SQL statements separated by semicolons.
+	// +optional
+	SQL string `json:"sql,omitempty"`
+	// ConfigMapKeyRef selects a key of a ConfigMap in the namespace of the Database holding the script.
+	// +optional
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+}
+
+// DatabaseMonitoring configures the monitoring resources generated for a Database.
+type DatabaseMonitoring struct {
+	// PrometheusRule creates a monitoring.coreos.com/v1 PrometheusRule with default alerts
+	// for the Database. Requires the prometheus-operator CRDs.
+	// +optional
+	PrometheusRule *DatabasePrometheusRule `json:"prometheusRule,omitempty"`
+	// GrafanaDashboard creates a ConfigMap with a dashboard of the Database, labeled to be
+	// picked up by the Grafana dashboard sidecar.
+	// +optional
+	GrafanaDashboard *DatabaseGrafanaDashboard `json:"grafanaDashboard,omitempty"`
+	// MetricsExporter injects a sidecar translating the stats of libsql-server into Prometheus
+	// metrics, for images without the native metrics endpoint. Its port is added to the client
+	// facing Service and scraped through a monitoring.coreos.com/v1 ServiceMonitor.
+	// +optional
+	MetricsExporter *DatabaseMetricsExporter `json:"metricsExporter,omitempty"`
+}
+
+// DatabaseMetricsExporter configures the metrics exporter sidecar of a Database.
+type DatabaseMetricsExporter struct {
+	// Image of the exporter. It reads the stats of libsql-server from the URL in SQLD_URL and
+	// serves the metrics on the port in METRICS_PORT.
+	// +kubebuilder:validation:MinLength=1
+	Image string `json:"image"`
+	// Port the exporter serves the metrics on, exposed as the metrics port of the Service.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +kubebuilder:default=9091
+	// +optional
+	Port int32 `json:"port,omitempty"`
+	// Resources of the exporter container.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// Labels added to the ServiceMonitor, e.g. to match the serviceMonitorSelector of Prometheus.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// DatabaseGrafanaDashboard configures the dashboard ConfigMap of a Database.
+type DatabaseGrafanaDashboard struct {
+	// Labels added to the ConfigMap on top of grafana_dashboard: "1".
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations added to the ConfigMap, e.g. to select the Grafana folder of the dashboard.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// DatabaseConnectionConfigMap configures the connection ConfigMap of a Database.
+type DatabaseConnectionConfigMap struct {
+	// Labels added to the ConfigMap.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations added to the ConfigMap, e.g. to have it copied into other namespaces.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// DatabasePrometheusRule configures the default alerts of a Database.
+type DatabasePrometheusRule struct {
+	// Labels added to the PrometheusRule, e.g. to match the ruleSelector of Prometheus.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// DownFor is how long the database has to be unavailable before LibsqlDatabaseDown fires.
+	// +kubebuilder:default="5m"
+	// +optional
+	DownFor metav1.Duration `json:"downFor,omitempty"`
+	// PodRestartThreshold is the number of restarts within an hour above which
+	// LibsqlDatabasePodRestarting fires.
+	// +kubebuilder:default=3
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	PodRestartThreshold int32 `json:"podRestartThreshold,omitempty"`
+	// DisabledAlerts lists the names of default alerts that are not generated.
+	// +optional
+	DisabledAlerts []string `json:"disabledAlerts,omitempty"`
+}
+
+// DatabaseExternalAuthSecret sources the auth keys of a Database from an external-secrets.io
+// ExternalSecret instead of generating them.
+type DatabaseExternalAuthSecret struct {
+	// SecretStoreRef is the SecretStore or ClusterSecretStore the keys are read from.
+	SecretStoreRef DatabaseSecretStoreRef `json:"secretStoreRef"`
+	// RemoteKey is the path of the keys in the store. It must hold PUBLIC_KEY and PRIVATE_KEY
+	// properties, encoded like the keys generated by the operator.
+	// +kubebuilder:validation:MinLength=1
+	RemoteKey string `json:"remoteKey"`
+	// RefreshInterval is how often the keys are synced from the store.
+	// +kubebuilder:default="1h"
+	// +optional
+	RefreshInterval metav1.Duration `json:"refreshInterval,omitempty"`
+}
+
+type DatabaseSecretStoreRef struct {
+	// Name of the SecretStore or ClusterSecretStore.
+	Name string `json:"name"`
+	// Kind of the store.
+	// +kubebuilder:validation:Enum=SecretStore;ClusterSecretStore
+	// +kubebuilder:default="SecretStore"
+	// +optional
+	Kind string `json:"kind,omitempty"`
+}
+
+// DatabaseIstio configures the integration of a Database with an Istio service mesh.
+type DatabaseIstio struct {
+	// SidecarInjection sets the sidecar.istio.io/inject label of the database pods.
+	// +optional
+	SidecarInjection *bool `json:"sidecarInjection,omitempty"`
+	// PeerAuthentication creates a security.istio.io PeerAuthentication for the database pods.
+	// +optional
+	PeerAuthentication *DatabasePeerAuthentication `json:"peerAuthentication,omitempty"`
+}
+
+type DatabasePeerAuthentication struct {
+	// Mode of mutual TLS for the database pods.
+	// +kubebuilder:validation:Enum=STRICT;PERMISSIVE;DISABLE
+	// +kubebuilder:default="STRICT"
+	// +optional
+	Mode string `json:"mode,omitempty"`
+}
+
+// DatabaseTokenClaims are the default claims of the tokens the operator mints for a Database.
+type DatabaseTokenClaims struct {
+	// Issuer is set as the iss claim.
+	// +optional
+	Issuer string `json:"issuer,omitempty"`
+	// Audience is set as the aud claim.
+	// +optional
+	Audience []string `json:"audience,omitempty"`
+	// Permissions is set as the p claim, which sqld uses to scope the access of a token.
+	// +optional
+	Permissions *apiextensionsv1.JSON `json:"permissions,omitempty"`
 }
 
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
@@ -40,20 +446,140 @@ type DatabaseSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
-	Image string `json:"image"`
+	// Image of the libsql-server container. Defaults to the operator wide default image
+	// when empty.
+	// +optional
+	Image string `json:"image,omitempty"`
+	// ContainerName is the name of the libsql-server container in the database pods, a DNS-1123
+	// label.
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:default="libsql-server"
+	// +optional
+	ContainerName string `json:"containerName,omitempty"`
+	// ImagePullPolicy of the database container.
+	// +kubebuilder:validation:Enum=Always;IfNotPresent;Never
 	// +kubebuilder:default="IfNotPresent"
 	// +optional
-	ImagePullPolicy string `json:"imagePullPolicy"`
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
 	// +kubebuilder:default=true
 	// +optional
-	Auth    bool            `json:"auth"`
-	Storage DatabaseStorage `json:"storage"`
+	Auth bool `json:"auth"`
+	// SeparatePublicKeySecret stores the auth public key in its own secret, apart from the
+	// private signing key, so that it can be shared with token verifiers.
+	// +optional
+	SeparatePublicKeySecret bool `json:"separatePublicKeySecret,omitempty"`
+	// PublishJWKS publishes the auth public key as a JSON Web Key Set under the jwks.json key of
+	// a <name>-jwks ConfigMap, for JWT libraries verifying the tokens of the Database. It follows
+	// the key of the auth secret, so rotated keys are published as well.
+	// +optional
+	PublishJWKS bool `json:"publishJWKS,omitempty"`
+	// RetainAuthSecret keeps the generated auth secret when auth is turned off, the database just
+	// stops using it, so turning auth back on restores the same keys and the tokens signed with
+	// them. This also means tokens issued before auth was turned off become valid again, delete
+	// the secret to rotate the keys instead. By default the secret is deleted along with auth.
+	// +optional
+	RetainAuthSecret bool `json:"retainAuthSecret,omitempty"`
+	// SecretAnnotations are set on the generated auth secret and the public key secret, e.g. to
+	// have them replicated into other namespaces. Annotations added by others are kept.
+	// +optional
+	SecretAnnotations map[string]string `json:"secretAnnotations,omitempty"`
+	// ExternalAuthSecret syncs the auth keys from an external secret store through the External
+	// Secrets Operator instead of generating them. The database is started once they are synced.
+	// +optional
+	ExternalAuthSecret *DatabaseExternalAuthSecret `json:"externalAuthSecret,omitempty"`
+	// SharedAuthSecretRef is a Secret in the namespace of the Database holding the PUBLIC_KEY
+	// tokens are verified with, instead of a key pair generated for the Database. Several
+	// Databases can reference the same secret to trust tokens signed by one central key. The
+	// secret is never modified or deleted by the operator, PRIVATE_KEY is only needed to run
+	// spec.initSQL. Cannot be combined with ExternalAuthSecret.
+	// +optional
+	SharedAuthSecretRef *corev1.LocalObjectReference `json:"sharedAuthSecretRef,omitempty"`
+	// InitSQL is executed once against the primary the first time it becomes available, to
+	// bootstrap the schema of a new database. A failed script is executed again once it is
+	// changed, so statements should be idempotent, e.g. CREATE TABLE IF NOT EXISTS.
+	// +optional
+	InitSQL *DatabaseInitSQL `json:"initSQL,omitempty"`
+	// TokenClaims are the default claims of the tokens minted by the operator for this Database.
+	// +optional
+	TokenClaims *DatabaseTokenClaims `json:"tokenClaims,omitempty"`
+	Storage     DatabaseStorage      `json:"storage"`
+	// Service configures the ClusterIP Service of the Database.
+	// +optional
+	Service *DatabaseServiceSpec `json:"service,omitempty"`
 	// +optional
 	Ingress *AhtiDatabaseIngressSpec `json:"ingress,omitempty"`
+	// AdditionalIngresses expose the database through further Ingresses, one per entry, e.g.
+	// through an internal and an external ingress class. They do not need spec.ingress.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	AdditionalIngresses []AhtiDatabaseAdditionalIngressSpec `json:"additionalIngresses,omitempty"`
+	// Istio opts the Database into an Istio service mesh.
+	// +optional
+	Istio *DatabaseIstio `json:"istio,omitempty"`
+	// Monitoring configures the monitoring resources generated for the Database.
+	// +optional
+	Monitoring *DatabaseMonitoring `json:"monitoring,omitempty"`
+	// ConnectionConfigMap publishes the connection details of the Database in a <name>-connection
+	// ConfigMap, for applications that mount their configuration. It holds no credentials, the
+	// AUTH_SECRET key names the Secret with the PUBLIC_KEY tokens are verified with.
+	// +optional
+	ConnectionConfigMap *DatabaseConnectionConfigMap `json:"connectionConfigMap,omitempty"`
 	// +optional
 	Resource corev1.ResourceRequirements `json:"resources"`
 	// +optional
 	Env []corev1.EnvVar `json:"env"`
+	// LogLevel of libsql-server, passed as RUST_LOG. A RUST_LOG in env takes precedence, for
+	// finer grained filters.
+	// +kubebuilder:default="info"
+	// +optional
+	LogLevel DatabaseLogLevel `json:"logLevel,omitempty"`
+	// HTTPPort libsql-server listens for HTTP requests on. The Service keeps exposing the
+	// HTTP API on port 8080.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +kubebuilder:default=8080
+	// +optional
+	HTTPPort int32 `json:"httpPort,omitempty"`
+	// MaxConcurrentConnections is the number of client connections libsql-server serves at the
+	// same time, passed as SQLD_MAX_CONCURRENT_CONNECTIONS. The sqld default of 128 applies when
+	// unset. Takes precedence over SQLD_MAX_CONCURRENT_CONNECTIONS in env.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxConcurrentConnections *int32 `json:"maxConcurrentConnections,omitempty"`
+	// MaxConcurrentRequests is the number of requests libsql-server executes at the same time
+	// across all connections, passed as SQLD_MAX_CONCURRENT_REQUESTS. Requests above the limit wait
+	// for a slot, so a single client can not overwhelm the database. The sqld default of 128
+	// applies when unset. Takes precedence over SQLD_MAX_CONCURRENT_REQUESTS in env.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxConcurrentRequests *int32 `json:"maxConcurrentRequests,omitempty"`
+	// MinReadySeconds a new database pod has to be ready before it is considered available.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinReadySeconds int32 `json:"minReadySeconds,omitempty"`
+	// Probe configures the liveness and readiness probes of the database container.
+	// Defaults to an HTTP GET on /health.
+	// +optional
+	Probe *DatabaseProbe `json:"probe,omitempty"`
+	// Drain delays the termination of the database pods on rollouts and scale down, so that
+	// in-flight queries are not dropped.
+	// +optional
+	Drain *DatabaseDrain `json:"drain,omitempty"`
+	// TerminationGracePeriodSeconds of the database pods, including the drain period. Defaults to
+	// 30 seconds, on top of the drain period when spec.drain is set.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds,omitempty"`
+	// PodTemplateOverrides is applied to the generated pod template of the database, as a
+	// strategic merge patch when it is an object or as a JSON patch when it is a list of
+	// operations. It is an escape hatch for pod settings the Database does not expose, the
+	// patched template must keep the database container and its data volume mount.
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	PodTemplateOverrides *runtime.RawExtension `json:"podTemplateOverrides,omitempty"`
 
 	// NodeSelector is a selector which must be true for the pod to fit on a node.
 	// Selector which must match a node's labels for the pod to be scheduled on that node.
@@ -79,15 +605,55 @@ type DatabaseSpec struct {
 	// If specified, the pod's scheduling constraints
 	// +optional
 	Affinity *corev1.Affinity `json:"affinity,omitempty" protobuf:"bytes,18,opt,name=affinity"`
+	// PodAntiAffinity keeps the pods of the Database off the nodes running the pods of other
+	// Databases, e.g. to isolate noisy databases on a shared node pool. It is added to the pod
+	// anti-affinity of spec.affinity.
+	// +optional
+	PodAntiAffinity *DatabasePodAntiAffinity `json:"podAntiAffinity,omitempty"`
+	// PodLabels are added to the database pods only, e.g. the version label Istio and Linkerd
+	// route traffic by. They are not part of the immutable StatefulSet selector, so they can be
+	// changed, which rolls the pods. The labels the pods are selected by cannot be set.
+	// +optional
+	PodLabels map[string]string `json:"podLabels,omitempty"`
 	// If specified, the pod will be dispatched by specified scheduler.
 	// If not specified, the pod will be dispatched by default scheduler.
 	// +optional
 	SchedulerName string `json:"schedulerName,omitempty" protobuf:"bytes,19,opt,name=schedulerName"`
 	// If specified, the pod's tolerations.
+	// The default tolerations of the operator are added, unless one of these tolerates the same
+	// taint key and effect.
 	// +optional
 	Tolerations []corev1.Toleration `json:"tolerations,omitempty" protobuf:"bytes,22,opt,name=tolerations"`
+	// RuntimeClassName refers to a RuntimeClass object in the node.k8s.io group, which should be used
+	// to run the database pods, for example a gVisor or Kata sandbox.
+	// More info: https://git.k8s.io/enhancements/keps/sig-node/585-runtime-class
+	// +optional
+	RuntimeClassName *string `json:"runtimeClassName,omitempty" protobuf:"bytes,29,opt,name=runtimeClassName"`
+	// HostAliases is an optional list of hosts and IPs that will be injected into the pod's hosts
+	// file if specified, for example to resolve an on-prem backup target.
+	// +optional
+	// +patchMergeKey=ip
+	// +patchStrategy=merge
+	HostAliases []corev1.HostAlias `json:"hostAliases,omitempty" patchStrategy:"merge" patchMergeKey:"ip" protobuf:"bytes,23,rep,name=hostAliases"`
 }
 
+// DatabasePhase is a high-level summary of the state of a Database.
+// +kubebuilder:validation:Enum=Pending;Provisioning;Running;Degraded;Terminating
+type DatabasePhase string
+
+const (
+	// DatabasePhasePending is set until the operator has started to reconcile the Database.
+	DatabasePhasePending DatabasePhase = "Pending"
+	// DatabasePhaseProvisioning is set while the database pods are rolling out.
+	DatabasePhaseProvisioning DatabasePhase = "Provisioning"
+	// DatabasePhaseRunning is set once the database is available.
+	DatabasePhaseRunning DatabasePhase = "Running"
+	// DatabasePhaseDegraded is set when the database needs intervention, see the conditions for details.
+	DatabasePhaseDegraded DatabasePhase = "Degraded"
+	// DatabasePhaseTerminating is set once the Database is being deleted.
+	DatabasePhaseTerminating DatabasePhase = "Terminating"
+)
+
 // DatabaseStatus defines the observed state of Database
 type DatabaseStatus struct {
 	// Represents the observations of a Database's current state.
@@ -101,10 +667,105 @@ type DatabaseStatus struct {
 
 	// Conditions store the status conditions of the Database instances
 	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+
+	// ObservedGeneration is the generation of the Database spec the last successful reconcile applied.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Phase summarizes the conditions of the Database.
+	// +optional
+	Phase DatabasePhase `json:"phase,omitempty"`
+	// Image is the libsql-server image the database is running with, after defaults and the
+	// registry rewrites of the operator are applied.
+	// +optional
+	Image string `json:"image,omitempty"`
+	// DesiredImage is the libsql-server image of the Database, after defaults are applied.
+	// +optional
+	DesiredImage string `json:"desiredImage,omitempty"`
+	// PrimaryEndpoint is the stable address replicas and clients discover the primary through.
+	// +optional
+	PrimaryEndpoint *DatabaseEndpoint `json:"primaryEndpoint,omitempty"`
+	// LastSnapshot is the VolumeSnapshot most recently requested through the
+	// libsql.ahti.io/snapshot annotation.
+	// +optional
+	LastSnapshot *DatabaseSnapshotStatus `json:"lastSnapshot,omitempty"`
+	// InitSQL records the execution of spec.initSQL.
+	// +optional
+	InitSQL *DatabaseInitSQLStatus `json:"initSQL,omitempty"`
+	// CloneSource records where the data of a Database created with spec.storage.cloneFrom comes from.
+	// +optional
+	CloneSource *DatabaseCloneStatus `json:"cloneSource,omitempty"`
+	// ManagedResources lists the resources generated for the Database, sorted by kind and name.
+	// +optional
+	ManagedResources []DatabaseManagedResource `json:"managedResources,omitempty"`
+	// LastError is the error of the last failed reconcile, cleared once a reconcile succeeds.
+	// +optional
+	LastError *DatabaseError `json:"lastError,omitempty"`
+	// ReadyTime is when the Database first became Available, it is kept when it becomes
+	// unavailable afterwards.
+	// +optional
+	ReadyTime *metav1.Time `json:"readyTime,omitempty"`
+	// StorageUsageCheckTime is when the usage of the data volume was last read for
+	// spec.storage.pressureThreshold.
+	// +optional
+	StorageUsageCheckTime *metav1.Time `json:"storageUsageCheckTime,omitempty"`
+}
+
+type DatabaseEndpoint struct {
+	// Host is the fully qualified domain name of the headless service of the primary.
+	Host string `json:"host"`
+	// GRPCPort is the port the primary serves replication on.
+	GRPCPort int32 `json:"grpcPort"`
+}
+
+type DatabaseInitSQLStatus struct {
+	// Job executing the script.
+	Job string `json:"job"`
+	// Succeeded is set once the script was executed, it is not executed again afterwards.
+	Succeeded bool `json:"succeeded"`
+	// Failed is set when the Job failed, the script is executed again once it is changed.
+	// +optional
+	Failed bool `json:"failed,omitempty"`
+	// CompletionTime of the script.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+type DatabaseCloneStatus struct {
+	// Database the data was cloned from.
+	Database string `json:"database"`
+	// Snapshot is the VolumeSnapshot of the source data volume the clone is restored from.
+	Snapshot string `json:"snapshot"`
+	// ReadyToUse mirrors the readyToUse status of the VolumeSnapshot.
+	ReadyToUse bool `json:"readyToUse"`
+}
+
+type DatabaseManagedResource struct {
+	// Kind of the resource.
+	Kind string `json:"kind"`
+	// Name of the resource, in the namespace of the Database.
+	Name string `json:"name"`
+}
+
+type DatabaseError struct {
+	// Message of the error, truncated to keep the status small.
+	Message string `json:"message"`
+	// Time the error occurred.
+	Time metav1.Time `json:"time"`
+}
+
+type DatabaseSnapshotStatus struct {
+	// Name of the VolumeSnapshot.
+	Name string `json:"name"`
+	// ReadyToUse mirrors the readyToUse status of the VolumeSnapshot.
+	ReadyToUse bool `json:"readyToUse"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Available")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // Database is the Schema for the databases API
 type Database struct {
@@ -0,0 +1,245 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var databaselog = logf.Log.WithName("database-resource")
+
+// defaultStorageClassAnnotation marks the StorageClass used by claims that do not name one
+const defaultStorageClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+
+// SetupWebhookWithManager registers the validating webhook of Databases with the manager.
+func (r *Database) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithValidator(&DatabaseValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+// The webhook is skipped rather than blocking Databases when it is unavailable, the errors it
+// reports early are still reported by the StatefulSet once it creates the pods.
+//+kubebuilder:webhook:path=/validate-libsql-ahti-io-v1-database,mutating=false,failurePolicy=ignore,sideEffects=None,groups=libsql.ahti.io,resources=databases,verbs=create;update,versions=v1,name=vdatabase.kb.io,admissionReviewVersions=v1
+//+kubebuilder:rbac:groups=storage.k8s.io,resources=storageclasses,verbs=get;list;watch
+
+// DatabaseValidator checks Databases on admission for mistakes that would only show once the
+// pods are created or scheduled. Invalid specs are still reported by the controller on the status.
+// +kubebuilder:object:generate=false
+type DatabaseValidator struct {
+	Client client.Reader
+}
+
+var _ webhook.CustomValidator = &DatabaseValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *DatabaseValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	database, ok := obj.(*Database)
+	if !ok {
+		return nil, fmt.Errorf("expected a Database but got a %T", obj)
+	}
+	// the name is immutable, checking it on update would only block the finalizer removal
+	// of a Database created before the check
+	if allErrs := validateName(database.Name, field.NewPath("metadata", "name")); len(allErrs) > 0 {
+		return nil, apierrors.NewInvalid(GroupVersion.WithKind("Database").GroupKind(), database.Name, allErrs)
+	}
+	return v.validate(ctx, database)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *DatabaseValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	database, ok := newObj.(*Database)
+	if !ok {
+		return nil, fmt.Errorf("expected a Database but got a %T", newObj)
+	}
+	return v.validate(ctx, database)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *DatabaseValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *DatabaseValidator) validate(ctx context.Context, database *Database) (admission.Warnings, error) {
+	allErrs := validateScheduling(&database.Spec, field.NewPath("spec"))
+	allErrs = append(allErrs, validateContainerName(database.Spec.ContainerName, field.NewPath("spec", "containerName"))...)
+	if len(allErrs) > 0 {
+		return nil, apierrors.NewInvalid(GroupVersion.WithKind("Database").GroupKind(), database.Name, allErrs)
+	}
+	var warnings admission.Warnings
+	if warning := v.checkStorageTopology(ctx, database); warning != "" {
+		warnings = append(warnings, warning)
+	}
+	return warnings, nil
+}
+
+// databaseServiceNameSuffixes are appended to the name of a Database for the names of its
+// Services, which have to be DNS-1035 labels. They follow the names of internal/utils, which
+// cannot be imported here. The other generated names are DNS subdomains of up to 253 characters.
+var databaseServiceNameSuffixes = []string{"-svc", "-svc-headless", "-svc-grpc", "-maintenance"}
+
+// maxDatabaseNameLength returns the longest Database name whose Service names fit a DNS label.
+func maxDatabaseNameLength() int {
+	maxLength := validation.DNS1035LabelMaxLength
+	for _, suffix := range databaseServiceNameSuffixes {
+		maxLength = min(maxLength, validation.DNS1035LabelMaxLength-len(suffix))
+	}
+	return maxLength
+}
+
+// validateName rejects names too long for the generated resources, which would otherwise
+// only fail once the controller creates them.
+func validateName(name string, fldPath *field.Path) field.ErrorList {
+	maxLength := maxDatabaseNameLength()
+	if len(name) <= maxLength {
+		return nil
+	}
+	return field.ErrorList{field.Invalid(fldPath, name, fmt.Sprintf(
+		"must be no more than %d characters, the names of the generated Services are DNS labels of at most %d characters",
+		maxLength, validation.DNS1035LabelMaxLength))}
+}
+
+// metricsExporterContainerName is the name of the metrics exporter sidecar of internal/controller,
+// which cannot be imported here.
+const metricsExporterContainerName = "metrics-exporter"
+
+// validateContainerName rejects the name of the metrics exporter sidecar for the database
+// container, the pod template would have two containers of the same name.
+func validateContainerName(name string, fldPath *field.Path) field.ErrorList {
+	if name != metricsExporterContainerName {
+		return nil
+	}
+	return field.ErrorList{field.Invalid(fldPath, name, "is reserved for the metrics exporter container")}
+}
+
+// checkStorageTopology warns when spec.nodeSelector and spec.affinity only select nodes outside
+// the allowedTopologies of the default StorageClass, the data volume could not be attached and
+// the pods would stay pending. The check is best effort, only the In operator of the required
+// node affinity is understood and a StorageClass that cannot be read is ignored.
+func (v *DatabaseValidator) checkStorageTopology(ctx context.Context, database *Database) string {
+	terms := []corev1.NodeSelectorTerm{{}}
+	if affinity := database.Spec.Affinity; affinity != nil && affinity.NodeAffinity != nil &&
+		affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil &&
+		len(affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms) > 0 {
+		terms = affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	} else if len(database.Spec.NodeSelector) == 0 {
+		return ""
+	}
+
+	storageClass, err := v.getStorageClass(ctx, database.Spec.Storage.StorageClassName)
+	if err != nil {
+		databaselog.Error(err, "unable to get the StorageClass of the data volume", "name", database.Name, "namespace", database.Namespace)
+		return ""
+	}
+	if storageClass == nil || len(storageClass.AllowedTopologies) == 0 {
+		return ""
+	}
+	// the terms of the required node affinity are ORed, the pods fit when any of them does
+	for _, term := range terms {
+		labels := getNodeLabelValues(database.Spec.NodeSelector, term)
+		for _, topology := range storageClass.AllowedTopologies {
+			if isTopologyAllowed(topology, labels) {
+				return ""
+			}
+		}
+	}
+	return fmt.Sprintf("spec.nodeSelector and spec.affinity only select nodes outside the allowedTopologies of StorageClass %s, "+
+		"the data volume cannot be attached and the pods of the Database would stay pending", storageClass.Name)
+}
+
+// getStorageClass returns the StorageClass of the data volume, nil for a volume without class.
+// The default class is used when spec.storage.storageClassName is unset, the most recent one
+// when several are marked as default, as Kubernetes does.
+func (v *DatabaseValidator) getStorageClass(ctx context.Context, name *string) (*storagev1.StorageClass, error) {
+	if name != nil {
+		if *name == "" {
+			return nil, nil
+		}
+		storageClass := &storagev1.StorageClass{}
+		if err := v.Client.Get(ctx, client.ObjectKey{Name: *name}, storageClass); err != nil {
+			return nil, client.IgnoreNotFound(err)
+		}
+		return storageClass, nil
+	}
+	storageClasses := &storagev1.StorageClassList{}
+	if err := v.Client.List(ctx, storageClasses); err != nil {
+		return nil, err
+	}
+	var defaultStorageClass *storagev1.StorageClass
+	for i, storageClass := range storageClasses.Items {
+		if storageClass.Annotations[defaultStorageClassAnnotation] != "true" {
+			continue
+		}
+		if defaultStorageClass == nil || defaultStorageClass.CreationTimestamp.Before(&storageClass.CreationTimestamp) {
+			defaultStorageClass = &storageClasses.Items[i]
+		}
+	}
+	return defaultStorageClass, nil
+}
+
+// getNodeLabelValues returns the values the node selector and a term of the required node
+// affinity allow for each node label they constrain.
+func getNodeLabelValues(nodeSelector map[string]string, term corev1.NodeSelectorTerm) map[string][]string {
+	labels := map[string][]string{}
+	for key, value := range nodeSelector {
+		labels[key] = []string{value}
+	}
+	for _, expression := range term.MatchExpressions {
+		if expression.Operator != corev1.NodeSelectorOpIn {
+			continue
+		}
+		values, ok := labels[expression.Key]
+		if !ok {
+			labels[expression.Key] = expression.Values
+			continue
+		}
+		labels[expression.Key] = slices.DeleteFunc(slices.Clone(values), func(value string) bool {
+			return !slices.Contains(expression.Values, value)
+		})
+	}
+	return labels
+}
+
+// isTopologyAllowed reports whether nodes with the label values can be in the topology.
+func isTopologyAllowed(topology corev1.TopologySelectorTerm, labels map[string][]string) bool {
+	for _, requirement := range topology.MatchLabelExpressions {
+		values, ok := labels[requirement.Key]
+		if !ok {
+			continue
+		}
+		if !slices.ContainsFunc(values, func(value string) bool { return slices.Contains(requirement.Values, value) }) {
+			return false
+		}
+	}
+	return true
+}
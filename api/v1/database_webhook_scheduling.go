@@ -0,0 +1,196 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// The checks below follow the pod validation of the API server for the scheduling fields copied
+// into the pod template. The CRD schema only checks their types, so a malformed affinity is
+// otherwise accepted and only rejected once the StatefulSet creates the pods.
+
+// validateScheduling returns the errors of spec.affinity and spec.tolerations.
+func validateScheduling(spec *DatabaseSpec, specPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if spec.Affinity != nil {
+		allErrs = append(allErrs, validateAffinity(spec.Affinity, specPath.Child("affinity"))...)
+	}
+	allErrs = append(allErrs, validateTolerations(spec.Tolerations, specPath.Child("tolerations"))...)
+	return allErrs
+}
+
+func validateAffinity(affinity *corev1.Affinity, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if nodeAffinity := affinity.NodeAffinity; nodeAffinity != nil {
+		nodeAffinityPath := fldPath.Child("nodeAffinity")
+		if required := nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution; required != nil {
+			requiredPath := nodeAffinityPath.Child("requiredDuringSchedulingIgnoredDuringExecution")
+			if len(required.NodeSelectorTerms) == 0 {
+				allErrs = append(allErrs, field.Required(requiredPath.Child("nodeSelectorTerms"), "must have at least one node selector term"))
+			}
+			for i, term := range required.NodeSelectorTerms {
+				allErrs = append(allErrs, validateNodeSelectorTerm(term, requiredPath.Child("nodeSelectorTerms").Index(i))...)
+			}
+		}
+		for i, preferred := range nodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+			preferredPath := nodeAffinityPath.Child("preferredDuringSchedulingIgnoredDuringExecution").Index(i)
+			allErrs = append(allErrs, validateSchedulingWeight(preferred.Weight, preferredPath.Child("weight"))...)
+			allErrs = append(allErrs, validateNodeSelectorTerm(preferred.Preference, preferredPath.Child("preference"))...)
+		}
+	}
+	if podAffinity := affinity.PodAffinity; podAffinity != nil {
+		allErrs = append(allErrs, validatePodAffinityTerms(podAffinity.RequiredDuringSchedulingIgnoredDuringExecution,
+			podAffinity.PreferredDuringSchedulingIgnoredDuringExecution, fldPath.Child("podAffinity"))...)
+	}
+	if podAntiAffinity := affinity.PodAntiAffinity; podAntiAffinity != nil {
+		allErrs = append(allErrs, validatePodAffinityTerms(podAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution,
+			podAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution, fldPath.Child("podAntiAffinity"))...)
+	}
+	return allErrs
+}
+
+func validateNodeSelectorTerm(term corev1.NodeSelectorTerm, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for i, requirement := range term.MatchExpressions {
+		requirementPath := fldPath.Child("matchExpressions").Index(i)
+		allErrs = append(allErrs, validateQualifiedName(requirement.Key, requirementPath.Child("key"))...)
+		valuesPath := requirementPath.Child("values")
+		switch requirement.Operator {
+		case corev1.NodeSelectorOpIn, corev1.NodeSelectorOpNotIn:
+			if len(requirement.Values) == 0 {
+				allErrs = append(allErrs, field.Required(valuesPath, "must be specified when `operator` is 'In' or 'NotIn'"))
+			}
+		case corev1.NodeSelectorOpExists, corev1.NodeSelectorOpDoesNotExist:
+			if len(requirement.Values) > 0 {
+				allErrs = append(allErrs, field.Forbidden(valuesPath, "may not be specified when `operator` is 'Exists' or 'DoesNotExist'"))
+			}
+		case corev1.NodeSelectorOpGt, corev1.NodeSelectorOpLt:
+			if len(requirement.Values) != 1 {
+				allErrs = append(allErrs, field.Required(valuesPath, "must be specified single value when `operator` is 'Lt' or 'Gt'"))
+			} else if _, err := strconv.ParseInt(requirement.Values[0], 10, 64); err != nil {
+				allErrs = append(allErrs, field.Invalid(valuesPath.Index(0), requirement.Values[0], "must be an integer"))
+			}
+		default:
+			allErrs = append(allErrs, field.NotSupported(requirementPath.Child("operator"), requirement.Operator, []string{
+				string(corev1.NodeSelectorOpIn), string(corev1.NodeSelectorOpNotIn), string(corev1.NodeSelectorOpExists),
+				string(corev1.NodeSelectorOpDoesNotExist), string(corev1.NodeSelectorOpGt), string(corev1.NodeSelectorOpLt),
+			}))
+		}
+	}
+	for i, requirement := range term.MatchFields {
+		requirementPath := fldPath.Child("matchFields").Index(i)
+		// metadata.name is the only node field supported by the scheduler
+		if requirement.Key != "metadata.name" {
+			allErrs = append(allErrs, field.Invalid(requirementPath.Child("key"), requirement.Key, "not a valid field selector key"))
+		}
+		if requirement.Operator != corev1.NodeSelectorOpIn && requirement.Operator != corev1.NodeSelectorOpNotIn {
+			allErrs = append(allErrs, field.NotSupported(requirementPath.Child("operator"), requirement.Operator, []string{
+				string(corev1.NodeSelectorOpIn), string(corev1.NodeSelectorOpNotIn),
+			}))
+		} else if len(requirement.Values) != 1 {
+			allErrs = append(allErrs, field.Required(requirementPath.Child("values"), "must be only one value when `operator` is 'In' or 'NotIn' for node field selector"))
+		}
+	}
+	return allErrs
+}
+
+func validatePodAffinityTerms(required []corev1.PodAffinityTerm, preferred []corev1.WeightedPodAffinityTerm, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for i, term := range required {
+		allErrs = append(allErrs, validatePodAffinityTerm(term, fldPath.Child("requiredDuringSchedulingIgnoredDuringExecution").Index(i))...)
+	}
+	for i, weighted := range preferred {
+		weightedPath := fldPath.Child("preferredDuringSchedulingIgnoredDuringExecution").Index(i)
+		allErrs = append(allErrs, validateSchedulingWeight(weighted.Weight, weightedPath.Child("weight"))...)
+		allErrs = append(allErrs, validatePodAffinityTerm(weighted.PodAffinityTerm, weightedPath.Child("podAffinityTerm"))...)
+	}
+	return allErrs
+}
+
+func validatePodAffinityTerm(term corev1.PodAffinityTerm, fldPath *field.Path) field.ErrorList {
+	options := metav1validation.LabelSelectorValidationOptions{}
+	allErrs := metav1validation.ValidateLabelSelector(term.LabelSelector, options, fldPath.Child("labelSelector"))
+	allErrs = append(allErrs, metav1validation.ValidateLabelSelector(term.NamespaceSelector, options, fldPath.Child("namespaceSelector"))...)
+	if term.TopologyKey == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("topologyKey"), "can not be empty"))
+	} else {
+		allErrs = append(allErrs, validateQualifiedName(term.TopologyKey, fldPath.Child("topologyKey"))...)
+	}
+	return allErrs
+}
+
+func validateSchedulingWeight(weight int32, fldPath *field.Path) field.ErrorList {
+	if weight < 1 || weight > 100 {
+		return field.ErrorList{field.Invalid(fldPath, weight, "must be in the range 1-100")}
+	}
+	return nil
+}
+
+func validateTolerations(tolerations []corev1.Toleration, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for i, toleration := range tolerations {
+		tolerationPath := fldPath.Index(i)
+		if toleration.Key != "" {
+			allErrs = append(allErrs, validateQualifiedName(toleration.Key, tolerationPath.Child("key"))...)
+		}
+		// an empty key only tolerates every taint with the Exists operator
+		if toleration.Key == "" && toleration.Operator != corev1.TolerationOpExists {
+			allErrs = append(allErrs, field.Invalid(tolerationPath.Child("operator"), toleration.Operator,
+				"operator must be Exists when `key` is empty, which means \"match all values and all keys\""))
+		}
+		if toleration.TolerationSeconds != nil && toleration.Effect != corev1.TaintEffectNoExecute {
+			allErrs = append(allErrs, field.Invalid(tolerationPath.Child("effect"), toleration.Effect,
+				"effect must be 'NoExecute' when `tolerationSeconds` is set"))
+		}
+		switch toleration.Operator {
+		case corev1.TolerationOpEqual, "":
+			for _, msg := range validation.IsValidLabelValue(toleration.Value) {
+				allErrs = append(allErrs, field.Invalid(tolerationPath.Child("value"), toleration.Value, msg))
+			}
+		case corev1.TolerationOpExists:
+			if toleration.Value != "" {
+				allErrs = append(allErrs, field.Invalid(tolerationPath, toleration, "value must be empty when `operator` is 'Exists'"))
+			}
+		default:
+			allErrs = append(allErrs, field.NotSupported(tolerationPath.Child("operator"), toleration.Operator, []string{
+				string(corev1.TolerationOpEqual), string(corev1.TolerationOpExists),
+			}))
+		}
+		switch toleration.Effect {
+		case "", corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+		default:
+			allErrs = append(allErrs, field.NotSupported(tolerationPath.Child("effect"), toleration.Effect, []string{
+				string(corev1.TaintEffectNoSchedule), string(corev1.TaintEffectPreferNoSchedule), string(corev1.TaintEffectNoExecute),
+			}))
+		}
+	}
+	return allErrs
+}
+
+func validateQualifiedName(value string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for _, msg := range validation.IsQualifiedName(value) {
+		allErrs = append(allErrs, field.Invalid(fldPath, value, msg))
+	}
+	return allErrs
+}
@@ -0,0 +1,213 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDatabaseValidatorWarnsOnStorageTopologyMismatch(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	storageClass := &storagev1.StorageClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "zonal",
+			Annotations: map[string]string{defaultStorageClassAnnotation: "true"},
+		},
+		Provisioner: "ebs.csi.aws.com",
+		AllowedTopologies: []corev1.TopologySelectorTerm{{
+			MatchLabelExpressions: []corev1.TopologySelectorLabelRequirement{
+				{Key: corev1.LabelTopologyZone, Values: []string{"eu-west-1a"}},
+			},
+		}},
+	}
+	validator := &DatabaseValidator{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(storageClass).Build()}
+	zoneAffinity := func(zones ...string) *corev1.Affinity {
+		return &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+					MatchExpressions: []corev1.NodeSelectorRequirement{
+						{Key: corev1.LabelTopologyZone, Operator: corev1.NodeSelectorOpIn, Values: zones},
+					},
+				}},
+			},
+		}}
+	}
+
+	for name, test := range map[string]struct {
+		spec DatabaseSpec
+		warn bool
+	}{
+		"no scheduling constraints":     {spec: DatabaseSpec{}},
+		"node selector of another zone": {spec: DatabaseSpec{NodeSelector: map[string]string{corev1.LabelTopologyZone: "eu-west-1b"}}, warn: true},
+		"node selector of the zone":     {spec: DatabaseSpec{NodeSelector: map[string]string{corev1.LabelTopologyZone: "eu-west-1a"}}},
+		"unrelated node selector":       {spec: DatabaseSpec{NodeSelector: map[string]string{"pool": "databases"}}},
+		"affinity of another zone":      {spec: DatabaseSpec{Affinity: zoneAffinity("eu-west-1b", "eu-west-1c")}, warn: true},
+		"affinity including the zone":   {spec: DatabaseSpec{Affinity: zoneAffinity("eu-west-1a", "eu-west-1b")}},
+		"affinity and node selector": {
+			spec: DatabaseSpec{
+				NodeSelector: map[string]string{corev1.LabelTopologyZone: "eu-west-1b"},
+				Affinity:     zoneAffinity("eu-west-1a", "eu-west-1b"),
+			},
+			warn: true,
+		},
+	} {
+		database := &Database{ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "default"}, Spec: test.spec}
+		warnings, err := validator.ValidateCreate(context.Background(), database)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if warned := len(warnings) > 0; warned != test.warn {
+			t.Errorf("%s: got warnings %v, expected a warning: %t", name, warnings, test.warn)
+		}
+	}
+}
+
+func TestDatabaseValidatorIgnoresTopologyWithoutDefaultStorageClass(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	validator := &DatabaseValidator{Client: fake.NewClientBuilder().WithScheme(scheme).Build()}
+	database := &Database{
+		ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "default"},
+		Spec:       DatabaseSpec{NodeSelector: map[string]string{corev1.LabelTopologyZone: "eu-west-1b"}},
+	}
+	warnings, err := validator.ValidateCreate(context.Background(), database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) > 0 {
+		t.Errorf("got warnings %v without a default StorageClass", warnings)
+	}
+}
+
+func TestDatabaseValidatorRejectsInvalidScheduling(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	validator := &DatabaseValidator{Client: fake.NewClientBuilder().WithScheme(scheme).Build()}
+	nodeAffinity := func(requirement corev1.NodeSelectorRequirement) *corev1.Affinity {
+		return &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{{MatchExpressions: []corev1.NodeSelectorRequirement{requirement}}},
+			},
+		}}
+	}
+
+	for name, test := range map[string]struct {
+		spec  DatabaseSpec
+		field string
+	}{
+		"valid scheduling": {
+			spec: DatabaseSpec{
+				Affinity: nodeAffinity(corev1.NodeSelectorRequirement{
+					Key: corev1.LabelTopologyZone, Operator: corev1.NodeSelectorOpIn, Values: []string{"eu-west-1a"},
+				}),
+				Tolerations: []corev1.Toleration{
+					{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "databases", Effect: corev1.TaintEffectNoSchedule},
+					{Operator: corev1.TolerationOpExists},
+				},
+			},
+		},
+		"node affinity without values": {
+			spec: DatabaseSpec{Affinity: nodeAffinity(corev1.NodeSelectorRequirement{
+				Key: corev1.LabelTopologyZone, Operator: corev1.NodeSelectorOpIn,
+			})},
+			field: "spec.affinity.nodeAffinity.requiredDuringSchedulingIgnoredDuringExecution.nodeSelectorTerms[0].matchExpressions[0].values",
+		},
+		"node affinity with an invalid key": {
+			spec: DatabaseSpec{Affinity: nodeAffinity(corev1.NodeSelectorRequirement{
+				Key: "zone name", Operator: corev1.NodeSelectorOpExists,
+			})},
+			field: "spec.affinity.nodeAffinity.requiredDuringSchedulingIgnoredDuringExecution.nodeSelectorTerms[0].matchExpressions[0].key",
+		},
+		"pod anti affinity without topology key": {
+			spec: DatabaseSpec{Affinity: &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{
+				PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{{
+					Weight:          100,
+					PodAffinityTerm: corev1.PodAffinityTerm{LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "database"}}},
+				}},
+			}}},
+			field: "spec.affinity.podAntiAffinity.preferredDuringSchedulingIgnoredDuringExecution[0].podAffinityTerm.topologyKey",
+		},
+		"toleration seconds without NoExecute": {
+			spec: DatabaseSpec{Tolerations: []corev1.Toleration{
+				{Key: "dedicated", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule, TolerationSeconds: ptr.To(int64(60))},
+			}},
+			field: "spec.tolerations[0].effect",
+		},
+		"toleration without key": {
+			spec:  DatabaseSpec{Tolerations: []corev1.Toleration{{Operator: corev1.TolerationOpEqual, Value: "databases"}}},
+			field: "spec.tolerations[0].operator",
+		},
+	} {
+		database := &Database{ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "default"}, Spec: test.spec}
+		_, err := validator.ValidateCreate(context.Background(), database)
+		if test.field == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", name, err)
+			}
+			continue
+		}
+		statusErr := &apierrors.StatusError{}
+		if !errors.As(err, &statusErr) || !apierrors.IsInvalid(err) {
+			t.Errorf("%s: got %v, expected an invalid error", name, err)
+			continue
+		}
+		if causes := statusErr.Status().Details.Causes; len(causes) != 1 || causes[0].Field != test.field {
+			t.Errorf("%s: got causes %v, expected an error of %s", name, causes, test.field)
+		}
+	}
+}
+
+func TestDatabaseValidatorRejectsLongNames(t *testing.T) {
+	validator := &DatabaseValidator{Client: fake.NewClientBuilder().WithScheme(runtime.NewScheme()).Build()}
+	// <name>-svc-headless is the longest Service name
+	if maxLength := maxDatabaseNameLength(); maxLength != 50 {
+		t.Fatalf("got a maximum name length of %d, expected 50", maxLength)
+	}
+
+	database := &Database{ObjectMeta: metav1.ObjectMeta{Name: strings.Repeat("a", 50), Namespace: "default"}}
+	if _, err := validator.ValidateCreate(context.Background(), database); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	database.Name = strings.Repeat("a", 51)
+	_, err := validator.ValidateCreate(context.Background(), database)
+	if !apierrors.IsInvalid(err) || !strings.Contains(err.Error(), "must be no more than 50 characters") {
+		t.Fatalf("got %v, expected the name to be rejected with its maximum length", err)
+	}
+	if _, err := validator.ValidateUpdate(context.Background(), database, database); err != nil {
+		t.Fatalf("unexpected error on update %v", err)
+	}
+}
+
+func TestDatabaseValidatorRejectsMetricsExporterContainerName(t *testing.T) {
+	validator := &DatabaseValidator{Client: fake.NewClientBuilder().WithScheme(runtime.NewScheme()).Build()}
+	database := &Database{
+		ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "default"},
+		Spec:       DatabaseSpec{ContainerName: "sqld"},
+	}
+	if _, err := validator.ValidateCreate(context.Background(), database); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	database.Spec.ContainerName = "metrics-exporter"
+	if _, err := validator.ValidateUpdate(context.Background(), database, database); !apierrors.IsInvalid(err) ||
+		!strings.Contains(err.Error(), "spec.containerName") {
+		t.Fatalf("got %v, expected spec.containerName to be rejected", err)
+	}
+}
@@ -23,10 +23,27 @@ package v1
 import (
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AhtiDatabaseAdditionalIngressSpec) DeepCopyInto(out *AhtiDatabaseAdditionalIngressSpec) {
+	*out = *in
+	in.AhtiDatabaseIngressSpec.DeepCopyInto(&out.AhtiDatabaseIngressSpec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AhtiDatabaseAdditionalIngressSpec.
+func (in *AhtiDatabaseAdditionalIngressSpec) DeepCopy() *AhtiDatabaseAdditionalIngressSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AhtiDatabaseAdditionalIngressSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AhtiDatabaseIngressSpec) DeepCopyInto(out *AhtiDatabaseIngressSpec) {
 	*out = *in
@@ -42,6 +59,28 @@ func (in *AhtiDatabaseIngressSpec) DeepCopyInto(out *AhtiDatabaseIngressSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.PathType != nil {
+		in, out := &in.PathType, &out.PathType
+		*out = new(networkingv1.PathType)
+		**out = **in
+	}
+	if in.ExternalDNS != nil {
+		in, out := &in.ExternalDNS, &out.ExternalDNS
+		*out = new(DatabaseExternalDNS)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WebSocketTimeout != nil {
+		in, out := &in.WebSocketTimeout, &out.WebSocketTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AhtiDatabaseIngressSpec.
@@ -81,6 +120,248 @@ func (in *Database) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseCloneStatus) DeepCopyInto(out *DatabaseCloneStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseCloneStatus.
+func (in *DatabaseCloneStatus) DeepCopy() *DatabaseCloneStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseCloneStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseConnectionConfigMap) DeepCopyInto(out *DatabaseConnectionConfigMap) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseConnectionConfigMap.
+func (in *DatabaseConnectionConfigMap) DeepCopy() *DatabaseConnectionConfigMap {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseConnectionConfigMap)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseDrain) DeepCopyInto(out *DatabaseDrain) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseDrain.
+func (in *DatabaseDrain) DeepCopy() *DatabaseDrain {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseDrain)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseEndpoint) DeepCopyInto(out *DatabaseEndpoint) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseEndpoint.
+func (in *DatabaseEndpoint) DeepCopy() *DatabaseEndpoint {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseEndpoint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseError) DeepCopyInto(out *DatabaseError) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseError.
+func (in *DatabaseError) DeepCopy() *DatabaseError {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseError)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseExternalAuthSecret) DeepCopyInto(out *DatabaseExternalAuthSecret) {
+	*out = *in
+	out.SecretStoreRef = in.SecretStoreRef
+	out.RefreshInterval = in.RefreshInterval
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseExternalAuthSecret.
+func (in *DatabaseExternalAuthSecret) DeepCopy() *DatabaseExternalAuthSecret {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseExternalAuthSecret)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseExternalDNS) DeepCopyInto(out *DatabaseExternalDNS) {
+	*out = *in
+	if in.TTL != nil {
+		in, out := &in.TTL, &out.TTL
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseExternalDNS.
+func (in *DatabaseExternalDNS) DeepCopy() *DatabaseExternalDNS {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseExternalDNS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseGRPCServiceSpec) DeepCopyInto(out *DatabaseGRPCServiceSpec) {
+	*out = *in
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseGRPCServiceSpec.
+func (in *DatabaseGRPCServiceSpec) DeepCopy() *DatabaseGRPCServiceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseGRPCServiceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseGrafanaDashboard) DeepCopyInto(out *DatabaseGrafanaDashboard) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseGrafanaDashboard.
+func (in *DatabaseGrafanaDashboard) DeepCopy() *DatabaseGrafanaDashboard {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseGrafanaDashboard)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseInitSQL) DeepCopyInto(out *DatabaseInitSQL) {
+	*out = *in
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(corev1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseInitSQL.
+func (in *DatabaseInitSQL) DeepCopy() *DatabaseInitSQL {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseInitSQL)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseInitSQLStatus) DeepCopyInto(out *DatabaseInitSQLStatus) {
+	*out = *in
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseInitSQLStatus.
+func (in *DatabaseInitSQLStatus) DeepCopy() *DatabaseInitSQLStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseInitSQLStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseIstio) DeepCopyInto(out *DatabaseIstio) {
+	*out = *in
+	if in.SidecarInjection != nil {
+		in, out := &in.SidecarInjection, &out.SidecarInjection
+		*out = new(bool)
+		**out = **in
+	}
+	if in.PeerAuthentication != nil {
+		in, out := &in.PeerAuthentication, &out.PeerAuthentication
+		*out = new(DatabasePeerAuthentication)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseIstio.
+func (in *DatabaseIstio) DeepCopy() *DatabaseIstio {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseIstio)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DatabaseList) DeepCopyInto(out *DatabaseList) {
 	*out = *in
@@ -113,15 +394,300 @@ func (in *DatabaseList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseManagedResource) DeepCopyInto(out *DatabaseManagedResource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseManagedResource.
+func (in *DatabaseManagedResource) DeepCopy() *DatabaseManagedResource {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseManagedResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseMetricsExporter) DeepCopyInto(out *DatabaseMetricsExporter) {
+	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseMetricsExporter.
+func (in *DatabaseMetricsExporter) DeepCopy() *DatabaseMetricsExporter {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseMetricsExporter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseMonitoring) DeepCopyInto(out *DatabaseMonitoring) {
+	*out = *in
+	if in.PrometheusRule != nil {
+		in, out := &in.PrometheusRule, &out.PrometheusRule
+		*out = new(DatabasePrometheusRule)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GrafanaDashboard != nil {
+		in, out := &in.GrafanaDashboard, &out.GrafanaDashboard
+		*out = new(DatabaseGrafanaDashboard)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MetricsExporter != nil {
+		in, out := &in.MetricsExporter, &out.MetricsExporter
+		*out = new(DatabaseMetricsExporter)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseMonitoring.
+func (in *DatabaseMonitoring) DeepCopy() *DatabaseMonitoring {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseMonitoring)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabasePeerAuthentication) DeepCopyInto(out *DatabasePeerAuthentication) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabasePeerAuthentication.
+func (in *DatabasePeerAuthentication) DeepCopy() *DatabasePeerAuthentication {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabasePeerAuthentication)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabasePodAntiAffinity) DeepCopyInto(out *DatabasePodAntiAffinity) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabasePodAntiAffinity.
+func (in *DatabasePodAntiAffinity) DeepCopy() *DatabasePodAntiAffinity {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabasePodAntiAffinity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseProbe) DeepCopyInto(out *DatabaseProbe) {
+	*out = *in
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Port != nil {
+		in, out := &in.Port, &out.Port
+		*out = new(int32)
+		**out = **in
+	}
+	if in.StartupTimeoutSeconds != nil {
+		in, out := &in.StartupTimeoutSeconds, &out.StartupTimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseProbe.
+func (in *DatabaseProbe) DeepCopy() *DatabaseProbe {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseProbe)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabasePrometheusRule) DeepCopyInto(out *DatabasePrometheusRule) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	out.DownFor = in.DownFor
+	if in.DisabledAlerts != nil {
+		in, out := &in.DisabledAlerts, &out.DisabledAlerts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabasePrometheusRule.
+func (in *DatabasePrometheusRule) DeepCopy() *DatabasePrometheusRule {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabasePrometheusRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseSecretStoreRef) DeepCopyInto(out *DatabaseSecretStoreRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseSecretStoreRef.
+func (in *DatabaseSecretStoreRef) DeepCopy() *DatabaseSecretStoreRef {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseSecretStoreRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseServiceSpec) DeepCopyInto(out *DatabaseServiceSpec) {
+	*out = *in
+	if in.GRPC != nil {
+		in, out := &in.GRPC, &out.GRPC
+		*out = new(DatabaseGRPCServiceSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HTTPAppProtocol != nil {
+		in, out := &in.HTTPAppProtocol, &out.HTTPAppProtocol
+		*out = new(string)
+		**out = **in
+	}
+	if in.GRPCAppProtocol != nil {
+		in, out := &in.GRPCAppProtocol, &out.GRPCAppProtocol
+		*out = new(string)
+		**out = **in
+	}
+	if in.ExternalDNS != nil {
+		in, out := &in.ExternalDNS, &out.ExternalDNS
+		*out = new(DatabaseExternalDNS)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.IPFamilies != nil {
+		in, out := &in.IPFamilies, &out.IPFamilies
+		*out = make([]corev1.IPFamily, len(*in))
+		copy(*out, *in)
+	}
+	if in.IPFamilyPolicy != nil {
+		in, out := &in.IPFamilyPolicy, &out.IPFamilyPolicy
+		*out = new(corev1.IPFamilyPolicy)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseServiceSpec.
+func (in *DatabaseServiceSpec) DeepCopy() *DatabaseServiceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseServiceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseSnapshotStatus) DeepCopyInto(out *DatabaseSnapshotStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseSnapshotStatus.
+func (in *DatabaseSnapshotStatus) DeepCopy() *DatabaseSnapshotStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseSnapshotStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DatabaseSpec) DeepCopyInto(out *DatabaseSpec) {
 	*out = *in
+	if in.SecretAnnotations != nil {
+		in, out := &in.SecretAnnotations, &out.SecretAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ExternalAuthSecret != nil {
+		in, out := &in.ExternalAuthSecret, &out.ExternalAuthSecret
+		*out = new(DatabaseExternalAuthSecret)
+		**out = **in
+	}
+	if in.SharedAuthSecretRef != nil {
+		in, out := &in.SharedAuthSecretRef, &out.SharedAuthSecretRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+	if in.InitSQL != nil {
+		in, out := &in.InitSQL, &out.InitSQL
+		*out = new(DatabaseInitSQL)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TokenClaims != nil {
+		in, out := &in.TokenClaims, &out.TokenClaims
+		*out = new(DatabaseTokenClaims)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Storage.DeepCopyInto(&out.Storage)
+	if in.Service != nil {
+		in, out := &in.Service, &out.Service
+		*out = new(DatabaseServiceSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Ingress != nil {
 		in, out := &in.Ingress, &out.Ingress
 		*out = new(AhtiDatabaseIngressSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.AdditionalIngresses != nil {
+		in, out := &in.AdditionalIngresses, &out.AdditionalIngresses
+		*out = make([]AhtiDatabaseAdditionalIngressSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Istio != nil {
+		in, out := &in.Istio, &out.Istio
+		*out = new(DatabaseIstio)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Monitoring != nil {
+		in, out := &in.Monitoring, &out.Monitoring
+		*out = new(DatabaseMonitoring)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ConnectionConfigMap != nil {
+		in, out := &in.ConnectionConfigMap, &out.ConnectionConfigMap
+		*out = new(DatabaseConnectionConfigMap)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Resource.DeepCopyInto(&out.Resource)
 	if in.Env != nil {
 		in, out := &in.Env, &out.Env
@@ -130,6 +696,36 @@ func (in *DatabaseSpec) DeepCopyInto(out *DatabaseSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.MaxConcurrentConnections != nil {
+		in, out := &in.MaxConcurrentConnections, &out.MaxConcurrentConnections
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxConcurrentRequests != nil {
+		in, out := &in.MaxConcurrentRequests, &out.MaxConcurrentRequests
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Probe != nil {
+		in, out := &in.Probe, &out.Probe
+		*out = new(DatabaseProbe)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Drain != nil {
+		in, out := &in.Drain, &out.Drain
+		*out = new(DatabaseDrain)
+		**out = **in
+	}
+	if in.TerminationGracePeriodSeconds != nil {
+		in, out := &in.TerminationGracePeriodSeconds, &out.TerminationGracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.PodTemplateOverrides != nil {
+		in, out := &in.PodTemplateOverrides, &out.PodTemplateOverrides
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.NodeSelector != nil {
 		in, out := &in.NodeSelector, &out.NodeSelector
 		*out = make(map[string]string, len(*in))
@@ -152,6 +748,18 @@ func (in *DatabaseSpec) DeepCopyInto(out *DatabaseSpec) {
 		*out = new(corev1.Affinity)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.PodAntiAffinity != nil {
+		in, out := &in.PodAntiAffinity, &out.PodAntiAffinity
+		*out = new(DatabasePodAntiAffinity)
+		**out = **in
+	}
+	if in.PodLabels != nil {
+		in, out := &in.PodLabels, &out.PodLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.Tolerations != nil {
 		in, out := &in.Tolerations, &out.Tolerations
 		*out = make([]corev1.Toleration, len(*in))
@@ -159,6 +767,18 @@ func (in *DatabaseSpec) DeepCopyInto(out *DatabaseSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.RuntimeClassName != nil {
+		in, out := &in.RuntimeClassName, &out.RuntimeClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.HostAliases != nil {
+		in, out := &in.HostAliases, &out.HostAliases
+		*out = make([]corev1.HostAlias, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseSpec.
@@ -181,6 +801,44 @@ func (in *DatabaseStatus) DeepCopyInto(out *DatabaseStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.PrimaryEndpoint != nil {
+		in, out := &in.PrimaryEndpoint, &out.PrimaryEndpoint
+		*out = new(DatabaseEndpoint)
+		**out = **in
+	}
+	if in.LastSnapshot != nil {
+		in, out := &in.LastSnapshot, &out.LastSnapshot
+		*out = new(DatabaseSnapshotStatus)
+		**out = **in
+	}
+	if in.InitSQL != nil {
+		in, out := &in.InitSQL, &out.InitSQL
+		*out = new(DatabaseInitSQLStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CloneSource != nil {
+		in, out := &in.CloneSource, &out.CloneSource
+		*out = new(DatabaseCloneStatus)
+		**out = **in
+	}
+	if in.ManagedResources != nil {
+		in, out := &in.ManagedResources, &out.ManagedResources
+		*out = make([]DatabaseManagedResource, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastError != nil {
+		in, out := &in.LastError, &out.LastError
+		*out = new(DatabaseError)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ReadyTime != nil {
+		in, out := &in.ReadyTime, &out.ReadyTime
+		*out = (*in).DeepCopy()
+	}
+	if in.StorageUsageCheckTime != nil {
+		in, out := &in.StorageUsageCheckTime, &out.StorageUsageCheckTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseStatus.
@@ -197,6 +855,40 @@ func (in *DatabaseStatus) DeepCopy() *DatabaseStatus {
 func (in *DatabaseStorage) DeepCopyInto(out *DatabaseStorage) {
 	*out = *in
 	out.Size = in.Size.DeepCopy()
+	if in.VolumeSnapshotClassName != nil {
+		in, out := &in.VolumeSnapshotClassName, &out.VolumeSnapshotClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.PVCLabels != nil {
+		in, out := &in.PVCLabels, &out.PVCLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PVCAnnotations != nil {
+		in, out := &in.PVCAnnotations, &out.PVCAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.StorageClassName != nil {
+		in, out := &in.StorageClassName, &out.StorageClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PressureThreshold != nil {
+		in, out := &in.PressureThreshold, &out.PressureThreshold
+		*out = new(int32)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseStorage.
@@ -208,3 +900,28 @@ func (in *DatabaseStorage) DeepCopy() *DatabaseStorage {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseTokenClaims) DeepCopyInto(out *DatabaseTokenClaims) {
+	*out = *in
+	if in.Audience != nil {
+		in, out := &in.Audience, &out.Audience
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Permissions != nil {
+		in, out := &in.Permissions, &out.Permissions
+		*out = new(apiextensionsv1.JSON)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseTokenClaims.
+func (in *DatabaseTokenClaims) DeepCopy() *DatabaseTokenClaims {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseTokenClaims)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -20,13 +20,17 @@ import (
 	"crypto/tls"
 	"flag"
 	"os"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
@@ -36,6 +40,7 @@ import (
 
 	libsqlv1 "github.com/ahti-database/operator/api/v1"
 	"github.com/ahti-database/operator/internal/controller"
+	"github.com/ahti-database/operator/internal/utils"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -57,6 +62,19 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var minStorageSize string
+	var defaultDatabaseImage string
+	var defaultImagePullSecrets string
+	var defaultTolerations string
+	var namespaceLabelMappings string
+	var maintenanceImage string
+	var initSQLImage string
+	var imageRegistryRewrites string
+	var finalizerName string
+	var managedByLabel string
+	var notReadyRequeueAfter time.Duration
+	var databaseReadinessAddr string
+	var enableStoragePressure bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
@@ -66,6 +84,37 @@ func main() {
 		"If set the metrics endpoint is served securely")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&minStorageSize, "min-storage-size", "1Mi",
+		"The smallest storage size a Database is allowed to request.")
+	flag.StringVar(&defaultDatabaseImage, "default-database-image", "",
+		"The libsql-server image used by Databases that do not set spec.image.")
+	flag.StringVar(&defaultImagePullSecrets, "default-image-pull-secrets", "",
+		"Comma separated names of image pull secrets added to every Database, next to its spec.imagePullSecrets.")
+	flag.StringVar(&defaultTolerations, "default-tolerations", "",
+		"Comma separated key[=value][:effect] tolerations added to every Database, "+
+			"a toleration in spec.tolerations with the same key and effect overrides its default.")
+	flag.StringVar(&namespaceLabelMappings, "namespace-label-mappings", "",
+		"Comma separated <namespace label>[=<resource label>] labels copied from the namespace of a Database "+
+			"onto the resources generated for it, e.g. team,cost-center=billing.example.com/cost-center.")
+	flag.StringVar(&maintenanceImage, "maintenance-image", controller.DefaultMaintenanceImage,
+		"The image serving the maintenance page of Databases annotated with libsql.ahti.io/maintenance.")
+	flag.StringVar(&initSQLImage, "init-sql-image", controller.DefaultInitSQLImage,
+		"The image of the Jobs executing spec.initSQL, it needs curl and a POSIX shell.")
+	flag.StringVar(&imageRegistryRewrites, "image-registry-rewrites", "",
+		"Comma separated <from>=<to> registry prefixes rewritten in the images run for Databases, "+
+			"e.g. ghcr.io=mirror.example.com/ghcr.")
+	flag.StringVar(&finalizerName, "finalizer-name", "libsql.ahti.io/finalizer",
+		"The finalizer added to Databases. Lets two operator versions run side by side on a shared cluster.")
+	flag.StringVar(&managedByLabel, "managed-by-label", "ahti.database.io/managed-by",
+		"The label key selecting the resources of a Database. Must not change for existing Databases.")
+	flag.DurationVar(&notReadyRequeueAfter, "not-ready-requeue-after", 10*time.Second,
+		"How often a Database whose pods are rolling out is reconciled to poll its readiness, 0 disables polling.")
+	flag.StringVar(&databaseReadinessAddr, "database-readiness-bind-address", "0",
+		"The address serving GET /ready/<namespace>/<name>, 200 while a Database is available, for external health checks. "+
+			"Use \"0\" to disable it.")
+	flag.BoolVar(&enableStoragePressure, "enable-storage-pressure", false,
+		"Read the usage of the data volumes from the kubelets to set the StoragePressure condition of Databases "+
+			"with spec.storage.pressureThreshold. Needs the storage-pressure-role granting get on nodes/proxy.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -74,6 +123,27 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	minStorageSizeQuantity, err := resource.ParseQuantity(minStorageSize)
+	if err != nil {
+		setupLog.Error(err, "unable to parse min-storage-size")
+		os.Exit(1)
+	}
+	imageRegistryRewritesMap, err := utils.ParseImageRegistryRewrites(imageRegistryRewrites)
+	if err != nil {
+		setupLog.Error(err, "unable to parse image-registry-rewrites")
+		os.Exit(1)
+	}
+	defaultTolerationList, err := utils.ParseTolerations(defaultTolerations)
+	if err != nil {
+		setupLog.Error(err, "unable to parse default-tolerations")
+		os.Exit(1)
+	}
+	namespaceLabelMappingsMap, err := utils.ParseLabelMappings(namespaceLabelMappings)
+	if err != nil {
+		setupLog.Error(err, "unable to parse namespace-label-mappings")
+		os.Exit(1)
+	}
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -122,15 +192,50 @@ func main() {
 		os.Exit(1)
 	}
 
+	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create clientset")
+		os.Exit(1)
+	}
+
+	var volumeStats controller.VolumeStatsReader
+	if enableStoragePressure {
+		volumeStats = &controller.KubeletVolumeStatsReader{Client: clientset.CoreV1().RESTClient()}
+	}
+
 	if err = (&controller.DatabaseReconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Recorder: mgr.GetEventRecorderFor("database-controller"),
+		Client:                  mgr.GetClient(),
+		Scheme:                  mgr.GetScheme(),
+		Recorder:                mgr.GetEventRecorderFor("database-controller"),
+		MinStorageSize:          minStorageSizeQuantity,
+		DefaultImage:            defaultDatabaseImage,
+		DefaultImagePullSecrets: splitFlagList(defaultImagePullSecrets),
+		DefaultTolerations:      defaultTolerationList,
+		NamespaceLabelMappings:  namespaceLabelMappingsMap,
+		NotReadyRequeueAfter:    notReadyRequeueAfter,
+		VolumeStats:             volumeStats,
+		MaintenanceImage:        maintenanceImage,
+		InitSQLImage:            initSQLImage,
+		ImageRegistryRewrites:   imageRegistryRewritesMap,
+		FinalizerName:           finalizerName,
+		ManagedByLabel:          managedByLabel,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Database")
 		os.Exit(1)
 	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&libsqlv1.Database{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Database")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
+	if databaseReadinessAddr != "0" {
+		if err := mgr.Add(&controller.DatabaseReadinessServer{Addr: databaseReadinessAddr, Client: mgr.GetClient()}); err != nil {
+			setupLog.Error(err, "unable to set up database readiness server")
+			os.Exit(1)
+		}
+	}
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
@@ -147,3 +252,14 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// splitFlagList splits a comma separated flag value, dropping empty entries.
+func splitFlagList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
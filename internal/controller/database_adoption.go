@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// databaseAdoptAnnotation set to "true" on an existing resource lets the Database take it over,
+// like the managed-by label set to the name of the Database does
+const databaseAdoptAnnotation string = "libsql.ahti.io/adopt"
+
+// adoptDatabaseResource takes over a resource with the name of a generated one that the Database
+// does not own yet, e.g. a StatefulSet of a hand-rolled manifest being migrated to the operator.
+// Only resources marked with the managed-by label or the adopt annotation and without another
+// controller are adopted. The owner reference is added to object, the caller writes it with the
+// rest of the generated spec and reports whether it was adopted.
+func (r *DatabaseReconciler) adoptDatabaseResource(ctx context.Context, database *libsqlv1.Database, object client.Object, kind string) (adopted bool, err error) {
+	if isOwnedByDatabase(object, database) {
+		return false, nil
+	}
+	if object.GetLabels()[r.GetManagedByLabel()] != database.Name && object.GetAnnotations()[databaseAdoptAnnotation] != "true" {
+		return false, fmt.Errorf("%s %s already exists and is not owned by Database %s, label it %s=%s or annotate it %s=true to adopt it",
+			kind, object.GetName(), database.Name, r.GetManagedByLabel(), database.Name, databaseAdoptAnnotation)
+	}
+	if controller := metav1.GetControllerOf(object); controller != nil {
+		return false, fmt.Errorf("%s %s is controlled by %s %s and cannot be adopted by Database %s",
+			kind, object.GetName(), controller.Kind, controller.Name, database.Name)
+	}
+	object.SetOwnerReferences(append(object.GetOwnerReferences(), metav1.OwnerReference{
+		APIVersion: databaseAPIVersion,
+		Kind:       databaseKind,
+		Name:       database.Name,
+		UID:        database.UID,
+	}))
+	r.recorder(ctx).Event(database, utils.EventNormal, "Adopted",
+		fmt.Sprintf("adopt %s %s in the Namespace %s success",
+			kind,
+			object.GetName(),
+			database.Namespace))
+	return true, nil
+}
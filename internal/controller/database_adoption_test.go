@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+)
+
+var _ = Describe("Database adoption", func() {
+	ctx := context.Background()
+
+	It("should adopt the marked resources of a hand-rolled database", func() {
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "adopted-database", Namespace: "default"},
+			Spec: libsqlv1.DatabaseSpec{
+				Image:   "ghcr.io/tursodatabase/libsql-server:v0.24.21",
+				Storage: libsqlv1.DatabaseStorage{Size: resource.MustParse("1Gi")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, database)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, database)
+		reconciler := &DatabaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: MockEventRecorder{}}
+
+		By("Creating the StatefulSet and the Services without owner references")
+		statefulSet, err := reconciler.ConstructDatabaseStatefulSet(ctx, database, nil)
+		Expect(err).NotTo(HaveOccurred())
+		statefulSet.OwnerReferences = nil
+		statefulSet.Annotations = map[string]string{databaseAdoptAnnotation: "true"}
+		Expect(k8sClient.Create(ctx, statefulSet)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, statefulSet)
+		labeledService := reconciler.ConstructDatabaseService(ctx, database, true)
+		labeledService.OwnerReferences = nil
+		Expect(k8sClient.Create(ctx, labeledService)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, labeledService)
+		unmarkedService := reconciler.ConstructDatabaseService(ctx, database, false)
+		unmarkedService.OwnerReferences = nil
+		unmarkedService.Labels = nil
+		Expect(k8sClient.Create(ctx, unmarkedService)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, unmarkedService)
+
+		_, err = reconciler.ReconcileDatabaseStatefulSets(ctx, database, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, _, err = reconciler.ReconcileDatabaseService(ctx, database)
+		Expect(err).To(MatchError(ContainSubstring("is not owned by Database")))
+
+		By("Checking the marked resources are owned by the Database")
+		found := &appsv1.StatefulSet{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: database.Name, Namespace: database.Namespace}, found)).To(Succeed())
+		Expect(isOwnedByDatabase(found, database)).Should(BeTrue())
+		service := &corev1.Service{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: utils.GetDatabaseServiceName(database, true), Namespace: database.Namespace}, service)).To(Succeed())
+		Expect(isOwnedByDatabase(service, database)).Should(BeTrue())
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: unmarkedService.Name, Namespace: database.Namespace}, service)).To(Succeed())
+		Expect(isOwnedByDatabase(service, database)).Should(BeFalse())
+	})
+
+	It("should adopt a hand-rolled StatefulSet with another selector", func() {
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "hand-rolled-database", Namespace: "default"},
+			Spec: libsqlv1.DatabaseSpec{
+				Image:   "ghcr.io/tursodatabase/libsql-server:v0.24.21",
+				Storage: libsqlv1.DatabaseStorage{Size: resource.MustParse("1Gi")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, database)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, database)
+		reconciler := &DatabaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: MockEventRecorder{}}
+		handRolled := func(claimTemplate string) *appsv1.StatefulSet {
+			return &appsv1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Name: database.Name, Namespace: database.Namespace,
+					Annotations: map[string]string{databaseAdoptAnnotation: "true"}},
+				Spec: appsv1.StatefulSetSpec{
+					Selector:    &metav1.LabelSelector{MatchLabels: map[string]string{"app": "legacy-sqld"}},
+					ServiceName: "legacy-sqld",
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "legacy-sqld"}},
+						Spec: corev1.PodSpec{Containers: []corev1.Container{{
+							Name:         "sqld",
+							Image:        "ghcr.io/tursodatabase/libsql-server:v0.24.14",
+							VolumeMounts: []corev1.VolumeMount{{Name: claimTemplate, MountPath: "/var/lib/sqld"}},
+						}}},
+					},
+					VolumeClaimTemplates: []corev1.PersistentVolumeClaim{{
+						ObjectMeta: metav1.ObjectMeta{Name: claimTemplate},
+						Spec: corev1.PersistentVolumeClaimSpec{
+							AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+							Resources: corev1.VolumeResourceRequirements{
+								Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+							},
+						},
+					}},
+				},
+			}
+		}
+
+		By("Reporting a StatefulSet without the volume claim template of the data volume")
+		statefulSet := handRolled("data")
+		Expect(k8sClient.Create(ctx, statefulSet)).To(Succeed())
+		_, err := reconciler.ReconcileDatabaseStatefulSets(ctx, database, nil)
+		Expect(err).To(MatchError(ContainSubstring("has no volume claim template")))
+		Expect(meta.IsStatusConditionTrue(database.Status.Conditions, typeDegradedDatabase)).Should(BeTrue())
+		Expect(meta.FindStatusCondition(database.Status.Conditions, typeDegradedDatabase).Reason).Should(Equal(reasonIncompatibleStatefulSet))
+		Expect(k8sClient.Delete(ctx, statefulSet)).To(Succeed())
+
+		By("Keeping the selector of a StatefulSet with the volume claim template of the data volume")
+		statefulSet = handRolled(utils.GetDatabasePVCName(database))
+		Expect(k8sClient.Create(ctx, statefulSet)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, statefulSet)
+		_, err = reconciler.ReconcileDatabaseStatefulSets(ctx, database, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(meta.IsStatusConditionTrue(database.Status.Conditions, typeDegradedDatabase)).Should(BeFalse())
+		found := &appsv1.StatefulSet{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: database.Name, Namespace: database.Namespace}, found)).To(Succeed())
+		Expect(isOwnedByDatabase(found, database)).Should(BeTrue())
+		Expect(found.Spec.Selector.MatchLabels).Should(Equal(map[string]string{"app": "legacy-sqld"}))
+		Expect(found.Spec.Template.Labels).Should(HaveKeyWithValue("app", "legacy-sqld"))
+		container := utils.GetContainer(&found.Spec.Template.Spec, utils.GetDatabaseContainerName(database))
+		Expect(container.Image).Should(Equal("ghcr.io/tursodatabase/libsql-server:v0.24.21"))
+	})
+})
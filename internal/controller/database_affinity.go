@@ -0,0 +1,64 @@
+package controller
+
+import (
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// getDatabaseAffinity returns spec.affinity with the anti-affinity of spec.podAntiAffinity added.
+func (r *DatabaseReconciler) getDatabaseAffinity(database *libsqlv1.Database) *corev1.Affinity {
+	antiAffinity := database.Spec.PodAntiAffinity
+	if antiAffinity == nil {
+		return database.Spec.Affinity
+	}
+	affinity := &corev1.Affinity{}
+	if database.Spec.Affinity != nil {
+		affinity = database.Spec.Affinity.DeepCopy()
+	}
+	if affinity.PodAntiAffinity == nil {
+		affinity.PodAntiAffinity = &corev1.PodAntiAffinity{}
+	}
+	topologyKey := antiAffinity.TopologyKey
+	if topologyKey == "" {
+		topologyKey = corev1.LabelHostname
+	}
+	term := corev1.PodAffinityTerm{
+		LabelSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"node": "primary"},
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: r.GetManagedByLabel(), Operator: metav1.LabelSelectorOpExists},
+			},
+		},
+		TopologyKey: topologyKey,
+	}
+	switch antiAffinity.Scope {
+	case libsqlv1.DatabaseAntiAffinityScopeDatabase:
+		term.LabelSelector = &metav1.LabelSelector{MatchLabels: r.databaseSelectorLabels(database)}
+	case libsqlv1.DatabaseAntiAffinityScopeTenant:
+		term.LabelSelector.MatchLabels[antiAffinity.TenantLabel] = database.Labels[antiAffinity.TenantLabel]
+		term.NamespaceSelector = &metav1.LabelSelector{}
+	default:
+		// an empty namespace selector selects the pods of all namespaces
+		term.NamespaceSelector = &metav1.LabelSelector{}
+	}
+	if antiAffinity.Required {
+		affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution = append(
+			affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution, term)
+	} else {
+		affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(
+			affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution,
+			corev1.WeightedPodAffinityTerm{Weight: 100, PodAffinityTerm: term})
+	}
+	return affinity
+}
+
+// databaseAntiAffinityPodLabels returns the tenant label the pods of other Databases of the
+// tenant avoid, if any.
+func databaseAntiAffinityPodLabels(database *libsqlv1.Database) map[string]string {
+	antiAffinity := database.Spec.PodAntiAffinity
+	if antiAffinity == nil || antiAffinity.Scope != libsqlv1.DatabaseAntiAffinityScopeTenant {
+		return nil
+	}
+	return map[string]string{antiAffinity.TenantLabel: database.Labels[antiAffinity.TenantLabel]}
+}
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// cloneSnapshotPollInterval is how often a clone waiting for the snapshot of its source is requeued
+const cloneSnapshotPollInterval = 10 * time.Second
+
+// ReconcileDatabaseClone snapshots the data volume of the spec.storage.cloneFrom Database before
+// the StatefulSet of the clone is created, so the clone is provisioned from a point in time copy
+// of the source. It returns whether the StatefulSet can be created, which is once the snapshot
+// is ready to use. The snapshot is owned by the clone and only taken once.
+func (r *DatabaseReconciler) ReconcileDatabaseClone(ctx context.Context, database *libsqlv1.Database) (ready bool, err error) {
+	sourceName := database.Spec.Storage.CloneFrom
+	if sourceName == "" {
+		return true, nil
+	}
+	if err := r.Get(ctx, types.NamespacedName{Name: database.Name, Namespace: database.Namespace}, &appsv1.StatefulSet{}); err == nil {
+		// the data volume exists already, cloneFrom only applies when it is created
+		return true, nil
+	} else if !apierrors.IsNotFound(err) {
+		return false, err
+	}
+
+	snapshotName := utils.GetDatabaseCloneSnapshotName(database)
+	snapshot := &unstructured.Unstructured{}
+	snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+	if err := r.Get(ctx, types.NamespacedName{Name: snapshotName, Namespace: database.Namespace}, snapshot); err != nil {
+		if meta.IsNoMatchError(err) {
+			return false, fmt.Errorf("VolumeSnapshot CRDs are required to clone Database %s: %w", sourceName, err)
+		}
+		if !apierrors.IsNotFound(err) {
+			return false, err
+		}
+		source := &libsqlv1.Database{}
+		if err := r.Get(ctx, types.NamespacedName{Name: sourceName, Namespace: database.Namespace}, source); err != nil {
+			return false, fmt.Errorf("failed to get Database %s to clone: %w", sourceName, err)
+		}
+		snapshot = r.ConstructDatabaseSnapshot(source, snapshotName)
+		snapshot.SetLabels(r.databaseLabels(database))
+		snapshot.SetOwnerReferences([]metav1.OwnerReference{
+			{
+				APIVersion: databaseAPIVersion,
+				Kind:       databaseKind,
+				Name:       database.Name,
+				UID:        database.UID,
+			},
+		})
+		if err := r.Create(ctx, snapshot); err != nil {
+			return false, err
+		}
+		r.recorder(ctx).Event(database, utils.EventNormal, "SuccessfulCreate",
+			fmt.Sprintf("create VolumeSnapshot %s of Database %s is being created in the Namespace %s success",
+				snapshotName,
+				sourceName,
+				database.Namespace))
+	}
+	readyToUse, _, err := unstructured.NestedBool(snapshot.Object, "status", "readyToUse")
+	if err != nil {
+		return false, err
+	}
+	database.Status.CloneSource = &libsqlv1.DatabaseCloneStatus{Database: sourceName, Snapshot: snapshotName, ReadyToUse: readyToUse}
+	return readyToUse, nil
+}
@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// databaseConfigRefsIndex indexes Databases by the ConfigMaps and Secrets their env reads
+	// values from, as <Kind>/<name>
+	databaseConfigRefsIndex = "spec.env.valueFrom"
+
+	// databaseConfigHashAnnotation on the pod template holds the hash of the values read from
+	// ConfigMaps and Secrets, env values read from them are only resolved when a pod starts
+	databaseConfigHashAnnotation = "libsql.ahti.io/config-hash"
+)
+
+// databaseConfigRef is a key of a ConfigMap or Secret an env var of the Database reads.
+type databaseConfigRef struct {
+	kind string
+	name string
+	key  string
+}
+
+// getDatabaseConfigRefs returns the ConfigMap and Secret keys read by spec.env. The auth secret
+// is left out, its key is tracked by the auth key hash.
+func getDatabaseConfigRefs(database *libsqlv1.Database) []databaseConfigRef {
+	refs := []databaseConfigRef{}
+	for _, env := range database.Spec.Env {
+		if env.ValueFrom == nil {
+			continue
+		}
+		if ref := env.ValueFrom.ConfigMapKeyRef; ref != nil {
+			refs = append(refs, databaseConfigRef{kind: "ConfigMap", name: ref.Name, key: ref.Key})
+		}
+		if ref := env.ValueFrom.SecretKeyRef; ref != nil {
+			refs = append(refs, databaseConfigRef{kind: "Secret", name: ref.Name, key: ref.Key})
+		}
+	}
+	return refs
+}
+
+// IndexDatabaseConfigRefs is the indexer of databaseConfigRefsIndex.
+func IndexDatabaseConfigRefs(object client.Object) []string {
+	database := object.(*libsqlv1.Database)
+	values := []string{}
+	for _, ref := range getDatabaseConfigRefs(database) {
+		value := fmt.Sprintf("%s/%s", ref.kind, ref.name)
+		if !slices.Contains(values, value) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// MapDatabaseConfigRefsToReconcile returns the Databases of the namespace whose env reads the
+// ConfigMap or Secret, so that a changed value rolls their pods.
+func (r *DatabaseReconciler) MapDatabaseConfigRefsToReconcile(ctx context.Context, object client.Object) []reconcile.Request {
+	kind := "ConfigMap"
+	if _, ok := object.(*corev1.Secret); ok {
+		kind = "Secret"
+	}
+	databases := &libsqlv1.DatabaseList{}
+	if err := r.List(ctx, databases, client.InNamespace(object.GetNamespace()),
+		client.MatchingFields{databaseConfigRefsIndex: fmt.Sprintf("%s/%s", kind, object.GetName())}); err != nil {
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, database := range databases.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: database.Namespace, Name: database.Name},
+		})
+	}
+	return requests
+}
+
+// setDatabaseConfigHash annotates the pod template with the hash of the values spec.env reads
+// from ConfigMaps and Secrets, so that the pods are rolled when one of them changes. A missing
+// object or key is hashed as such, the pod fails to start on it unless it is optional.
+func (r *DatabaseReconciler) setDatabaseConfigHash(ctx context.Context, database *libsqlv1.Database, template *corev1.PodTemplateSpec) error {
+	refs := getDatabaseConfigRefs(database)
+	if len(refs) == 0 {
+		return nil
+	}
+	values := []string{}
+	for _, ref := range refs {
+		value, found, err := r.getDatabaseConfigValue(ctx, database, ref)
+		if err != nil {
+			return err
+		}
+		hash := "<missing>"
+		if found {
+			hash = utils.HashValue(value)
+		}
+		values = append(values, fmt.Sprintf("%s/%s/%s=%s", ref.kind, ref.name, ref.key, hash))
+	}
+	metav1.SetMetaDataAnnotation(&template.ObjectMeta, databaseConfigHashAnnotation,
+		utils.HashValue([]byte(strings.Join(values, "\n"))))
+	return nil
+}
+
+// getDatabaseConfigValue returns the value of the ConfigMap or Secret key, and whether it exists.
+func (r *DatabaseReconciler) getDatabaseConfigValue(ctx context.Context, database *libsqlv1.Database, ref databaseConfigRef) ([]byte, bool, error) {
+	name := types.NamespacedName{Name: ref.name, Namespace: database.Namespace}
+	if ref.kind == "Secret" {
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, name, secret); err != nil {
+			return nil, false, client.IgnoreNotFound(err)
+		}
+		value, ok := secret.Data[ref.key]
+		return value, ok, nil
+	}
+	configMap := &corev1.ConfigMap{}
+	if err := r.Get(ctx, name, configMap); err != nil {
+		return nil, false, client.IgnoreNotFound(err)
+	}
+	if value, ok := configMap.BinaryData[ref.key]; ok {
+		return value, true, nil
+	}
+	value, ok := configMap.Data[ref.key]
+	return []byte(value), ok, nil
+}
@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ReconcileDatabaseConnectionConfigMap keeps a ConfigMap with the connection details of the
+// Database when spec.connectionConfigMap is set, and deletes it otherwise.
+func (r *DatabaseReconciler) ReconcileDatabaseConnectionConfigMap(ctx context.Context, database *libsqlv1.Database) (*corev1.ConfigMap, error) {
+	found := &corev1.ConfigMap{}
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      utils.GetDatabaseConnectionConfigMapName(database),
+		Namespace: database.Namespace,
+	}, found); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		if database.Spec.ConnectionConfigMap == nil {
+			return nil, nil
+		}
+		configMap := r.ConstructDatabaseConnectionConfigMap(database)
+		if err := r.Create(ctx, configMap); err != nil {
+			return nil, err
+		}
+		r.recorder(ctx).Event(database, utils.EventNormal, "SuccessfulCreate",
+			fmt.Sprintf("create ConfigMap %s is being created in the Namespace %s success",
+				configMap.Name,
+				database.Namespace))
+		return configMap, nil
+	}
+	if database.Spec.ConnectionConfigMap == nil {
+		if !isOwnedByDatabase(found, database) {
+			return nil, nil
+		}
+		// delete connection config map if database does not need it
+		return nil, r.Delete(ctx, found)
+	}
+	if !isOwnedByDatabase(found, database) {
+		return nil, fmt.Errorf("config map %s already exists and is not owned by Database %s", found.Name, database.Name)
+	}
+	configMap := r.ConstructDatabaseConnectionConfigMap(database)
+	labels := utils.MergeLabels(found.Labels, configMap.Labels)
+	if equality.Semantic.DeepEqual(found.Data, configMap.Data) &&
+		equality.Semantic.DeepEqual(found.Labels, labels) &&
+		equality.Semantic.DeepEqual(found.Annotations, configMap.Annotations) {
+		return found, nil
+	}
+	patch := client.MergeFrom(found.DeepCopy())
+	found.Data = configMap.Data
+	found.Labels = labels
+	found.Annotations = configMap.Annotations
+	if err := r.Patch(ctx, found, patch); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+func (r *DatabaseReconciler) ConstructDatabaseConnectionConfigMap(database *libsqlv1.Database) *corev1.ConfigMap {
+	host := utils.GetDatabaseServiceFQDN(database, false)
+	grpcHost := host
+	if isDatabaseGRPCServiceSeparate(database) {
+		grpcHost = fmt.Sprintf("%v.%v.svc.%v", utils.GetDatabaseGRPCServiceName(database), database.Namespace, utils.ClusterDomain)
+	}
+	data := map[string]string{
+		"HOST":      host,
+		"HTTP_PORT": strconv.Itoa(int(databaseHTTPPort)),
+		"URL":       fmt.Sprintf("http://%s:%d", host, databaseHTTPPort),
+		"GRPC_HOST": grpcHost,
+		"GRPC_PORT": strconv.Itoa(int(databaseGRPCPort)),
+		"AUTH":      strconv.FormatBool(database.Spec.Auth),
+	}
+	if database.Spec.Auth {
+		data["AUTH_SECRET"] = utils.GetAuthPublicKeySecretRef(database)
+	}
+	connection := database.Spec.ConnectionConfigMap
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      utils.GetDatabaseConnectionConfigMapName(database),
+			Namespace: database.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: databaseAPIVersion,
+					Kind:       databaseKind,
+					Name:       database.Name,
+					UID:        database.UID,
+				},
+			},
+			Labels:      r.databaseLabelsWithOverrides(database, connection.Labels),
+			Annotations: connection.Annotations,
+		},
+		Data: data,
+	}
+}
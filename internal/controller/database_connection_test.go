@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+)
+
+var _ = Describe("Database connection ConfigMap", func() {
+	ctx := context.Background()
+
+	It("should publish the connection details and delete them once disabled", func() {
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "connection-database", Namespace: "default"},
+			Spec: libsqlv1.DatabaseSpec{
+				Image:               "ghcr.io/tursodatabase/libsql-server:v0.24.21",
+				Auth:                true,
+				Storage:             libsqlv1.DatabaseStorage{Size: resource.MustParse("1Gi")},
+				ConnectionConfigMap: &libsqlv1.DatabaseConnectionConfigMap{Labels: map[string]string{"app": "orders"}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, database)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, database)
+		reconciler := &DatabaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: MockEventRecorder{}}
+		configMapName := types.NamespacedName{Name: utils.GetDatabaseConnectionConfigMapName(database), Namespace: database.Namespace}
+
+		_, err := reconciler.ReconcileDatabaseConnectionConfigMap(ctx, database)
+		Expect(err).NotTo(HaveOccurred())
+		configMap := &corev1.ConfigMap{}
+		Expect(k8sClient.Get(ctx, configMapName, configMap)).To(Succeed())
+		Expect(configMap.Labels).Should(HaveKeyWithValue("app", "orders"))
+		Expect(configMap.Data).Should(Equal(map[string]string{
+			"HOST":        "connection-database-svc.default.svc.cluster.local",
+			"HTTP_PORT":   "8080",
+			"URL":         "http://connection-database-svc.default.svc.cluster.local:8080",
+			"GRPC_HOST":   "connection-database-svc.default.svc.cluster.local",
+			"GRPC_PORT":   "5001",
+			"AUTH":        "true",
+			"AUTH_SECRET": utils.GetAuthSecretName(database),
+		}))
+
+		By("Moving the gRPC port to its own Service, the ConfigMap follows")
+		database.Spec.Service = &libsqlv1.DatabaseServiceSpec{GRPC: &libsqlv1.DatabaseGRPCServiceSpec{}}
+		_, err = reconciler.ReconcileDatabaseConnectionConfigMap(ctx, database)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Get(ctx, configMapName, configMap)).To(Succeed())
+		Expect(configMap.Data).Should(HaveKeyWithValue("GRPC_HOST", "connection-database-svc-grpc.default.svc.cluster.local"))
+
+		By("Disabling the ConfigMap, it is deleted")
+		database.Spec.ConnectionConfigMap = nil
+		_, err = reconciler.ReconcileDatabaseConnectionConfigMap(ctx, database)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(apierrors.IsNotFound(k8sClient.Get(ctx, configMapName, configMap))).Should(BeTrue())
+	})
+})
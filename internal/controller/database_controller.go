@@ -18,14 +18,21 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
@@ -41,6 +48,16 @@ const (
 	databaseFinalizer  string = "libsql.ahti.io/finalizer"
 	databaseLabel      string = "ahti.database.io/managed-by"
 	databaseAppName    string = "ahti-database"
+
+	// databaseSnapshotAnnotation requests a VolumeSnapshot of the data volume, named after its value
+	databaseSnapshotAnnotation string = "libsql.ahti.io/snapshot"
+	// databaseKeepPVCAnnotation keeps the data volumes when the Database is deleted
+	databaseKeepPVCAnnotation string = "libsql.ahti.io/keep-pvc"
+	// databaseAuthKeyHashAnnotation on the pod template rolls the pods when the auth keys change
+	databaseAuthKeyHashAnnotation string = "libsql.ahti.io/auth-key-hash"
+	// databaseSpecHashAnnotation records the hash of the generated StatefulSet, so that it is only
+	// updated when the Database or the operator settings it is generated from change
+	databaseSpecHashAnnotation string = "libsql.ahti.io/spec-hash"
 )
 
 // Definitions to manage status conditions
@@ -49,13 +66,115 @@ const (
 	typeAvailableDatabase = "Available"
 	// typeDegradedDatabase represents the status used when the custom resource is deleted and the finalizer operations are yet to occur.
 	typeDegradedDatabase = "Degraded"
+	// typeProgressingDatabase represents the status of the rollout of the database pods
+	typeProgressingDatabase = "Progressing"
+)
+
+// Reasons set on the status conditions. They are part of the API, automation may key on them.
+const (
+	reasonReconciling             = "Reconciling"
+	reasonReady                   = "Ready"
+	reasonRolloutInProgress       = "RolloutInProgress"
+	reasonPVCPending              = "PVCPending"
+	reasonInvalidSpec             = "InvalidSpec"
+	reasonInvalidStorageSize      = "InvalidStorageSize"
+	reasonStorageSizeValid        = "StorageSizeValid"
+	reasonFinalizing              = "Finalizing"
+	reasonReconcileFailed         = "ReconcileFailed"
+	reasonSecretReconcileFailed   = "SecretReconcileFailed"
+	reasonStatefulSetCreateFailed = "StatefulSetCreateFailed"
+	reasonStatefulSetUpdateFailed = "StatefulSetUpdateFailed"
+	reasonServiceReconcileFailed  = "ServiceReconcileFailed"
+	reasonIngressReconcileFailed  = "IngressReconcileFailed"
+	reasonResourceQuotaExceeded   = "ResourceQuotaExceeded"
+	reasonResourceQuotaSatisfied  = "ResourceQuotaSatisfied"
+	reasonContainersRunning       = "ContainersRunning"
+	reasonWaitingForAuthSecret    = "WaitingForAuthSecret"
+	reasonWaitingForCloneSnapshot = "WaitingForCloneSnapshot"
+	reasonForeignController       = "ForeignController"
+	reasonControllerOwned         = "ControllerOwned"
+	reasonIncompatibleStatefulSet = "IncompatibleStatefulSet"
+	reasonCompatibleStatefulSet   = "CompatibleStatefulSet"
+	reasonCRDNotInstalled         = "CRDNotInstalled"
 )
 
+// maxLastErrorLength bounds the error message kept in the status of a Database
+const maxLastErrorLength = 512
+
+// ReconcileError is returned by the sub reconcilers when a failure maps to a more specific
+// condition reason than the one of the sub reconciler as a whole.
+type ReconcileError struct {
+	Reason string
+	Err    error
+}
+
+func (e *ReconcileError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ReconcileError) Unwrap() error {
+	return e.Err
+}
+
 // DatabaseReconciler reconciles a Database object
 type DatabaseReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+
+	// MinStorageSize is the smallest spec.storage.size accepted for a Database.
+	MinStorageSize resource.Quantity
+	// DefaultImage is the libsql-server image used by Databases that do not set spec.image.
+	DefaultImage string
+	// MaintenanceImage serves the maintenance page, defaults to DefaultMaintenanceImage.
+	MaintenanceImage string
+	// InitSQLImage runs the init SQL Jobs, defaults to DefaultInitSQLImage.
+	InitSQLImage string
+	// ImageRegistryRewrites maps registry prefixes of the images run for Databases to the
+	// prefixes they are pulled from instead, e.g. while migrating to another registry.
+	ImageRegistryRewrites map[string]string
+	// DefaultImagePullSecrets are added to the pull secrets of every Database.
+	DefaultImagePullSecrets []string
+	// DefaultTolerations are added to the tolerations of every Database, unless it tolerates the
+	// same taint key and effect itself.
+	DefaultTolerations []corev1.Toleration
+	// NamespaceLabelMappings maps labels of namespaces to labels set on the resources generated
+	// for the Databases in them, e.g. to stamp a cost center on all of them. Labels set in the
+	// Database spec take precedence. They are part of the pod template, so a change of a mapped
+	// namespace label rolls the database pods.
+	NamespaceLabelMappings map[string]string
+	// namespaceLabels holds the labels mapped from each namespace by NamespaceLabelMappings, as
+	// last read by Reconcile.
+	namespaceLabels sync.Map
+	// NotReadyRequeueAfter is how often a Database is reconciled again while its pods are rolling
+	// out, to poll its readiness besides the watch of the StatefulSet. Zero disables polling.
+	NotReadyRequeueAfter time.Duration
+	// VolumeStats reads the usage of the data volumes of Databases with a storage pressure
+	// threshold. The StoragePressure condition is not set without it. Reading the kubelet stats
+	// needs get on nodes/proxy, which is granted by the separate storage-pressure-role and lets the
+	// operator reach every kubelet API through the API server, so it is only set when enabled.
+	VolumeStats VolumeStatsReader
+	// FinalizerName overrides the finalizer added to Databases, defaults to databaseFinalizer.
+	FinalizerName string
+	// ManagedByLabel overrides the label key selecting the resources of a Database, defaults to databaseLabel.
+	// It is part of the immutable StatefulSet selector, so it must not change for existing Databases.
+	ManagedByLabel string
+}
+
+// GetFinalizerName returns the finalizer added to Databases.
+func (r *DatabaseReconciler) GetFinalizerName() string {
+	if r.FinalizerName != "" {
+		return r.FinalizerName
+	}
+	return databaseFinalizer
+}
+
+// GetManagedByLabel returns the label key selecting the resources of a Database.
+func (r *DatabaseReconciler) GetManagedByLabel() string {
+	if r.ManagedByLabel != "" {
+		return r.ManagedByLabel
+	}
+	return databaseLabel
 }
 
 //+kubebuilder:rbac:groups=libsql.ahti.io,resources=databases,verbs=get;list;watch;create;update;patch;delete
@@ -71,9 +190,21 @@ type DatabaseReconciler struct {
 //+kubebuilder:rbac:groups="apps",resources=statefulsets/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups="apps",resources=deployments,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="apps",resources=deployments/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups="batch",resources=jobs,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="networking.k8s.io",resources=ingresses,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="networking.k8s.io",resources=ingresses/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups="networking.k8s.io",resources=ingressclasses,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=events,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=resourcequotas,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups="node.k8s.io",resources=runtimeclasses,verbs=get;list;watch
+//+kubebuilder:rbac:groups="monitoring.coreos.com",resources=prometheusrules,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="monitoring.coreos.com",resources=servicemonitors,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="external-secrets.io",resources=externalsecrets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="security.istio.io",resources=peerauthentications,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="snapshot.storage.k8s.io",resources=volumesnapshots,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -85,6 +216,7 @@ type DatabaseReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.17.3/pkg/reconcile
 func (r *DatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx = withEventCorrelationID(ctx)
 	log := log.FromContext(ctx)
 
 	// Get the Database object
@@ -93,9 +225,22 @@ func (r *DatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	// Nothing can be created in a namespace that is being deleted and the resources of the
+	// Database are deleted along with it, only the finalizer is left to remove.
+	namespace, err := r.getDatabaseNamespace(ctx, database)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if isNamespaceTerminating(namespace) {
+		requeue, err := r.ReleaseDatabaseFinalizer(ctx, database)
+		return ctrl.Result{Requeue: requeue}, err
+	}
+	r.setNamespaceResourceLabels(namespace)
+
 	// Let's just set the status as Unknown when no status is available
 	if len(database.Status.Conditions) == 0 || database.Status.Conditions == nil {
-		changed := meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{Type: typeAvailableDatabase, Status: metav1.ConditionUnknown, Reason: "Reconciling", Message: "Starting reconciliation"})
+		changed := meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{Type: typeAvailableDatabase, Status: metav1.ConditionUnknown, Reason: reasonReconciling, Message: "Starting reconciliation"})
+		database.Status.Phase = GetDatabasePhase(database)
 		if changed {
 			if err := r.Status().Update(ctx, database); err != nil {
 				// requeue for case of stale data without raising errors
@@ -117,55 +262,266 @@ func (r *DatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return ctrl.Result{Requeue: true}, nil
 	}
 
-	_, err = r.ReconcileDatabaseSecrets(ctx, database)
+	if err := r.ValidateDatabase(database); err != nil {
+		log.Error(err, "Invalid Database spec")
+		reason := reasonInvalidSpec
+		reconcileErr := &ReconcileError{}
+		if errors.As(err, &reconcileErr) {
+			reason = reconcileErr.Reason
+		}
+		r.recorder(ctx).Event(database, utils.EventWarning, reason, err.Error())
+		changed := meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{Type: typeAvailableDatabase,
+			Status: metav1.ConditionFalse, Reason: reason, Message: err.Error()})
+		if reason == reasonInvalidStorageSize {
+			// a milli unit size would request a PVC of a fraction of a byte, which is never what was meant
+			changed = meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{Type: typeDegradedDatabase,
+				Status: metav1.ConditionTrue, Reason: reason, Message: err.Error()}) || changed
+		}
+		database.Status.Phase = GetDatabasePhase(database)
+		if changed {
+			if err := r.Status().Update(ctx, database); err != nil {
+				if apierrors.IsConflict(err) {
+					return ctrl.Result{Requeue: true}, nil
+				}
+				log.Error(err, "Failed to update Database status")
+				return ctrl.Result{}, err
+			}
+		}
+		// the spec has to be changed by the user, which will trigger a new reconcile
+		return ctrl.Result{}, nil
+	}
+
+	// sub reconcilers record their observations on the status, which is written once at the end
+	originalStatus := database.Status.DeepCopy()
+	clearDatabaseInvalidStorageSizeCondition(database)
+
+	authSecret, err := r.ReconcileDatabaseSecrets(ctx, database)
 	if err != nil {
 		log.Error(err, "Failed to reconcile database auth secret")
-		return ctrl.Result{}, err
+		return r.ReconcileFailed(ctx, database, reasonSecretReconcileFailed, err)
+	}
+	if database.Spec.Auth && authSecret == nil {
+		// the database cannot start until its externally sourced auth keys are synced
+		message := fmt.Sprintf("Waiting for ExternalSecret %s to sync the auth keys", utils.GetAuthSecretName(database))
+		if database.Spec.SharedAuthSecretRef != nil {
+			message = fmt.Sprintf("Waiting for the shared auth Secret %s to be created", database.Spec.SharedAuthSecretRef.Name)
+		}
+		meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{Type: typeProgressingDatabase,
+			Status: metav1.ConditionTrue, Reason: reasonWaitingForAuthSecret, Message: message})
+		if _, err := r.UpdateDatabaseStatus(ctx, database, originalStatus); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: authSecretSyncInterval}, nil
 	}
-	_, err = r.ReconcileDatabaseStatefulSets(ctx, database)
+	cloneReady, err := r.ReconcileDatabaseClone(ctx, database)
+	if err != nil {
+		log.Error(err, "Failed to clone database")
+		return r.ReconcileFailed(ctx, database, reasonReconcileFailed, err)
+	}
+	if !cloneReady {
+		meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{Type: typeProgressingDatabase,
+			Status: metav1.ConditionTrue, Reason: reasonWaitingForCloneSnapshot,
+			Message: fmt.Sprintf("Waiting for VolumeSnapshot %s of Database %s to be ready",
+				utils.GetDatabaseCloneSnapshotName(database), database.Spec.Storage.CloneFrom)})
+		if _, err := r.UpdateDatabaseStatus(ctx, database, originalStatus); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: cloneSnapshotPollInterval}, nil
+	}
+	statefulSet, err := r.ReconcileDatabaseStatefulSets(ctx, database, authSecret)
 	if err != nil {
 		log.Error(err, "Failed to reconcile statefulset")
-		return ctrl.Result{}, err
+		return r.ReconcileFailed(ctx, database, reasonStatefulSetUpdateFailed, err)
+	}
+	if err := r.ReconcileDatabaseAvailability(ctx, database, statefulSet); err != nil {
+		log.Error(err, "Failed to check database availability")
+		return r.ReconcileFailed(ctx, database, reasonReconcileFailed, err)
+	}
+	if err := r.ReconcileDatabaseResourceQuota(ctx, database, statefulSet); err != nil {
+		log.Error(err, "Failed to check resource quota")
+		return r.ReconcileFailed(ctx, database, reasonReconcileFailed, err)
 	}
 	_, _, err = r.ReconcileDatabaseService(ctx, database)
 	if err != nil {
 		log.Error(err, "Failed to reconcile service")
-		return ctrl.Result{}, err
+		return r.ReconcileFailed(ctx, database, reasonServiceReconcileFailed, err)
+	}
+	if err := r.ReconcileDatabaseMaintenance(ctx, database); err != nil {
+		log.Error(err, "Failed to reconcile maintenance page")
+		return r.ReconcileFailed(ctx, database, reasonReconcileFailed, err)
 	}
 	_, err = r.ReconcileDatabaseIngress(ctx, database)
 	if err != nil {
 		log.Error(err, "Failed to reconcile ingress")
-		return ctrl.Result{}, err
+		return r.ReconcileFailed(ctx, database, reasonIngressReconcileFailed, err)
 	}
-
-	// The following implementation will update the status
-	changed := meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{Type: typeAvailableDatabase,
-		Status: metav1.ConditionTrue, Reason: "Reconciling",
-		Message: fmt.Sprintf("Deployment for custom resource (%s) created successfully", database.Name)})
-	if changed {
-		if err := r.Status().Update(ctx, database); err != nil {
-			if apierrors.IsConflict(err) {
-				return ctrl.Result{Requeue: true}, nil
-			}
-			log.Error(err, "Failed to update Database status")
+	if err := r.ReconcileDatabaseSnapshot(ctx, database); err != nil {
+		log.Error(err, "Failed to reconcile volume snapshot")
+		return r.ReconcileFailed(ctx, database, reasonReconcileFailed, err)
+	}
+	if err := r.ReconcileDatabasePrometheusRule(ctx, database); err != nil {
+		log.Error(err, "Failed to reconcile prometheus rule")
+		return r.ReconcileFailed(ctx, database, reasonReconcileFailed, err)
+	}
+	if err := r.ReconcileDatabaseServiceMonitor(ctx, database); err != nil {
+		log.Error(err, "Failed to reconcile service monitor")
+		return r.ReconcileFailed(ctx, database, reasonReconcileFailed, err)
+	}
+	if err := r.ReconcileDatabasePeerAuthentication(ctx, database); err != nil {
+		log.Error(err, "Failed to reconcile peer authentication")
+		return r.ReconcileFailed(ctx, database, reasonReconcileFailed, err)
+	}
+	_, err = r.ReconcileDatabaseGrafanaDashboard(ctx, database)
+	if err != nil {
+		log.Error(err, "Failed to reconcile grafana dashboard")
+		return r.ReconcileFailed(ctx, database, reasonReconcileFailed, err)
+	}
+	_, err = r.ReconcileDatabaseConnectionConfigMap(ctx, database)
+	if err != nil {
+		log.Error(err, "Failed to reconcile connection config map")
+		return r.ReconcileFailed(ctx, database, reasonReconcileFailed, err)
+	}
+	_, err = r.ReconcileDatabaseJWKSConfigMap(ctx, database, authSecret)
+	if err != nil {
+		log.Error(err, "Failed to reconcile jwks config map")
+		return r.ReconcileFailed(ctx, database, reasonReconcileFailed, err)
+	}
+	if err := r.ReconcileDatabaseInitSQL(ctx, database, authSecret); err != nil {
+		log.Error(err, "Failed to reconcile init SQL")
+		return r.ReconcileFailed(ctx, database, reasonReconcileFailed, err)
+	}
+	podsFailing, err := r.ReconcileDatabasePods(ctx, database)
+	if err != nil {
+		log.Error(err, "Failed to inspect database pods")
+		return r.ReconcileFailed(ctx, database, reasonReconcileFailed, err)
+	}
+	if err := r.DeleteOrphanedDatabaseResources(ctx, database); err != nil {
+		log.Error(err, "Failed to delete orphaned resources")
+		return r.ReconcileFailed(ctx, database, reasonReconcileFailed, err)
+	}
+	if err := r.ReconcileDatabaseManagedResources(ctx, database); err != nil {
+		log.Error(err, "Failed to list managed resources")
+		return r.ReconcileFailed(ctx, database, reasonReconcileFailed, err)
+	}
+	database.Status.LastError = nil
+	if podsFailing {
+		if _, err := r.UpdateDatabaseStatus(ctx, database, originalStatus); err != nil {
 			return ctrl.Result{}, err
 		}
+		// keep checking with the rate limiter backoff until the containers recover
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	storagePressureCheckAfter, err := r.ReconcileDatabaseStoragePressure(ctx, database)
+	if err != nil {
+		log.Error(err, "Failed to check the storage pressure")
+		return r.ReconcileFailed(ctx, database, reasonReconcileFailed, err)
+	}
+
+	// The following implementation will update the status
+	database.Status.DesiredImage = r.GetDatabaseImage(database)
+	database.Status.Image = r.rewriteImage(database.Status.DesiredImage)
+	database.Status.ObservedGeneration = database.Generation
+	requeue, err = r.UpdateDatabaseStatus(ctx, database, originalStatus)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	requeueAfter := storagePressureCheckAfter
+	if r.NotReadyRequeueAfter > 0 && meta.IsStatusConditionTrue(database.Status.Conditions, typeProgressingDatabase) &&
+		(requeueAfter == 0 || r.NotReadyRequeueAfter < requeueAfter) {
+		requeueAfter = r.NotReadyRequeueAfter
+	}
+	return ctrl.Result{Requeue: requeue, RequeueAfter: requeueAfter}, nil
+}
+
+// ReconcileFailed marks the Database unavailable with the reason of the failed sub reconciler,
+// or the more specific one carried by a ReconcileError, and returns err to be retried.
+func (r *DatabaseReconciler) ReconcileFailed(ctx context.Context, database *libsqlv1.Database, reason string, err error) (ctrl.Result, error) {
+	reconcileErr := &ReconcileError{}
+	if errors.As(err, &reconcileErr) {
+		reason = reconcileErr.Reason
 	}
+	message := err.Error()
+	if len(message) > maxLastErrorLength {
+		message = message[:maxLastErrorLength] + "..."
+	}
+	database.Status.LastError = &libsqlv1.DatabaseError{Message: message, Time: metav1.Now()}
+	meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{Type: typeAvailableDatabase,
+		Status: metav1.ConditionFalse, Reason: reason, Message: err.Error()})
+	database.Status.Phase = GetDatabasePhase(database)
+	if statusErr := r.Status().Update(ctx, database); statusErr != nil && !apierrors.IsConflict(statusErr) {
+		log.FromContext(ctx).Error(statusErr, "Failed to update Database status")
+	}
+	return ctrl.Result{}, err
+}
+
+// setDatabaseCRDNotInstalled sets the conditionType condition, e.g. ServiceMonitorUnsupported,
+// while a feature of the spec is skipped because the CRDs it needs are not installed. The Warning
+// event is only emitted when the condition becomes True, not on every reconcile.
+func (r *DatabaseReconciler) setDatabaseCRDNotInstalled(ctx context.Context, database *libsqlv1.Database, conditionType, message string) {
+	if meta.IsStatusConditionTrue(database.Status.Conditions, conditionType) {
+		return
+	}
+	log.FromContext(ctx).Info(message)
+	r.recorder(ctx).Event(database, utils.EventWarning, conditionType, message)
+	meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{Type: conditionType,
+		Status: metav1.ConditionTrue, Reason: reasonCRDNotInstalled, Message: message})
+}
 
-	return ctrl.Result{}, nil
+// UpdateDatabaseStatus refreshes the phase and writes the status of the Database when it differs from originalStatus.
+// A conflict caused by stale data is reported as a requeue rather than an error.
+func (r *DatabaseReconciler) UpdateDatabaseStatus(ctx context.Context, database *libsqlv1.Database, originalStatus *libsqlv1.DatabaseStatus) (requeue bool, err error) {
+	log := log.FromContext(ctx)
+	database.Status.Phase = GetDatabasePhase(database)
+	if equality.Semantic.DeepEqual(originalStatus, &database.Status) {
+		return false, nil
+	}
+	if err := r.Status().Update(ctx, database); err != nil {
+		// requeue for case of stale data without raising errors
+		// https://github.com/kubernetes-sigs/controller-runtime/issues/1464
+		if apierrors.IsConflict(err) {
+			return true, nil
+		}
+		log.Error(err, "Failed to update Database status")
+		return false, err
+	}
+	return false, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *DatabaseReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &libsqlv1.Database{},
+		databaseConfigRefsIndex, IndexDatabaseConfigRefs); err != nil {
+		return err
+	}
+	builder := ctrl.NewControllerManagedBy(mgr)
+	if len(r.NamespaceLabelMappings) > 0 {
+		// the labels mapped from a namespace are applied to its Databases when they change
+		builder = builder.Watches(
+			&corev1.Namespace{},
+			handler.EnqueueRequestsFromMapFunc(r.MapNamespaceToReconcile),
+		)
+	}
+	return builder.
 		For(&libsqlv1.Database{}).
 		Owns(&networkingv1.Ingress{}).
 		Owns(&appsv1.Deployment{}).
 		Owns(&corev1.Secret{}).
+		Owns(&corev1.ConfigMap{}).
+		Owns(&batchv1.Job{}).
 		Watches(
 			&corev1.Secret{},
 			handler.EnqueueRequestsFromMapFunc(r.MapAuthSecretsToReconcile),
 		).
+		Watches(
+			&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.MapDatabaseConfigRefsToReconcile),
+		).
+		Watches(
+			&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.MapDatabaseConfigRefsToReconcile),
+		).
 		Watches(
 			&appsv1.StatefulSet{},
 			handler.EnqueueRequestsFromMapFunc(r.MapDatabaseStatefulSetsToReconcile),
@@ -174,5 +530,9 @@ func (r *DatabaseReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			&networkingv1.Ingress{},
 			handler.EnqueueRequestsFromMapFunc(r.MapDatabaseIngressToReconcile),
 		).
+		Watches(
+			&corev1.Pod{},
+			handler.EnqueueRequestsFromMapFunc(r.MapDatabasePodsToReconcile),
+		).
 		Complete(r)
 }
@@ -24,9 +24,11 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/utils/ptr"
@@ -68,7 +70,7 @@ var _ = Describe("Database Controller", func() {
 						Image:           "ghcr.io/tursodatabase/libsql-server:v0.24.21",
 						ImagePullPolicy: "Always",
 						Auth:            true,
-						Storage:         libsqlv1.DatabaseStorage{Size: *resource.NewMilliQuantity(int64(1000), resource.BinarySI)},
+						Storage:         libsqlv1.DatabaseStorage{Size: resource.MustParse("1Gi")},
 						Ingress: &libsqlv1.AhtiDatabaseIngressSpec{
 							IngressClassName: ptr.To("nginx"),
 							Host:             "database.ahti.io",
@@ -97,46 +99,89 @@ var _ = Describe("Database Controller", func() {
 
 			By("Reconciling the created resource")
 			controllerReconciler := &DatabaseReconciler{
-				Client:   k8sClient,
-				Scheme:   k8sClient.Scheme(),
-				Recorder: MockEventRecorder{},
+				Client:               k8sClient,
+				Scheme:               k8sClient.Scheme(),
+				Recorder:             MockEventRecorder{},
+				NotReadyRequeueAfter: 10 * time.Second,
 			}
 
-			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
 				NamespacedName: typeNamespacedName,
 			})
 			Expect(err).NotTo(HaveOccurred())
 			Eventually(func() error {
 				return k8sClient.Get(ctx, typeNamespacedName, database)
 			}, time.Minute, time.Second).Should(Succeed())
-			Expect(controllerutil.ContainsFinalizer(database, databaseFinalizer)).Should(BeTrue())
+			Expect(controllerutil.ContainsFinalizer(database, controllerReconciler.GetFinalizerName())).Should(BeTrue())
 
 			By("Checking if StatefulSet was successfully created in the reconciliation")
 			databaseStatefulSet := &appsv1.StatefulSet{}
 			Eventually(func() error {
 				return k8sClient.Get(ctx, typeNamespacedName, databaseStatefulSet)
 			}, time.Minute, time.Second).Should(Succeed())
-			Expect(databaseStatefulSet.Spec.Template.Spec.Containers[0].Image).Should(Equal(database.Spec.Image))
+			container := utils.GetContainer(&databaseStatefulSet.Spec.Template.Spec, utils.GetDatabaseContainerName(database))
+			Expect(container).NotTo(BeNil())
+			Expect(container.Image).Should(Equal(database.Spec.Image))
+			Expect(container.Env).Should(ContainElement(corev1.EnvVar{Name: "RUST_LOG", Value: "info"}))
+			Expect(database.Status.Image).Should(Equal(database.Spec.Image))
+			Expect(database.Status.PrimaryEndpoint).Should(Equal(&libsqlv1.DatabaseEndpoint{
+				Host:     fmt.Sprintf("%s-svc-headless.%s.svc.cluster.local", database.Name, database.Namespace),
+				GRPCPort: 5001,
+			}))
 			Expect(databaseStatefulSet.ObjectMeta.OwnerReferences[0].Name).Should(Equal(database.Name))
+			Expect(databaseStatefulSet.Labels).Should(HaveKeyWithValue("app.kubernetes.io/instance", database.Name))
 
-			By("Checking the latest Status Condition added to the Database instance")
-			Eventually(func() error {
-				if len(database.Status.Conditions) != 0 {
-					latestStatusCondition := database.Status.Conditions[len(database.Status.Conditions)-1]
-					expectedLatestStatusCondition := metav1.Condition{
-						Type:               typeAvailableDatabase,
-						Status:             metav1.ConditionTrue,
-						LastTransitionTime: latestStatusCondition.LastTransitionTime,
-						Reason:             "Reconciling",
-						Message: fmt.Sprintf(
-							"Deployment for custom resource (%s) created successfully", database.Name),
-					}
-					if latestStatusCondition != expectedLatestStatusCondition {
-						return fmt.Errorf("The latest status condition added to the Database instance is not as expected\n%v\n%v", latestStatusCondition, expectedLatestStatusCondition)
-					}
-				}
-				return nil
-			}, time.Minute, time.Second).Should(Succeed())
+			By("Checking the Available condition while the pods are not ready")
+			availableCondition := meta.FindStatusCondition(database.Status.Conditions, typeAvailableDatabase)
+			Expect(availableCondition).NotTo(BeNil())
+			Expect(availableCondition.Status).Should(Equal(metav1.ConditionFalse))
+			Expect(availableCondition.Reason).Should(Equal(reasonRolloutInProgress))
+			Expect(database.Status.Phase).Should(Equal(libsqlv1.DatabasePhaseProvisioning))
+			Expect(result.RequeueAfter).Should(Equal(10 * time.Second))
+
+			By("Checking the Available condition once the pods are ready")
+			databaseStatefulSet.Status.Replicas = 1
+			databaseStatefulSet.Status.ReadyReplicas = 1
+			databaseStatefulSet.Status.UpdatedReplicas = 1
+			databaseStatefulSet.Status.ObservedGeneration = databaseStatefulSet.Generation
+			Expect(k8sClient.Status().Update(ctx, databaseStatefulSet)).To(Succeed())
+			result, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).Should(BeZero())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, database)).To(Succeed())
+			availableCondition = meta.FindStatusCondition(database.Status.Conditions, typeAvailableDatabase)
+			Expect(availableCondition).NotTo(BeNil())
+			Expect(availableCondition.Status).Should(Equal(metav1.ConditionTrue))
+			Expect(availableCondition.Reason).Should(Equal(reasonReady))
+			Expect(availableCondition.Message).Should(Equal(fmt.Sprintf("Database %s is ready", database.Name)))
+			Expect(database.Status.Phase).Should(Equal(libsqlv1.DatabasePhaseRunning))
+
+			By("Checking if spec.initSQL is executed once the database is available")
+			database.Spec.InitSQL = &libsqlv1.DatabaseInitSQL{SQL: "CREATE TABLE IF NOT EXISTS users (id INTEGER PRIMARY KEY);"}
+			Expect(k8sClient.Update(ctx, database)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			initSQLName := types.NamespacedName{Name: utils.GetDatabaseInitSQLName(database), Namespace: database.Namespace}
+			initSQLJob := &batchv1.Job{}
+			Expect(k8sClient.Get(ctx, initSQLName, initSQLJob)).To(Succeed())
+			initSQLSecret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, initSQLName, initSQLSecret)).To(Succeed())
+			Expect(string(initSQLSecret.Data["pipeline.json"])).Should(ContainSubstring("CREATE TABLE IF NOT EXISTS users"))
+			Expect(string(initSQLSecret.Data["headers"])).Should(ContainSubstring("Authorization: Bearer "))
+			initSQLJob.Status.Succeeded = 1
+			Expect(k8sClient.Status().Update(ctx, initSQLJob)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, database)).To(Succeed())
+			Expect(database.Status.InitSQL).NotTo(BeNil())
+			Expect(database.Status.InitSQL.Succeeded).Should(BeTrue())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, initSQLName, initSQLSecret))).Should(BeTrue())
 
 			By("Checking if Auth Secret was successfully created in the reconciliation")
 			secret := &corev1.Secret{}
@@ -145,6 +190,19 @@ var _ = Describe("Database Controller", func() {
 			}, time.Minute, time.Second).Should(Succeed())
 			Expect(secret.ObjectMeta.OwnerReferences[0].Name).Should(Equal(database.Name))
 
+			By("Checking if a corrupted Auth Secret is repaired")
+			publicKey := secret.Data["PUBLIC_KEY"]
+			delete(secret.Data, "PUBLIC_KEY")
+			Expect(k8sClient.Update(ctx, secret)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: utils.GetAuthSecretName(database), Namespace: database.Namespace}, secret)).To(Succeed())
+			Expect(secret.Data["PUBLIC_KEY"]).Should(Equal(publicKey))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, databaseStatefulSet)).To(Succeed())
+			Expect(databaseStatefulSet.Spec.Template.Annotations).Should(HaveKeyWithValue(databaseAuthKeyHashAnnotation, utils.HashValue(publicKey)))
+
 			By("Checking if Headless Service was successfully created in the reconciliation")
 			headlessService := &corev1.Service{}
 			Eventually(func() error {
@@ -166,7 +224,184 @@ var _ = Describe("Database Controller", func() {
 			}, time.Minute, time.Second).Should(Succeed())
 			Expect(ingress.ObjectMeta.OwnerReferences[0].Name).Should(Equal(database.Name))
 
+			By("Checking if enabling TLS updates the existing Ingress")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, database)).To(Succeed())
+			database.Spec.Ingress.TLS = []networkingv1.IngressTLS{
+				{Hosts: []string{database.Spec.Ingress.Host}, SecretName: "database-tls"},
+			}
+			Expect(k8sClient.Update(ctx, database)).To(Succeed())
+			for i := 0; i < 2; i++ {
+				_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: typeNamespacedName,
+				})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: utils.GetDatabaseIngressName(database), Namespace: database.Namespace}, ingress)).To(Succeed())
+			Expect(ingress.Spec.TLS).Should(Equal(database.Spec.Ingress.TLS))
+
+			By("Checking if changing the host updates the Ingress in place")
+			ingressUID := ingress.UID
+			Expect(k8sClient.Get(ctx, typeNamespacedName, database)).To(Succeed())
+			database.Spec.Ingress.Host = "database-renamed.ahti.io"
+			Expect(k8sClient.Update(ctx, database)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: utils.GetDatabaseIngressName(database), Namespace: database.Namespace}, ingress)).To(Succeed())
+			Expect(ingress.UID).Should(Equal(ingressUID))
+			Expect(ingress.Spec.Rules[0].Host).Should(Equal("database-renamed.ahti.io"))
+
+			By("Checking if the maintenance annotation switches the Ingress to the maintenance page")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, database)).To(Succeed())
+			database.Annotations = map[string]string{databaseMaintenanceAnnotation: "true"}
+			Expect(k8sClient.Update(ctx, database)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: utils.GetDatabaseMaintenanceName(database), Namespace: database.Namespace}, &appsv1.Deployment{})).To(Succeed())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: utils.GetDatabaseIngressName(database), Namespace: database.Namespace}, ingress)).To(Succeed())
+			Expect(ingress.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name).Should(Equal(utils.GetDatabaseMaintenanceName(database)))
+
+			By("Checking if clearing the maintenance annotation switches the Ingress back")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, database)).To(Succeed())
+			database.Annotations = nil
+			Expect(k8sClient.Update(ctx, database)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: utils.GetDatabaseIngressName(database), Namespace: database.Namespace}, ingress)).To(Succeed())
+			Expect(ingress.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name).Should(Equal(utils.GetDatabaseServiceName(database, false)))
+
+			By("Checking if external-dns annotations are set and other annotations are kept")
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: utils.GetDatabaseServiceName(database, false), Namespace: database.Namespace}, service)).To(Succeed())
+			service.Annotations = map[string]string{"example.com/owner": "team"}
+			Expect(k8sClient.Update(ctx, service)).To(Succeed())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, database)).To(Succeed())
+			database.Spec.Service = &libsqlv1.DatabaseServiceSpec{
+				ExternalDNS: &libsqlv1.DatabaseExternalDNS{Hostname: "database.internal.ahti.io", TTL: ptr.To(int32(60))},
+			}
+			Expect(k8sClient.Update(ctx, database)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: utils.GetDatabaseServiceName(database, false), Namespace: database.Namespace}, service)).To(Succeed())
+			Expect(service.Annotations).Should(HaveKeyWithValue("example.com/owner", "team"))
+			Expect(service.Annotations).Should(HaveKeyWithValue(externalDNSHostnameAnnotation, "database.internal.ahti.io"))
+			Expect(service.Annotations).Should(HaveKeyWithValue(externalDNSTTLAnnotation, "60"))
+
+			By("Checking if the service is renamed with spec.service.nameOverride")
+			defaultServiceName := utils.GetDatabaseServiceName(database, false)
+			Expect(k8sClient.Get(ctx, typeNamespacedName, database)).To(Succeed())
+			database.Spec.Service.NameOverride = "legacy-database"
+			Expect(k8sClient.Update(ctx, database)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "legacy-database", Namespace: database.Namespace}, service)).To(Succeed())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: defaultServiceName, Namespace: database.Namespace}, service)).NotTo(Succeed())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, database)).To(Succeed())
+			database.Spec.Service.NameOverride = ""
+			Expect(k8sClient.Update(ctx, database)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: defaultServiceName, Namespace: database.Namespace}, service)).To(Succeed())
+
+			By("Checking if the gRPC port is moved to a separate service with spec.service.grpc")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, database)).To(Succeed())
+			database.Spec.Service.GRPC = &libsqlv1.DatabaseGRPCServiceSpec{
+				Type:        corev1.ServiceTypeLoadBalancer,
+				Annotations: map[string]string{"service.beta.kubernetes.io/aws-load-balancer-internal": "true"},
+			}
+			Expect(k8sClient.Update(ctx, database)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			grpcServiceName := types.NamespacedName{Name: utils.GetDatabaseGRPCServiceName(database), Namespace: database.Namespace}
+			grpcService := &corev1.Service{}
+			Expect(k8sClient.Get(ctx, grpcServiceName, grpcService)).To(Succeed())
+			Expect(grpcService.Spec.Type).Should(Equal(corev1.ServiceTypeLoadBalancer))
+			Expect(grpcService.Spec.Ports).Should(HaveLen(1))
+			Expect(grpcService.Spec.Ports[0].Port).Should(Equal(int32(5001)))
+			Expect(grpcService.Spec.Ports[0].AppProtocol).Should(Equal(ptr.To("grpc")))
+			Expect(grpcService.Annotations).Should(HaveKeyWithValue("service.beta.kubernetes.io/aws-load-balancer-internal", "true"))
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: defaultServiceName, Namespace: database.Namespace}, service)).To(Succeed())
+			Expect(service.Spec.Ports).Should(HaveLen(1))
+			Expect(service.Spec.Ports[0].Port).Should(Equal(int32(8080)))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, database)).To(Succeed())
+			database.Spec.Service.GRPC.Annotations = nil
+			Expect(k8sClient.Update(ctx, database)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, grpcServiceName, grpcService)).To(Succeed())
+			Expect(grpcService.Annotations).ShouldNot(HaveKey("service.beta.kubernetes.io/aws-load-balancer-internal"))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, database)).To(Succeed())
+			database.Spec.Service.GRPC = nil
+			Expect(k8sClient.Update(ctx, database)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, grpcServiceName, grpcService))).Should(BeTrue())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: defaultServiceName, Namespace: database.Namespace}, service)).To(Succeed())
+			Expect(service.Spec.Ports).Should(HaveLen(2))
+			Expect(service.Spec.Ports[0].AppProtocol).Should(Equal(ptr.To("http")))
+
+			By("Checking if the appProtocol of a service port can be overridden")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, database)).To(Succeed())
+			database.Spec.Service.GRPCAppProtocol = ptr.To("kubernetes.io/h2c")
+			database.Spec.Service.HTTPAppProtocol = ptr.To("")
+			Expect(k8sClient.Update(ctx, database)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: defaultServiceName, Namespace: database.Namespace}, service)).To(Succeed())
+			Expect(service.Spec.Ports[0].AppProtocol).Should(BeNil())
+			Expect(service.Spec.Ports[1].AppProtocol).Should(Equal(ptr.To("kubernetes.io/h2c")))
+
+			By("Checking if the StatefulSet is left untouched when nothing changed")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, databaseStatefulSet)).To(Succeed())
+			resourceVersion := databaseStatefulSet.ResourceVersion
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, databaseStatefulSet)).To(Succeed())
+			Expect(databaseStatefulSet.ResourceVersion).Should(Equal(resourceVersion))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, database)).To(Succeed())
+			Expect(database.Status.ObservedGeneration).Should(Equal(database.Generation))
+			Expect(database.Status.ManagedResources).Should(ContainElements(
+				libsqlv1.DatabaseManagedResource{Kind: "StatefulSet", Name: database.Name},
+				libsqlv1.DatabaseManagedResource{Kind: "Service", Name: utils.GetDatabaseServiceName(database, true)},
+				libsqlv1.DatabaseManagedResource{Kind: "Ingress", Name: utils.GetDatabaseIngressName(database)},
+				libsqlv1.DatabaseManagedResource{Kind: "Secret", Name: utils.GetAuthSecretName(database)},
+			))
+
+			By("Checking if a manually scaled primary is scaled back to a single replica")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, databaseStatefulSet)).To(Succeed())
+			databaseStatefulSet.Spec.Replicas = ptr.To(int32(2))
+			Expect(k8sClient.Update(ctx, databaseStatefulSet)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, databaseStatefulSet)).To(Succeed())
+			Expect(databaseStatefulSet.Spec.Replicas).Should(Equal(ptr.To(databasePrimaryReplicas)))
+
 			By("Checking if secret is removed after updating database auth to false")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, database)).To(Succeed())
 			database.Spec.Auth = false
 			Eventually(func() error {
 				return k8sClient.Update(ctx, database)
@@ -184,6 +419,7 @@ var _ = Describe("Database Controller", func() {
 			}, time.Minute, time.Second).ShouldNot(Succeed())
 
 			By("Checking if ingress is removed after updating database ingress to nil")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, database)).To(Succeed())
 			database.Spec.Ingress = nil
 			Eventually(func() error {
 				return k8sClient.Update(ctx, database)
@@ -0,0 +1,179 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// grafanaDashboardLabel is the label the Grafana sidecar discovers dashboard ConfigMaps with
+	grafanaDashboardLabel = "grafana_dashboard"
+	// grafanaDashboardPanelHeight is the height of every panel of the dashboard, in grid units
+	grafanaDashboardPanelHeight = 8
+)
+
+// ReconcileDatabaseGrafanaDashboard keeps a ConfigMap with the dashboard of the Database when
+// spec.monitoring.grafanaDashboard is set, and deletes it otherwise.
+func (r *DatabaseReconciler) ReconcileDatabaseGrafanaDashboard(ctx context.Context, database *libsqlv1.Database) (*corev1.ConfigMap, error) {
+	found := &corev1.ConfigMap{}
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      utils.GetDatabaseGrafanaDashboardName(database),
+		Namespace: database.Namespace,
+	}, found); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		if database.Spec.Monitoring == nil || database.Spec.Monitoring.GrafanaDashboard == nil {
+			return nil, nil
+		}
+		configMap, err := r.ConstructDatabaseGrafanaDashboard(database)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.Create(ctx, configMap); err != nil {
+			return nil, err
+		}
+		r.recorder(ctx).Event(database, utils.EventNormal, "SuccessfulCreate",
+			fmt.Sprintf("create ConfigMap %s is being created in the Namespace %s success",
+				configMap.Name,
+				database.Namespace))
+		return configMap, nil
+	}
+	if database.Spec.Monitoring == nil || database.Spec.Monitoring.GrafanaDashboard == nil {
+		// delete dashboard if database does not need it
+		return nil, r.Delete(ctx, found)
+	}
+	configMap, err := r.ConstructDatabaseGrafanaDashboard(database)
+	if err != nil {
+		return nil, err
+	}
+	labels := utils.MergeLabels(found.Labels, configMap.Labels)
+	if equality.Semantic.DeepEqual(found.Data, configMap.Data) &&
+		equality.Semantic.DeepEqual(found.Labels, labels) &&
+		equality.Semantic.DeepEqual(found.Annotations, configMap.Annotations) {
+		return found, nil
+	}
+	patch := client.MergeFrom(found.DeepCopy())
+	found.Data = configMap.Data
+	found.Labels = labels
+	found.Annotations = configMap.Annotations
+	if err := r.Patch(ctx, found, patch); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+func (r *DatabaseReconciler) ConstructDatabaseGrafanaDashboard(database *libsqlv1.Database) (*corev1.ConfigMap, error) {
+	dashboard, err := json.MarshalIndent(constructDatabaseDashboardModel(database), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	dashboardSpec := database.Spec.Monitoring.GrafanaDashboard
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      utils.GetDatabaseGrafanaDashboardName(database),
+			Namespace: database.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: databaseAPIVersion,
+					Kind:       databaseKind,
+					Name:       database.Name,
+					UID:        database.UID,
+				},
+			},
+			Labels: utils.MergeLabels(r.databaseLabelsWithOverrides(database, dashboardSpec.Labels),
+				map[string]string{grafanaDashboardLabel: "1"}),
+			Annotations: dashboardSpec.Annotations,
+		},
+		Data: map[string]string{
+			fmt.Sprintf("%s-%s.json", database.Namespace, database.Name): string(dashboard),
+		},
+	}
+	return configMap, nil
+}
+
+// constructDatabaseDashboardModel builds the Grafana dashboard of the Database from the
+// kube-state-metrics, cAdvisor and kubelet series of its primary pod and data volume.
+func constructDatabaseDashboardModel(database *libsqlv1.Database) map[string]interface{} {
+	podSelector := fmt.Sprintf(`namespace="%s",pod=~"%s-[0-9]+",container="%s"`,
+		database.Namespace, database.Name, utils.GetDatabaseContainerName(database))
+	panels := []struct {
+		title string
+		unit  string
+		expr  string
+	}{
+		{
+			title: "Ready replicas",
+			unit:  "short",
+			expr:  fmt.Sprintf(`kube_statefulset_status_replicas_ready{namespace="%s",statefulset="%s"}`, database.Namespace, database.Name),
+		},
+		{
+			title: "Container restarts",
+			unit:  "short",
+			expr:  fmt.Sprintf(`increase(kube_pod_container_status_restarts_total{%s}[1h])`, podSelector),
+		},
+		{
+			title: "CPU usage",
+			unit:  "cores",
+			expr:  fmt.Sprintf(`sum(rate(container_cpu_usage_seconds_total{%s}[5m]))`, podSelector),
+		},
+		{
+			title: "Memory usage",
+			unit:  "bytes",
+			expr:  fmt.Sprintf(`sum(container_memory_working_set_bytes{%s})`, podSelector),
+		},
+		{
+			title: "Volume usage",
+			unit:  "bytes",
+			expr: fmt.Sprintf(`kubelet_volume_stats_used_bytes{namespace="%s",persistentvolumeclaim="%s"}`,
+				database.Namespace, utils.GetDatabaseStatefulSetPVCName(database, 0)),
+		},
+		{
+			title: "Volume capacity",
+			unit:  "bytes",
+			expr: fmt.Sprintf(`kubelet_volume_stats_capacity_bytes{namespace="%s",persistentvolumeclaim="%s"}`,
+				database.Namespace, utils.GetDatabaseStatefulSetPVCName(database, 0)),
+		},
+	}
+	dashboardPanels := []interface{}{}
+	for i, panel := range panels {
+		dashboardPanels = append(dashboardPanels, map[string]interface{}{
+			"id":    i + 1,
+			"type":  "timeseries",
+			"title": panel.title,
+			"gridPos": map[string]interface{}{
+				"h": grafanaDashboardPanelHeight,
+				"w": 12,
+				"x": (i % 2) * 12,
+				"y": (i / 2) * grafanaDashboardPanelHeight,
+			},
+			"fieldConfig": map[string]interface{}{
+				"defaults": map[string]interface{}{"unit": panel.unit},
+			},
+			"targets": []interface{}{
+				map[string]interface{}{"refId": "A", "expr": panel.expr},
+			},
+		})
+	}
+	return map[string]interface{}{
+		// Grafana limits uids to 40 characters
+		"uid":           fmt.Sprintf("libsql-%.32s", strings.ReplaceAll(string(database.UID), "-", "")),
+		"title":         fmt.Sprintf("libsql / %s / %s", database.Namespace, database.Name),
+		"tags":          []string{"libsql", databaseAppName},
+		"schemaVersion": 39,
+		"time":          map[string]interface{}{"from": "now-6h", "to": "now"},
+		"refresh":       "30s",
+		"panels":        dashboardPanels,
+	}
+}
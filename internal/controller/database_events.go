@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// eventCorrelationIDAnnotation on the events of a reconcile holds its correlation ID, which is
+// also logged, so that an event leads to the logs of the reconcile.
+const eventCorrelationIDAnnotation = "libsql.ahti.io/correlation-id"
+
+type eventCorrelationIDKey struct{}
+
+// withEventCorrelationID returns the context of a reconcile with a new correlation ID, logged
+// as correlationID.
+func withEventCorrelationID(ctx context.Context) context.Context {
+	id := string(uuid.NewUUID())
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("correlationID", id))
+	return context.WithValue(ctx, eventCorrelationIDKey{}, id)
+}
+
+// recorder returns the Recorder annotating events with the correlation ID of the reconcile.
+func (r *DatabaseReconciler) recorder(ctx context.Context) record.EventRecorder {
+	id, ok := ctx.Value(eventCorrelationIDKey{}).(string)
+	if !ok {
+		return r.Recorder
+	}
+	return &correlatedEventRecorder{EventRecorder: r.Recorder, correlationID: id}
+}
+
+// correlatedEventRecorder adds the correlation ID annotation to the events, their messages are
+// left as they are.
+type correlatedEventRecorder struct {
+	record.EventRecorder
+	correlationID string
+}
+
+func (r *correlatedEventRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	r.AnnotatedEventf(object, nil, eventtype, reason, "%s", message)
+}
+
+func (r *correlatedEventRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.AnnotatedEventf(object, nil, eventtype, reason, messageFmt, args...)
+}
+
+func (r *correlatedEventRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	correlated := map[string]string{eventCorrelationIDAnnotation: r.correlationID}
+	for key, value := range annotations {
+		correlated[key] = value
+	}
+	r.EventRecorder.AnnotatedEventf(object, correlated, eventtype, reason, "%s", fmt.Sprintf(messageFmt, args...))
+}
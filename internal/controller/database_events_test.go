@@ -0,0 +1,31 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+)
+
+var _ = Describe("Database events", func() {
+	database := &libsqlv1.Database{ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "default"}}
+
+	It("should annotate the events of a reconcile with its correlation ID", func() {
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &DatabaseReconciler{Recorder: recorder}
+
+		reconciler.recorder(context.Background()).Event(database, utils.EventNormal, "Tested", "no reconcile")
+		Expect(recorder.Events).Should(Receive(Equal("Normal Tested no reconcile")))
+
+		ctx := withEventCorrelationID(context.Background())
+		id := ctx.Value(eventCorrelationIDKey{}).(string)
+		Expect(id).NotTo(BeEmpty())
+		reconciler.recorder(ctx).Event(database, utils.EventNormal, "Tested", "100% reconciled")
+		Expect(recorder.Events).Should(Receive(Equal("Normal Tested 100% reconciled map[libsql.ahti.io/correlation-id:" + id + "]")))
+	})
+})
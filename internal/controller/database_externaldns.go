@@ -0,0 +1,32 @@
+package controller
+
+import (
+	"strconv"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+)
+
+// Annotations read by external-dns. They are managed by the operator on the generated
+// Service and Ingress, so they are removed again when the configuration is removed.
+const (
+	externalDNSHostnameAnnotation = "external-dns.alpha.kubernetes.io/hostname"
+	externalDNSTTLAnnotation      = "external-dns.alpha.kubernetes.io/ttl"
+)
+
+var externalDNSAnnotations = []string{externalDNSHostnameAnnotation, externalDNSTTLAnnotation}
+
+// constructExternalDNSAnnotations returns the external-dns annotations for the configuration,
+// or nil when it is not set.
+func constructExternalDNSAnnotations(externalDNS *libsqlv1.DatabaseExternalDNS) map[string]string {
+	if externalDNS == nil {
+		return nil
+	}
+	annotations := map[string]string{}
+	if externalDNS.Hostname != "" {
+		annotations[externalDNSHostnameAnnotation] = externalDNS.Hostname
+	}
+	if externalDNS.TTL != nil {
+		annotations[externalDNSTTLAnnotation] = strconv.Itoa(int(*externalDNS.TTL))
+	}
+	return annotations
+}
@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var externalSecretGVK = schema.GroupVersionKind{
+	Group:   "external-secrets.io",
+	Version: "v1beta1",
+	Kind:    "ExternalSecret",
+}
+
+const (
+	defaultExternalSecretRefreshInterval = time.Hour
+	// authSecretSyncInterval is how often a Database waiting for its synced auth keys is requeued
+	authSecretSyncInterval = 10 * time.Second
+)
+
+// ReconcileDatabaseExternalSecret keeps the ExternalSecret syncing the auth keys of the Database
+// into its auth secret. When the External Secrets Operator CRDs are not installed the keys
+// cannot be synced, which is reported with a Warning event.
+func (r *DatabaseReconciler) ReconcileDatabaseExternalSecret(ctx context.Context, database *libsqlv1.Database) error {
+	log := log.FromContext(ctx)
+	found := &unstructured.Unstructured{}
+	found.SetGroupVersionKind(externalSecretGVK)
+	err := r.Get(ctx, types.NamespacedName{
+		Name:      utils.GetAuthSecretName(database),
+		Namespace: database.Namespace,
+	}, found)
+	if meta.IsNoMatchError(err) {
+		log.Info("ExternalSecret CRDs are not installed, the auth keys cannot be synced")
+		r.recorder(ctx).Event(database, utils.EventWarning, "ExternalSecretUnsupported",
+			"ExternalSecret CRDs are not installed in the cluster, the auth keys of spec.externalAuthSecret cannot be synced")
+		return nil
+	}
+	externalSecret := r.ConstructDatabaseExternalSecret(database)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		if err := r.Create(ctx, externalSecret); err != nil {
+			return err
+		}
+		r.recorder(ctx).Event(database, utils.EventNormal, "SuccessfulCreate",
+			fmt.Sprintf("create ExternalSecret %s is being created in the Namespace %s success",
+				externalSecret.GetName(),
+				database.Namespace))
+		return nil
+	}
+	if utils.ContainsFields(found.Object["spec"], externalSecret.Object["spec"]) {
+		return nil
+	}
+	patch := client.MergeFrom(found.DeepCopy())
+	found.Object["spec"] = externalSecret.Object["spec"]
+	return r.Patch(ctx, found, patch)
+}
+
+// deleteDatabaseExternalSecret removes the ExternalSecret of the Database, if any.
+func (r *DatabaseReconciler) deleteDatabaseExternalSecret(ctx context.Context, database *libsqlv1.Database) error {
+	externalSecret := &unstructured.Unstructured{}
+	externalSecret.SetGroupVersionKind(externalSecretGVK)
+	externalSecret.SetName(utils.GetAuthSecretName(database))
+	externalSecret.SetNamespace(database.Namespace)
+	if err := r.Delete(ctx, externalSecret); err != nil && !meta.IsNoMatchError(err) {
+		return client.IgnoreNotFound(err)
+	}
+	return nil
+}
+
+func (r *DatabaseReconciler) ConstructDatabaseExternalSecret(database *libsqlv1.Database) *unstructured.Unstructured {
+	source := database.Spec.ExternalAuthSecret
+	refreshInterval := source.RefreshInterval.Duration
+	if refreshInterval == 0 {
+		refreshInterval = defaultExternalSecretRefreshInterval
+	}
+	storeKind := source.SecretStoreRef.Kind
+	if storeKind == "" {
+		storeKind = "SecretStore"
+	}
+	data := []interface{}{}
+	for _, key := range []string{"PUBLIC_KEY", "PRIVATE_KEY"} {
+		data = append(data, map[string]interface{}{
+			"secretKey": key,
+			"remoteRef": map[string]interface{}{
+				"key":      source.RemoteKey,
+				"property": key,
+			},
+		})
+	}
+	externalSecret := &unstructured.Unstructured{}
+	externalSecret.SetGroupVersionKind(externalSecretGVK)
+	externalSecret.SetName(utils.GetAuthSecretName(database))
+	externalSecret.SetNamespace(database.Namespace)
+	externalSecret.SetOwnerReferences([]metav1.OwnerReference{
+		{
+			APIVersion: databaseAPIVersion,
+			Kind:       databaseKind,
+			Name:       database.Name,
+			UID:        database.UID,
+		},
+	})
+	externalSecret.SetLabels(r.databaseLabels(database))
+	externalSecret.Object["spec"] = map[string]interface{}{
+		"refreshInterval": refreshInterval.String(),
+		"secretStoreRef": map[string]interface{}{
+			"name": source.SecretStoreRef.Name,
+			"kind": storeKind,
+		},
+		"target": map[string]interface{}{
+			"name":           utils.GetAuthSecretName(database),
+			"creationPolicy": "Owner",
+		},
+		"data": data,
+	}
+	return externalSecret
+}
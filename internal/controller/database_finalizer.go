@@ -4,11 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 
 	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
@@ -19,9 +24,9 @@ func (r *DatabaseReconciler) ReconcileDatabaseFinalizer(ctx context.Context, dat
 	// Let's add a finalizer. Then, we can define some operations which should
 	// occur before the custom resource is deleted.
 	// More info: https://kubernetes.io/docs/concepts/overview/working-with-objects/finalizers
-	if !controllerutil.ContainsFinalizer(database, databaseFinalizer) {
+	if !controllerutil.ContainsFinalizer(database, r.GetFinalizerName()) {
 		log.Info("Adding Finalizer for Database")
-		if ok := controllerutil.AddFinalizer(database, databaseFinalizer); !ok {
+		if ok := controllerutil.AddFinalizer(database, r.GetFinalizerName()); !ok {
 			log.Error(errors.New("failed to add finalizer"), "Failed to add finalizer into the custom resource")
 			return true, nil
 		}
@@ -38,12 +43,13 @@ func (r *DatabaseReconciler) ReconcileDatabaseFinalizer(ctx context.Context, dat
 	// indicated by the deletion timestamp being set.
 	isDatabaseMarkedToBeDeleted := database.GetDeletionTimestamp() != nil && !database.GetDeletionTimestamp().IsZero()
 	if isDatabaseMarkedToBeDeleted {
-		if controllerutil.ContainsFinalizer(database, databaseFinalizer) {
+		if controllerutil.ContainsFinalizer(database, r.GetFinalizerName()) {
 			log.Info("Performing Finalizer Operations for Database before delete CR")
 			// Let's add here a status "Downgrade" to reflect that this resource began its process to be terminated.
 			changed := meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{Type: typeDegradedDatabase,
-				Status: metav1.ConditionUnknown, Reason: "Finalizing",
+				Status: metav1.ConditionUnknown, Reason: reasonFinalizing,
 				Message: fmt.Sprintf("Performing finalizer operations for the custom resource: %s ", database.Name)})
+			database.Status.Phase = GetDatabasePhase(database)
 			if changed {
 				if err := r.Status().Update(ctx, database); err != nil {
 					if apierrors.IsConflict(err) {
@@ -62,7 +68,7 @@ func (r *DatabaseReconciler) ReconcileDatabaseFinalizer(ctx context.Context, dat
 			// then you need to ensure that all worked fine before deleting and updating the Downgrade status
 			// otherwise, you should requeue here.
 			changed = meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{Type: typeDegradedDatabase,
-				Status: metav1.ConditionTrue, Reason: "Finalizing",
+				Status: metav1.ConditionTrue, Reason: reasonFinalizing,
 				Message: fmt.Sprintf("Finalizer operations for custom resource %s name were successfully accomplished", database.Name)})
 			if changed {
 				if err := r.Status().Update(ctx, database); err != nil {
@@ -75,7 +81,7 @@ func (r *DatabaseReconciler) ReconcileDatabaseFinalizer(ctx context.Context, dat
 			}
 
 			log.Info("Removing Finalizer for Database after successfully perform the operations")
-			if ok := controllerutil.RemoveFinalizer(database, databaseFinalizer); !ok {
+			if ok := controllerutil.RemoveFinalizer(database, r.GetFinalizerName()); !ok {
 				log.Error(errors.New("failed to remove finalizer"), "Failed to remove finalizer for Database")
 				return true, nil
 			}
@@ -94,6 +100,47 @@ func (r *DatabaseReconciler) ReconcileDatabaseFinalizer(ctx context.Context, dat
 	return false, nil
 }
 
+// getDatabaseNamespace returns the namespace of the Database, or nil when it cannot be found.
+func (r *DatabaseReconciler) getDatabaseNamespace(ctx context.Context, database *libsqlv1.Database) (*corev1.Namespace, error) {
+	namespace := &corev1.Namespace{}
+	if err := r.Get(ctx, types.NamespacedName{Name: database.Namespace}, namespace); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	return namespace, nil
+}
+
+// isNamespaceTerminating reports whether the namespace is being deleted. Nothing can be created
+// in it anymore and Kubernetes deletes everything it contains, PVCs included.
+func isNamespaceTerminating(namespace *corev1.Namespace) bool {
+	if namespace == nil {
+		return false
+	}
+	return namespace.Status.Phase == corev1.NamespaceTerminating || !namespace.GetDeletionTimestamp().IsZero()
+}
+
+// ReleaseDatabaseFinalizer removes the finalizer of a Database whose namespace is being deleted,
+// without the status updates and PVC deletion of the finalizer operations, so the Database does
+// not hold up the deletion of its namespace.
+func (r *DatabaseReconciler) ReleaseDatabaseFinalizer(ctx context.Context, database *libsqlv1.Database) (requeue bool, err error) {
+	log := log.FromContext(ctx)
+	if !controllerutil.ContainsFinalizer(database, r.GetFinalizerName()) {
+		return false, nil
+	}
+	log.Info("Removing Finalizer for Database, its namespace is being deleted")
+	controllerutil.RemoveFinalizer(database, r.GetFinalizerName())
+	if err := r.Update(ctx, database); err != nil {
+		if apierrors.IsConflict(err) {
+			return true, nil
+		}
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		log.Error(err, "Failed to remove finalizer for Database")
+		return false, err
+	}
+	return false, nil
+}
+
 // finalizeDatabase will perform the required operations before delete the CR.
 func (r *DatabaseReconciler) DoFinalizerOperationsForDatabase(ctx context.Context, database *libsqlv1.Database) {
 	// Add the cleanup steps that the operator
@@ -109,11 +156,21 @@ func (r *DatabaseReconciler) DoFinalizerOperationsForDatabase(ctx context.Contex
 
 	// The following implementation will raise an event
 	log := log.FromContext(ctx)
-	r.Recorder.Event(database, "Warning", "Deleting",
+	r.recorder(ctx).Event(database, "Warning", "Deleting",
 		fmt.Sprintf("Custom Resource %s is being deleted from the namespace %s",
 			database.Name,
 			database.Namespace))
 
+	if keepPVC, _ := strconv.ParseBool(database.Annotations[databaseKeepPVCAnnotation]); keepPVC {
+		r.recorder(ctx).Event(database, utils.EventNormal, "KeepingPVC",
+			fmt.Sprintf("Keeping the PVCs of Database %s as requested by the %s annotation",
+				database.Name,
+				databaseKeepPVCAnnotation))
+		return
+	}
+
+	r.recorder(ctx).Event(database, utils.EventNormal, "DeletingPVC",
+		fmt.Sprintf("Deleting the PVCs of Database %s", database.Name))
 	err := r.DeleteDatabasePVC(ctx, database)
 	if err != nil {
 		log.Error(err, "Failed to delete database PVC")
@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+)
+
+var _ = Describe("Database finalizer", func() {
+	ctx := context.Background()
+
+	It("should release the finalizer when the namespace is being deleted", func() {
+		namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "terminating-namespace"}}
+		Expect(k8sClient.Create(ctx, namespace)).To(Succeed())
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "database",
+				Namespace:  namespace.Name,
+				Finalizers: []string{databaseFinalizer},
+			},
+			Spec: libsqlv1.DatabaseSpec{
+				Image:   "ghcr.io/tursodatabase/libsql-server:v0.24.21",
+				Storage: libsqlv1.DatabaseStorage{Size: resource.MustParse("1Gi")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, database)).To(Succeed())
+
+		By("Deleting the namespace, which envtest keeps in the Terminating phase")
+		Expect(k8sClient.Delete(ctx, namespace)).To(Succeed())
+
+		controllerReconciler := &DatabaseReconciler{
+			Client:   k8sClient,
+			Scheme:   k8sClient.Scheme(),
+			Recorder: MockEventRecorder{},
+		}
+		typeNamespacedName := types.NamespacedName{Name: database.Name, Namespace: namespace.Name}
+		_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("Checking the finalizer was removed without creating the resources of the Database")
+		Expect(k8sClient.Get(ctx, typeNamespacedName, database)).To(Succeed())
+		Expect(controllerutil.ContainsFinalizer(database, databaseFinalizer)).Should(BeFalse())
+		Expect(database.Status.Conditions).Should(BeEmpty())
+		Expect(k8sClient.Delete(ctx, database)).To(Succeed())
+	})
+
+	It("should delete the PVCs of the StatefulSet by their labels", func() {
+		otherNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "pvc-other-namespace"}}
+		Expect(k8sClient.Create(ctx, otherNamespace)).To(Succeed())
+		database := &libsqlv1.Database{ObjectMeta: metav1.ObjectMeta{Name: "pvc-database", Namespace: "default"}}
+		reconciler := &DatabaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+
+		newPVC := func(name, namespace, databaseName string) *corev1.PersistentVolumeClaim {
+			pvc := &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespace,
+					Labels:    map[string]string{reconciler.GetManagedByLabel(): databaseName, "node": "primary"},
+				},
+				Spec: corev1.PersistentVolumeClaimSpec{
+					AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+					Resources: corev1.VolumeResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, pvc)).To(Succeed())
+			return pvc
+		}
+		deleted := []*corev1.PersistentVolumeClaim{
+			newPVC(utils.GetDatabaseStatefulSetPVCName(database, 0), database.Namespace, database.Name),
+			newPVC(utils.GetDatabaseStatefulSetPVCName(database, 1), database.Namespace, database.Name),
+		}
+		kept := []*corev1.PersistentVolumeClaim{
+			newPVC("pvc-other-database-pvc-other-database-0", database.Namespace, "pvc-other-database"),
+			newPVC(utils.GetDatabaseStatefulSetPVCName(database, 0), otherNamespace.Name, database.Name),
+		}
+
+		Expect(reconciler.DeleteDatabasePVC(ctx, database)).To(Succeed())
+
+		// the pvc-protection finalizer keeps deleted PVCs around with a deletion timestamp
+		isDeleted := func(pvc *corev1.PersistentVolumeClaim) bool {
+			found := &corev1.PersistentVolumeClaim{}
+			err := k8sClient.Get(ctx, types.NamespacedName{Name: pvc.Name, Namespace: pvc.Namespace}, found)
+			if apierrors.IsNotFound(err) {
+				return true
+			}
+			Expect(err).NotTo(HaveOccurred())
+			return !found.GetDeletionTimestamp().IsZero()
+		}
+		for _, pvc := range deleted {
+			Expect(isDeleted(pvc)).Should(BeTrue(), "PVC %s/%s should be deleted", pvc.Namespace, pvc.Name)
+		}
+		for _, pvc := range kept {
+			Expect(isDeleted(pvc)).Should(BeFalse(), "PVC %s/%s should be kept", pvc.Namespace, pvc.Name)
+			Expect(k8sClient.Delete(ctx, pvc)).To(Succeed())
+		}
+	})
+})
@@ -3,67 +3,178 @@ package controller
 import (
 	"context"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 
 	libsqlv1 "github.com/ahti-database/operator/api/v1"
 	"github.com/ahti-database/operator/internal/utils"
 	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
+// Annotations of ingress-nginx managed by the operator on the generated Ingress.
+const (
+	nginxProxyReadTimeoutAnnotation = "nginx.ingress.kubernetes.io/proxy-read-timeout"
+	nginxProxySendTimeoutAnnotation = "nginx.ingress.kubernetes.io/proxy-send-timeout"
+	// ingressWebSocketTimeoutAnnotation records the webSocketTimeout of the spec on the Ingress,
+	// so a timeout the Ingress class ignores is only reported when it changes
+	ingressWebSocketTimeoutAnnotation = "libsql.ahti.io/websocket-timeout"
+)
+
+// nginxIngressController is the controller of the IngressClasses served by ingress-nginx
+const nginxIngressController = "k8s.io/ingress-nginx"
+
+var ingressManagedAnnotations = append([]string{nginxProxyReadTimeoutAnnotation, nginxProxySendTimeoutAnnotation,
+	ingressWebSocketTimeoutAnnotation}, externalDNSAnnotations...)
+
+// ReconcileDatabaseIngress keeps the Ingress of spec.ingress and one Ingress per entry of
+// spec.additionalIngresses. The Ingresses of entries removed from spec.additionalIngresses are
+// deleted with the orphaned resources.
 func (r *DatabaseReconciler) ReconcileDatabaseIngress(ctx context.Context, database *libsqlv1.Database) (*networkingv1.Ingress, error) {
+	ingress, err := r.reconcileDatabaseIngress(ctx, database, utils.GetDatabaseIngressName(database), database.Spec.Ingress)
+	if err != nil {
+		return nil, err
+	}
+	for i := range database.Spec.AdditionalIngresses {
+		additional := &database.Spec.AdditionalIngresses[i]
+		if _, err := r.reconcileDatabaseIngress(ctx, database,
+			utils.GetDatabaseAdditionalIngressName(database, additional.Name), &additional.AhtiDatabaseIngressSpec); err != nil {
+			return ingress, err
+		}
+	}
+	return ingress, nil
+}
+
+// reconcileDatabaseIngress creates, patches or, without spec, deletes the Ingress of the name.
+func (r *DatabaseReconciler) reconcileDatabaseIngress(ctx context.Context, database *libsqlv1.Database, name string, spec *libsqlv1.AhtiDatabaseIngressSpec) (*networkingv1.Ingress, error) {
+	log := log.FromContext(ctx)
 	found := &networkingv1.Ingress{}
 	if err := r.Get(
 		ctx,
 		types.NamespacedName{
-			Name:      utils.GetDatabaseIngressName(database),
+			Name:      name,
 			Namespace: database.Namespace,
 		},
 		found,
 	); err != nil {
-		if apierrors.IsNotFound(err) && database.Spec.Ingress != nil {
-			ingress := r.ConstructDatabaseIngress(ctx, database)
+		if apierrors.IsNotFound(err) && spec != nil {
+			if spec.WaitForReady && !meta.IsStatusConditionTrue(database.Status.Conditions, typeAvailableDatabase) {
+				log.Info("Waiting for the database to be available before creating the Ingress", "ingress", name)
+				return nil, nil
+			}
+			nginx, err := r.isDatabaseIngressNginx(ctx, spec)
+			if err != nil {
+				return nil, err
+			}
+			ingress := r.constructDatabaseIngress(database, name, spec, nginx)
 			if err := r.Create(ctx, ingress); err != nil {
 				return nil, err
 			}
-			r.Recorder.Event(database, utils.EventNormal, "SuccessfulCreate",
+			r.checkDatabaseIngressWebSocketTimeout(ctx, database, name, spec, nginx)
+			r.recorder(ctx).Event(database, utils.EventNormal, "SuccessfulCreate",
 				fmt.Sprintf("create Ingress %s is being created in the Namespace %s success",
-					utils.GetDatabaseIngressName(database),
+					name,
 					database.Namespace))
-		} else if apierrors.IsNotFound(err) && database.Spec.Ingress == nil {
+			return ingress, nil
+		} else if apierrors.IsNotFound(err) && spec == nil {
 			return nil, nil
 		} else {
 			return nil, err
 		}
 	}
-	if database.Spec.Ingress == nil {
+	if spec == nil {
 		// delete ingress if database does not need it
 		if err := r.Delete(ctx, found); err != nil {
 			return nil, err
 		}
 		return nil, nil
-	} else {
-		// patch the statefulset
-		ingress := r.ConstructDatabaseIngress(ctx, database)
-		if err := r.Update(ctx, ingress); err != nil {
-			if apierrors.IsNotFound(err) {
-				return ingress, nil
-			}
-			return nil, err
-		}
-		return ingress, nil
 	}
+	// patch the found ingress, so the fields set by others are kept
+	nginx, err := r.isDatabaseIngressNginx(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+	ingress := r.constructDatabaseIngress(database, name, spec, nginx)
+	// the annotations of the spec applied by the previous patch are replaced, so removed ones are removed
+	managedAnnotations := append(strings.Split(found.Annotations[databaseManagedAnnotationsAnnotation], ","),
+		databaseManagedAnnotationsAnnotation)
+	annotations := utils.ReplaceAnnotations(found.Annotations, append(managedAnnotations, ingressManagedAnnotations...), ingress.Annotations)
+	labels := utils.MergeLabels(found.Labels, ingress.Labels)
+	ownerReferences := withDatabaseOwnerReference(found.OwnerReferences, ingress.OwnerReferences[0])
+	if equality.Semantic.DeepEqual(found.Spec, ingress.Spec) &&
+		equality.Semantic.DeepEqual(found.Labels, labels) &&
+		equality.Semantic.DeepEqual(found.Annotations, annotations) &&
+		equality.Semantic.DeepEqual(found.OwnerReferences, ownerReferences) {
+		return found, nil
+	}
+	previousHost := getIngressHost(found)
+	webSocketTimeoutChanged := found.Annotations[ingressWebSocketTimeoutAnnotation] != annotations[ingressWebSocketTimeoutAnnotation] ||
+		!equality.Semantic.DeepEqual(found.Spec.IngressClassName, spec.IngressClassName)
+	patch := client.MergeFrom(found.DeepCopy())
+	found.Spec = ingress.Spec
+	found.Labels = labels
+	found.Annotations = annotations
+	found.OwnerReferences = ownerReferences
+	if err := r.Patch(ctx, found, patch); err != nil {
+		return nil, err
+	}
+	if webSocketTimeoutChanged {
+		r.checkDatabaseIngressWebSocketTimeout(ctx, database, name, spec, nginx)
+	}
+	if previousHost != spec.Host {
+		// the Ingress is updated in place so external-dns moves its records instead of flapping,
+		// but with an upsert-only policy the record of the previous host is left behind
+		r.recorder(ctx).Event(database, utils.EventWarning, "IngressHostChanged",
+			fmt.Sprintf("Host of Ingress %s changed from %s to %s, the DNS record of %s may have to be removed",
+				found.Name,
+				previousHost,
+				spec.Host,
+				previousHost))
+	}
+	return found, nil
+}
+
+// getIngressHost returns the host of the single rule of an Ingress generated for a Database.
+func getIngressHost(ingress *networkingv1.Ingress) string {
+	if len(ingress.Spec.Rules) == 0 {
+		return ""
+	}
+	return ingress.Spec.Rules[0].Host
+}
+
+func (r *DatabaseReconciler) ConstructDatabaseIngress(ctx context.Context, database *libsqlv1.Database) (*networkingv1.Ingress, error) {
+	nginx, err := r.isDatabaseIngressNginx(ctx, database.Spec.Ingress)
+	if err != nil {
+		return nil, err
+	}
+	return r.constructDatabaseIngress(database, utils.GetDatabaseIngressName(database), database.Spec.Ingress, nginx), nil
 }
 
-func (r *DatabaseReconciler) ConstructDatabaseIngress(ctx context.Context, database *libsqlv1.Database) *networkingv1.Ingress {
+// constructDatabaseIngress builds the Ingress of the name from spec.ingress or an entry of
+// spec.additionalIngresses. The annotations generated by the operator take precedence over
+// those of the spec. nginx tells whether the Ingress class is served by ingress-nginx.
+func (r *DatabaseReconciler) constructDatabaseIngress(database *libsqlv1.Database, name string, spec *libsqlv1.AhtiDatabaseIngressSpec, nginx bool) *networkingv1.Ingress {
+	path := "/"
+	if spec.Path != "" {
+		path = spec.Path
+	}
+	pathType := ptr.To(networkingv1.PathTypePrefix)
+	if spec.PathType != nil {
+		pathType = spec.PathType
+	}
 	ingress := &networkingv1.Ingress{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      utils.GetDatabaseIngressName(database),
+			Name:      name,
 			Namespace: database.Namespace,
 			OwnerReferences: []metav1.OwnerReference{
 				{
@@ -73,29 +184,25 @@ func (r *DatabaseReconciler) ConstructDatabaseIngress(ctx context.Context, datab
 					UID:        database.UID,
 				},
 			},
-			Labels: map[string]string{
-				databaseLabel: database.Name,
-				"node":        "primary",
-			}},
+			Labels: r.databaseLabels(database),
+			Annotations: utils.MergeLabels(withManagedAnnotations(spec.Annotations),
+				constructExternalDNSAnnotations(spec.ExternalDNS),
+				constructIngressWebSocketTimeoutAnnotations(spec, nginx)),
+		},
 		Spec: networkingv1.IngressSpec{
-			IngressClassName: database.Spec.Ingress.IngressClassName,
-			TLS:              database.Spec.Ingress.TLS,
+			IngressClassName: spec.IngressClassName,
+			TLS:              spec.TLS,
 			Rules: []networkingv1.IngressRule{
 				{
-					Host: database.Spec.Ingress.Host,
+					Host: spec.Host,
 					IngressRuleValue: networkingv1.IngressRuleValue{
 						HTTP: &networkingv1.HTTPIngressRuleValue{
 							Paths: []networkingv1.HTTPIngressPath{
 								{
-									Path:     "/",
-									PathType: ptr.To(networkingv1.PathTypePrefix),
+									Path:     path,
+									PathType: pathType,
 									Backend: networkingv1.IngressBackend{
-										Service: &networkingv1.IngressServiceBackend{
-											Name: utils.GetDatabaseServiceName(database, false),
-											Port: networkingv1.ServiceBackendPort{
-												Number: int32(8080),
-											},
-										},
+										Service: constructDatabaseIngressBackend(database),
 									},
 								},
 							}},
@@ -107,6 +214,92 @@ func (r *DatabaseReconciler) ConstructDatabaseIngress(ctx context.Context, datab
 	return ingress
 }
 
+// constructIngressWebSocketTimeoutAnnotations returns the webSocketTimeout of the Ingress and,
+// when its class is served by ingress-nginx, the ingress-nginx timeouts of it. It returns nil
+// when the timeout is not set.
+func constructIngressWebSocketTimeoutAnnotations(ingress *libsqlv1.AhtiDatabaseIngressSpec, nginx bool) map[string]string {
+	if ingress.WebSocketTimeout == nil {
+		return nil
+	}
+	annotations := map[string]string{ingressWebSocketTimeoutAnnotation: ingress.WebSocketTimeout.Duration.String()}
+	if !nginx {
+		return annotations
+	}
+	// the timeouts are whole seconds, round up so a timeout below a second is not disabled
+	seconds := strconv.FormatInt(int64(math.Ceil(ingress.WebSocketTimeout.Seconds())), 10)
+	annotations[nginxProxyReadTimeoutAnnotation] = seconds
+	annotations[nginxProxySendTimeoutAnnotation] = seconds
+	return annotations
+}
+
+// isDatabaseIngressNginx reports whether the Ingress class of the Ingress is served by
+// ingress-nginx. The class is only looked up when the Ingress has a webSocketTimeout, the only
+// setting that depends on it.
+func (r *DatabaseReconciler) isDatabaseIngressNginx(ctx context.Context, ingress *libsqlv1.AhtiDatabaseIngressSpec) (bool, error) {
+	if ingress == nil || ingress.WebSocketTimeout == nil {
+		return false, nil
+	}
+	return r.isNginxIngressClass(ctx, ingress.IngressClassName)
+}
+
+// isNginxIngressClass reports whether the IngressClass of the name has the ingress-nginx
+// controller. Without a name the default IngressClass of the cluster is used. A class that does
+// not exist is not served by ingress-nginx.
+func (r *DatabaseReconciler) isNginxIngressClass(ctx context.Context, ingressClassName *string) (bool, error) {
+	if ingressClassName != nil {
+		ingressClass := &networkingv1.IngressClass{}
+		if err := r.Get(ctx, types.NamespacedName{Name: *ingressClassName}, ingressClass); err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+		return ingressClass.Spec.Controller == nginxIngressController, nil
+	}
+	ingressClasses := &networkingv1.IngressClassList{}
+	if err := r.List(ctx, ingressClasses); err != nil {
+		return false, err
+	}
+	for _, ingressClass := range ingressClasses.Items {
+		if ingressClass.Annotations[networkingv1.AnnotationIsDefaultIngressClass] == "true" {
+			return ingressClass.Spec.Controller == nginxIngressController, nil
+		}
+	}
+	return false, nil
+}
+
+// checkDatabaseIngressWebSocketTimeout warns that the webSocketTimeout of the Ingress is ignored
+// by its Ingress class.
+func (r *DatabaseReconciler) checkDatabaseIngressWebSocketTimeout(ctx context.Context, database *libsqlv1.Database, name string, ingress *libsqlv1.AhtiDatabaseIngressSpec, nginx bool) {
+	if ingress.WebSocketTimeout == nil || nginx {
+		return
+	}
+	ingressClassName := "default"
+	if ingress.IngressClassName != nil {
+		ingressClassName = *ingress.IngressClassName
+	}
+	r.recorder(ctx).Event(database, utils.EventWarning, "WebSocketTimeoutUnsupported",
+		fmt.Sprintf("webSocketTimeout of Ingress %s is only supported for ingress-nginx, configure the timeouts of Ingress class %s on the Ingress controller",
+			name,
+			ingressClassName))
+}
+
+// constructDatabaseIngressBackend routes to the database, or to the maintenance page while the
+// Database is in maintenance.
+func constructDatabaseIngressBackend(database *libsqlv1.Database) *networkingv1.IngressServiceBackend {
+	if isDatabaseInMaintenance(database) {
+		return &networkingv1.IngressServiceBackend{
+			Name: utils.GetDatabaseMaintenanceName(database),
+			Port: networkingv1.ServiceBackendPort{
+				Number: maintenancePort,
+			},
+		}
+	}
+	return &networkingv1.IngressServiceBackend{
+		Name: utils.GetDatabaseServiceName(database, false),
+		Port: networkingv1.ServiceBackendPort{
+			Number: databaseHTTPPort,
+		},
+	}
+}
+
 func (r *DatabaseReconciler) MapDatabaseIngressToReconcile(ctx context.Context, object client.Object) []reconcile.Request {
 	ingress := object.(*networkingv1.Ingress)
 	gvk, err := apiutil.GVKForObject(&libsqlv1.Database{}, r.Scheme)
@@ -0,0 +1,156 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+)
+
+var _ = Describe("Database Ingress", func() {
+	It("should translate spec.ingress.webSocketTimeout into the ingress-nginx timeouts", func() {
+		ctx := context.Background()
+		for _, ingressClass := range []*networkingv1.IngressClass{
+			{ObjectMeta: metav1.ObjectMeta{Name: "public"}, Spec: networkingv1.IngressClassSpec{Controller: nginxIngressController}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "traefik", Annotations: map[string]string{networkingv1.AnnotationIsDefaultIngressClass: "true"}},
+				Spec: networkingv1.IngressClassSpec{Controller: "traefik.io/ingress-controller"}},
+		} {
+			Expect(k8sClient.Create(ctx, ingressClass)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, ingressClass)
+		}
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "default"},
+			Spec: libsqlv1.DatabaseSpec{
+				Ingress: &libsqlv1.AhtiDatabaseIngressSpec{
+					IngressClassName: ptr.To("public"),
+					Host:             "database.ahti.io",
+					WebSocketTimeout: &metav1.Duration{Duration: time.Hour},
+				},
+			},
+		}
+		reconciler := &DatabaseReconciler{Client: k8sClient}
+		ingress, err := reconciler.ConstructDatabaseIngress(ctx, database)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ingress.Annotations).Should(HaveKeyWithValue(nginxProxyReadTimeoutAnnotation, "3600"))
+		Expect(ingress.Annotations).Should(HaveKeyWithValue(nginxProxySendTimeoutAnnotation, "3600"))
+
+		By("Leaving out the annotations for other ingress controllers")
+		database.Spec.Ingress.IngressClassName = ptr.To("traefik")
+		ingress, err = reconciler.ConstructDatabaseIngress(ctx, database)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ingress.Annotations).ShouldNot(HaveKey(nginxProxyReadTimeoutAnnotation))
+
+		By("Leaving them out for a default class of another ingress controller")
+		database.Spec.Ingress.IngressClassName = nil
+		ingress, err = reconciler.ConstructDatabaseIngress(ctx, database)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ingress.Annotations).ShouldNot(HaveKey(nginxProxyReadTimeoutAnnotation))
+	})
+
+	It("should report a webSocketTimeout ignored by the Ingress class only when it changes", func() {
+		ctx := context.Background()
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "websocket-database", Namespace: "default", UID: "websocket-database-uid"},
+			Spec: libsqlv1.DatabaseSpec{
+				Ingress: &libsqlv1.AhtiDatabaseIngressSpec{
+					IngressClassName: ptr.To("alb"),
+					Host:             "websocket.ahti.io",
+					WebSocketTimeout: &metav1.Duration{Duration: time.Hour},
+				},
+			},
+		}
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &DatabaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: recorder}
+		ingress, err := reconciler.ReconcileDatabaseIngress(ctx, database)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(k8sClient.Delete, ctx, ingress)
+		Expect(recorder.Events).Should(Receive(ContainSubstring("WebSocketTimeoutUnsupported")))
+		Expect(recorder.Events).Should(Receive(ContainSubstring("SuccessfulCreate")))
+
+		By("Reconciling again, nothing is reported")
+		_, err = reconciler.ReconcileDatabaseIngress(ctx, database)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Events).ShouldNot(Receive())
+
+		By("Reporting a changed timeout")
+		database.Spec.Ingress.WebSocketTimeout = &metav1.Duration{Duration: 2 * time.Hour}
+		_, err = reconciler.ReconcileDatabaseIngress(ctx, database)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Events).Should(Receive(ContainSubstring("WebSocketTimeoutUnsupported")))
+	})
+
+	It("should keep one Ingress per entry of spec.additionalIngresses", func() {
+		ctx := context.Background()
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "dual-ingress-database", Namespace: "default"},
+			Spec: libsqlv1.DatabaseSpec{
+				Image:   "ghcr.io/tursodatabase/libsql-server:v0.24.21",
+				Storage: libsqlv1.DatabaseStorage{Size: resource.MustParse("1Gi")},
+				AdditionalIngresses: []libsqlv1.AhtiDatabaseAdditionalIngressSpec{
+					{Name: "internal", AhtiDatabaseIngressSpec: libsqlv1.AhtiDatabaseIngressSpec{
+						IngressClassName: ptr.To("nginx"),
+						Host:             "database.internal.ahti.io",
+					}},
+					{Name: "external", AhtiDatabaseIngressSpec: libsqlv1.AhtiDatabaseIngressSpec{
+						IngressClassName: ptr.To("alb"),
+						Host:             "database.ahti.io",
+						Annotations:      map[string]string{"alb.ingress.kubernetes.io/scheme": "internet-facing"},
+					}},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, database)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, database)
+		reconciler := &DatabaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: MockEventRecorder{}}
+		getIngress := func(name string) (*networkingv1.Ingress, error) {
+			ingress := &networkingv1.Ingress{}
+			err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, ingress)
+			return ingress, err
+		}
+
+		ingress, err := reconciler.ReconcileDatabaseIngress(ctx, database)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ingress).Should(BeNil())
+		internal, err := getIngress("dual-ingress-database-ingress-internal")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(internal.Spec.IngressClassName).Should(Equal(ptr.To("nginx")))
+		Expect(internal.Spec.Rules[0].Host).Should(Equal("database.internal.ahti.io"))
+		external, err := getIngress("dual-ingress-database-ingress-external")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(external.Spec.IngressClassName).Should(Equal(ptr.To("alb")))
+		Expect(external.Annotations).Should(HaveKeyWithValue("alb.ingress.kubernetes.io/scheme", "internet-facing"))
+
+		By("Removing the annotations dropped from the spec, keeping those set by others")
+		patch := client.MergeFrom(external.DeepCopy())
+		metav1.SetMetaDataAnnotation(&external.ObjectMeta, "alb.ingress.kubernetes.io/conditions", "[]")
+		Expect(k8sClient.Patch(ctx, external, patch)).To(Succeed())
+		database.Spec.AdditionalIngresses[1].Annotations = nil
+		_, err = reconciler.ReconcileDatabaseIngress(ctx, database)
+		Expect(err).NotTo(HaveOccurred())
+		external, err = getIngress("dual-ingress-database-ingress-external")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(external.Annotations).ShouldNot(HaveKey("alb.ingress.kubernetes.io/scheme"))
+		Expect(external.Annotations).Should(HaveKey("alb.ingress.kubernetes.io/conditions"))
+
+		By("Deleting the Ingress of a removed entry")
+		database.Spec.AdditionalIngresses = database.Spec.AdditionalIngresses[:1]
+		Expect(reconciler.DeleteOrphanedDatabaseResources(ctx, database)).To(Succeed())
+		_, err = getIngress("dual-ingress-database-ingress-external")
+		Expect(apierrors.IsNotFound(err)).Should(BeTrue())
+		internal, err = getIngress(utils.GetDatabaseAdditionalIngressName(database, "internal"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Delete(ctx, internal)).To(Succeed())
+	})
+})
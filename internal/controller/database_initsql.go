@@ -0,0 +1,273 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+	"github.com/golang-jwt/jwt/v5"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// DefaultInitSQLImage runs the init SQL Job, it needs curl and a POSIX shell
+	DefaultInitSQLImage string = "curlimages/curl:8.7.1"
+	// databaseInitSQLHashAnnotation on the init SQL Job records the script it executes
+	databaseInitSQLHashAnnotation string = "libsql.ahti.io/init-sql-hash"
+
+	initSQLAppName      string = "init-sql"
+	initSQLMountPath    string = "/init-sql"
+	initSQLBackoffLimit int32  = 3
+	// initSQLTokenLifetime bounds the token the Job authenticates with, it is only used once
+	initSQLTokenLifetime = time.Hour
+)
+
+// initSQLCommand posts the pipeline to the Hrana over HTTP endpoint of sqld. The endpoint
+// answers 200 even when a statement fails, so the results are checked for errors as well.
+const initSQLCommand = `response=$(curl --silent --show-error --fail-with-body \
+  -H @` + initSQLMountPath + `/headers --data-binary @` + initSQLMountPath + `/pipeline.json \
+  "$DATABASE_URL/v2/pipeline") || { echo "$response"; exit 1; }
+echo "$response"
+case "$response" in *'"type":"error"'*) exit 1 ;; esac`
+
+// ReconcileDatabaseInitSQL executes spec.initSQL once against the primary, through a Job
+// started the first time the Database is available. The outcome is recorded on the status,
+// a script that succeeded is never executed again and a failed one is retried once it changes.
+func (r *DatabaseReconciler) ReconcileDatabaseInitSQL(ctx context.Context, database *libsqlv1.Database, authSecret *corev1.Secret) error {
+	log := log.FromContext(ctx)
+	if database.Spec.InitSQL == nil || (database.Status.InitSQL != nil && database.Status.InitSQL.Succeeded) {
+		return nil
+	}
+	if !meta.IsStatusConditionTrue(database.Status.Conditions, typeAvailableDatabase) {
+		// the pods of the Database are watched, the Job is started once the primary is ready
+		return nil
+	}
+	script, err := r.getDatabaseInitSQL(ctx, database)
+	if err != nil {
+		return err
+	}
+	name := utils.GetDatabaseInitSQLName(database)
+	found := &batchv1.Job{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, found); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		secret, err := r.ConstructDatabaseInitSQLSecret(database, authSecret, script)
+		if err != nil {
+			return err
+		}
+		if err := r.applyDatabaseInitSQLSecret(ctx, secret); err != nil {
+			return err
+		}
+		job := r.ConstructDatabaseInitSQLJob(database, script)
+		log.Info("Creating init SQL Job")
+		if err := r.Create(ctx, job); err != nil {
+			return err
+		}
+		r.recorder(ctx).Event(database, utils.EventNormal, "SuccessfulCreate",
+			fmt.Sprintf("create Job %s is being created in the Namespace %s success",
+				job.Name,
+				database.Namespace))
+		database.Status.InitSQL = &libsqlv1.DatabaseInitSQLStatus{Job: job.Name}
+		return nil
+	}
+
+	switch {
+	case found.Status.Succeeded > 0:
+		database.Status.InitSQL = &libsqlv1.DatabaseInitSQLStatus{Job: found.Name, Succeeded: true, CompletionTime: found.Status.CompletionTime}
+		r.recorder(ctx).Event(database, utils.EventNormal, "InitSQLSucceeded",
+			fmt.Sprintf("Init SQL of Database %s was executed", database.Name))
+		// the secret holds a token of the database, the Job is not needed anymore either
+		if err := r.Delete(ctx, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: database.Namespace}}); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+		return client.IgnoreNotFound(r.Delete(ctx, found, client.PropagationPolicy(metav1.DeletePropagationBackground)))
+	case found.Annotations[databaseInitSQLHashAnnotation] != utils.HashValue([]byte(script)):
+		// the script changed before it succeeded, the Job is recreated with the new one
+		return client.IgnoreNotFound(r.Delete(ctx, found, client.PropagationPolicy(metav1.DeletePropagationBackground)))
+	case isJobFailed(found):
+		if database.Status.InitSQL == nil || !database.Status.InitSQL.Failed {
+			r.recorder(ctx).Event(database, utils.EventWarning, "InitSQLFailed",
+				fmt.Sprintf("Init SQL of Database %s failed, see the logs of Job %s", database.Name, found.Name))
+		}
+		database.Status.InitSQL = &libsqlv1.DatabaseInitSQLStatus{Job: found.Name, Failed: true}
+	default:
+		database.Status.InitSQL = &libsqlv1.DatabaseInitSQLStatus{Job: found.Name}
+	}
+	return nil
+}
+
+func isJobFailed(job *batchv1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// getDatabaseInitSQL returns the inline script of spec.initSQL or reads it from its ConfigMap.
+func (r *DatabaseReconciler) getDatabaseInitSQL(ctx context.Context, database *libsqlv1.Database) (string, error) {
+	initSQL := database.Spec.InitSQL
+	if initSQL.ConfigMapKeyRef == nil {
+		return initSQL.SQL, nil
+	}
+	configMap := &corev1.ConfigMap{}
+	if err := r.Get(ctx, types.NamespacedName{Name: initSQL.ConfigMapKeyRef.Name, Namespace: database.Namespace}, configMap); err != nil {
+		return "", fmt.Errorf("failed to get ConfigMap %s of spec.initSQL: %w", initSQL.ConfigMapKeyRef.Name, err)
+	}
+	script, ok := configMap.Data[initSQL.ConfigMapKeyRef.Key]
+	if !ok {
+		return "", fmt.Errorf("ConfigMap %s of spec.initSQL has no key %s", configMap.Name, initSQL.ConfigMapKeyRef.Key)
+	}
+	return script, nil
+}
+
+// applyDatabaseInitSQLSecret creates the secret of the init SQL Job, or replaces the one left
+// behind by a previous Job.
+func (r *DatabaseReconciler) applyDatabaseInitSQLSecret(ctx context.Context, secret *corev1.Secret) error {
+	found := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: secret.Name, Namespace: secret.Namespace}, found); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		return r.Create(ctx, secret)
+	}
+	patch := client.MergeFrom(found.DeepCopy())
+	found.Data = secret.Data
+	return r.Patch(ctx, found, patch)
+}
+
+// ConstructDatabaseInitSQLSecret holds the Hrana pipeline executing the script and the headers
+// of the request, including a short lived token when the Database requires auth.
+func (r *DatabaseReconciler) ConstructDatabaseInitSQLSecret(database *libsqlv1.Database, authSecret *corev1.Secret, script string) (*corev1.Secret, error) {
+	pipeline, err := json.Marshal(map[string]interface{}{
+		"requests": []interface{}{
+			map[string]interface{}{"type": "sequence", "sql": script},
+			map[string]interface{}{"type": "close"},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	headers := "Content-Type: application/json\n"
+	if database.Spec.Auth && authSecret != nil {
+		privateKey, err := utils.ParsePrivateKey(getSecretValue(authSecret, "PRIVATE_KEY"))
+		if err != nil {
+			return nil, fmt.Errorf("auth Secret %s has no valid PRIVATE_KEY to sign the token of the init SQL Job: %w", authSecret.Name, err)
+		}
+		now := time.Now()
+		token, err := utils.GenerateJWT(privateKey, jwt.MapClaims{
+			"iat": now.Unix(),
+			"exp": now.Add(initSQLTokenLifetime).Unix(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		headers += fmt.Sprintf("Authorization: Bearer %s\n", token)
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      utils.GetDatabaseInitSQLName(database),
+			Namespace: database.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: databaseAPIVersion,
+					Kind:       databaseKind,
+					Name:       database.Name,
+					UID:        database.UID,
+				},
+			},
+			Labels: r.databaseLabels(database),
+		},
+		Data: map[string][]byte{
+			"pipeline.json": pipeline,
+			"headers":       []byte(headers),
+		},
+	}, nil
+}
+
+func (r *DatabaseReconciler) ConstructDatabaseInitSQLJob(database *libsqlv1.Database, script string) *batchv1.Job {
+	image := r.InitSQLImage
+	if image == "" {
+		image = DefaultInitSQLImage
+	}
+	labels := utils.MergeLabels(r.namespaceResourceLabels(database.Namespace), map[string]string{
+		appNameLabel:      initSQLAppName,
+		appInstanceLabel:  database.Name,
+		appManagedByLabel: operatorName,
+		appPartOfLabel:    databaseAppName,
+	})
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      utils.GetDatabaseInitSQLName(database),
+			Namespace: database.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: databaseAPIVersion,
+					Kind:       databaseKind,
+					Name:       database.Name,
+					UID:        database.UID,
+				},
+			},
+			Labels: labels,
+			Annotations: map[string]string{
+				databaseInitSQLHashAnnotation: utils.HashValue([]byte(script)),
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: ptr.To(initSQLBackoffLimit),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy:    corev1.RestartPolicyNever,
+					ImagePullSecrets: r.getDatabaseImagePullSecrets(database),
+					Containers: []corev1.Container{
+						{
+							Name:    initSQLAppName,
+							Image:   r.rewriteImage(image),
+							Command: []string{"/bin/sh", "-c", initSQLCommand},
+							Env: []corev1.EnvVar{
+								{
+									Name: "DATABASE_URL",
+									Value: fmt.Sprintf("http://%s:%d",
+										utils.GetDatabaseServiceFQDN(database, true), getDatabaseHTTPPort(database)),
+								},
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      initSQLAppName,
+									MountPath: initSQLMountPath,
+									ReadOnly:  true,
+								},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: initSQLAppName,
+							VolumeSource: corev1.VolumeSource{
+								Secret: &corev1.SecretVolumeSource{
+									SecretName: utils.GetDatabaseInitSQLName(database),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
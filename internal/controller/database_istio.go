@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var peerAuthenticationGVK = schema.GroupVersionKind{
+	Group:   "security.istio.io",
+	Version: "v1beta1",
+	Kind:    "PeerAuthentication",
+}
+
+// typePeerAuthenticationUnsupported is True while spec.istio.peerAuthentication is ignored because
+// the Istio CRDs are not installed
+const typePeerAuthenticationUnsupported = "PeerAuthenticationUnsupported"
+
+// istioInjectLabel enables or disables the istio-proxy sidecar of a pod
+const istioInjectLabel = "sidecar.istio.io/inject"
+
+// databaseIstioPodLabels returns the Istio labels of the database pods, if any.
+func databaseIstioPodLabels(database *libsqlv1.Database) map[string]string {
+	if database.Spec.Istio == nil || database.Spec.Istio.SidecarInjection == nil {
+		return nil
+	}
+	return map[string]string{
+		istioInjectLabel: strconv.FormatBool(*database.Spec.Istio.SidecarInjection),
+	}
+}
+
+// ReconcileDatabasePeerAuthentication keeps a PeerAuthentication selecting the database pods
+// when spec.istio.peerAuthentication is set, and deletes it otherwise.
+// When the Istio CRDs are not installed the policy is skipped and the
+// PeerAuthenticationUnsupported condition is set.
+func (r *DatabaseReconciler) ReconcileDatabasePeerAuthentication(ctx context.Context, database *libsqlv1.Database) error {
+	enabled := database.Spec.Istio != nil && database.Spec.Istio.PeerAuthentication != nil
+	found := &unstructured.Unstructured{}
+	found.SetGroupVersionKind(peerAuthenticationGVK)
+	err := r.Get(ctx, types.NamespacedName{Name: database.Name, Namespace: database.Namespace}, found)
+	if meta.IsNoMatchError(err) {
+		if enabled {
+			r.setDatabaseCRDNotInstalled(ctx, database, typePeerAuthenticationUnsupported,
+				"Istio CRDs are not installed in the cluster, spec.istio.peerAuthentication is ignored")
+		} else {
+			meta.RemoveStatusCondition(&database.Status.Conditions, typePeerAuthenticationUnsupported)
+		}
+		return nil
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	meta.RemoveStatusCondition(&database.Status.Conditions, typePeerAuthenticationUnsupported)
+	notFound := apierrors.IsNotFound(err)
+	if !enabled {
+		if notFound {
+			return nil
+		}
+		return client.IgnoreNotFound(r.Delete(ctx, found))
+	}
+	peerAuthentication := r.ConstructDatabasePeerAuthentication(database)
+	if notFound {
+		if err := r.Create(ctx, peerAuthentication); err != nil {
+			return err
+		}
+		r.recorder(ctx).Event(database, utils.EventNormal, "SuccessfulCreate",
+			fmt.Sprintf("create PeerAuthentication %s is being created in the Namespace %s success",
+				peerAuthentication.GetName(),
+				database.Namespace))
+		return nil
+	}
+	if utils.ContainsFields(found.Object["spec"], peerAuthentication.Object["spec"]) {
+		return nil
+	}
+	patch := client.MergeFrom(found.DeepCopy())
+	found.Object["spec"] = peerAuthentication.Object["spec"]
+	return r.Patch(ctx, found, patch)
+}
+
+func (r *DatabaseReconciler) ConstructDatabasePeerAuthentication(database *libsqlv1.Database) *unstructured.Unstructured {
+	mode := database.Spec.Istio.PeerAuthentication.Mode
+	if mode == "" {
+		mode = "STRICT"
+	}
+	matchLabels := map[string]interface{}{}
+	for key, value := range r.databaseSelectorLabels(database) {
+		matchLabels[key] = value
+	}
+	peerAuthentication := &unstructured.Unstructured{}
+	peerAuthentication.SetGroupVersionKind(peerAuthenticationGVK)
+	peerAuthentication.SetName(database.Name)
+	peerAuthentication.SetNamespace(database.Namespace)
+	peerAuthentication.SetOwnerReferences([]metav1.OwnerReference{
+		{
+			APIVersion: databaseAPIVersion,
+			Kind:       databaseKind,
+			Name:       database.Name,
+			UID:        database.UID,
+		},
+	})
+	peerAuthentication.SetLabels(r.databaseLabels(database))
+	peerAuthentication.Object["spec"] = map[string]interface{}{
+		"selector": map[string]interface{}{
+			"matchLabels": matchLabels,
+		},
+		"mtls": map[string]interface{}{
+			"mode": mode,
+		},
+	}
+	return peerAuthentication
+}
@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// databaseJWKSKey is the key of the JWKS ConfigMap holding the key set, named after the
+// well-known path it is usually served under.
+const databaseJWKSKey = "jwks.json"
+
+// ReconcileDatabaseJWKSConfigMap keeps a ConfigMap with the public key of authSecret as a JSON
+// Web Key Set when spec.publishJWKS is set and auth is on, and deletes it otherwise. The key set
+// is built from authSecret on every reconcile, so it follows rotated keys.
+func (r *DatabaseReconciler) ReconcileDatabaseJWKSConfigMap(ctx context.Context, database *libsqlv1.Database, authSecret *corev1.Secret) (*corev1.ConfigMap, error) {
+	publish := database.Spec.PublishJWKS && database.Spec.Auth && authSecret != nil
+	found := &corev1.ConfigMap{}
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      utils.GetDatabaseJWKSConfigMapName(database),
+		Namespace: database.Namespace,
+	}, found); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		if !publish {
+			return nil, nil
+		}
+		configMap, err := r.ConstructDatabaseJWKSConfigMap(database, authSecret)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.Create(ctx, configMap); err != nil {
+			return nil, err
+		}
+		r.recorder(ctx).Event(database, utils.EventNormal, "SuccessfulCreate",
+			fmt.Sprintf("create ConfigMap %s is being created in the Namespace %s success",
+				configMap.Name,
+				database.Namespace))
+		return configMap, nil
+	}
+	if !publish {
+		if !isOwnedByDatabase(found, database) {
+			return nil, nil
+		}
+		// delete jwks config map if database does not publish its key
+		return nil, r.Delete(ctx, found)
+	}
+	if !isOwnedByDatabase(found, database) {
+		return nil, fmt.Errorf("config map %s already exists and is not owned by Database %s", found.Name, database.Name)
+	}
+	configMap, err := r.ConstructDatabaseJWKSConfigMap(database, authSecret)
+	if err != nil {
+		return nil, err
+	}
+	labels := utils.MergeLabels(found.Labels, configMap.Labels)
+	if equality.Semantic.DeepEqual(found.Data, configMap.Data) &&
+		equality.Semantic.DeepEqual(found.Labels, labels) {
+		return found, nil
+	}
+	patch := client.MergeFrom(found.DeepCopy())
+	found.Data = configMap.Data
+	found.Labels = labels
+	if err := r.Patch(ctx, found, patch); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+func (r *DatabaseReconciler) ConstructDatabaseJWKSConfigMap(database *libsqlv1.Database, authSecret *corev1.Secret) (*corev1.ConfigMap, error) {
+	publicKey, err := utils.ParsePublicKey(getSecretValue(authSecret, "PUBLIC_KEY"))
+	if err != nil {
+		return nil, fmt.Errorf("auth Secret %s has no valid PUBLIC_KEY to publish as JWKS: %w", authSecret.Name, err)
+	}
+	jwks, err := utils.NewJWKS(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      utils.GetDatabaseJWKSConfigMapName(database),
+			Namespace: database.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: databaseAPIVersion,
+					Kind:       databaseKind,
+					Name:       database.Name,
+					UID:        database.UID,
+				},
+			},
+			Labels: r.databaseLabels(database),
+		},
+		Data: map[string]string{
+			databaseJWKSKey: string(jwks),
+		},
+	}, nil
+}
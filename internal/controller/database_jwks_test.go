@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+)
+
+var _ = Describe("Database JWKS ConfigMap", func() {
+	ctx := context.Background()
+
+	It("should publish the public key of the auth secret and follow its rotation", func() {
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "jwks-database", Namespace: "default"},
+			Spec: libsqlv1.DatabaseSpec{
+				Image:       "ghcr.io/tursodatabase/libsql-server:v0.24.21",
+				Auth:        true,
+				PublishJWKS: true,
+				Storage:     libsqlv1.DatabaseStorage{Size: resource.MustParse("1Gi")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, database)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, database)
+		reconciler := &DatabaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: MockEventRecorder{}}
+		authSecret, err := reconciler.ReconcileDatabaseSecrets(ctx, database)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(k8sClient.Delete, ctx, authSecret)
+		configMapName := types.NamespacedName{Name: utils.GetDatabaseJWKSConfigMapName(database), Namespace: database.Namespace}
+
+		_, err = reconciler.ReconcileDatabaseJWKSConfigMap(ctx, database, authSecret)
+		Expect(err).NotTo(HaveOccurred())
+		configMap := &corev1.ConfigMap{}
+		Expect(k8sClient.Get(ctx, configMapName, configMap)).To(Succeed())
+		publicKey := string(getSecretValue(authSecret, "PUBLIC_KEY"))
+		Expect(configMap.Data[databaseJWKSKey]).Should(ContainSubstring(`"x":"` + publicKey + `"`))
+
+		By("Rotating the keys, the key set is published again")
+		rotatedPublicKey, rotatedPrivateKey, err := utils.GenerateAsymmetricKeys()
+		Expect(err).NotTo(HaveOccurred())
+		encoding := base64.URLEncoding.WithPadding(base64.NoPadding)
+		authSecret.Data["PUBLIC_KEY"] = []byte(encoding.EncodeToString(rotatedPublicKey))
+		authSecret.Data["PRIVATE_KEY"] = []byte(encoding.EncodeToString(rotatedPrivateKey))
+		Expect(k8sClient.Update(ctx, authSecret)).To(Succeed())
+		_, err = reconciler.ReconcileDatabaseJWKSConfigMap(ctx, database, authSecret)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Get(ctx, configMapName, configMap)).To(Succeed())
+		Expect(configMap.Data[databaseJWKSKey]).Should(ContainSubstring(`"x":"` + encoding.EncodeToString(rotatedPublicKey) + `"`))
+
+		By("Turning the option off, the ConfigMap is deleted")
+		database.Spec.PublishJWKS = false
+		_, err = reconciler.ReconcileDatabaseJWKSConfigMap(ctx, database, authSecret)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(apierrors.IsNotFound(k8sClient.Get(ctx, configMapName, configMap))).Should(BeTrue())
+	})
+})
@@ -0,0 +1,156 @@
+package controller
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Recommended labels shared by all resources generated for a Database.
+// More info: https://kubernetes.io/docs/concepts/overview/working-with-objects/common-labels/
+const (
+	appNameLabel      string = "app.kubernetes.io/name"
+	appInstanceLabel  string = "app.kubernetes.io/instance"
+	appManagedByLabel string = "app.kubernetes.io/managed-by"
+	appPartOfLabel    string = "app.kubernetes.io/part-of"
+
+	databaseServerName string = "libsql-server"
+	operatorName       string = "ahti-operator"
+)
+
+// databaseSelectorLabels select the primary pods of a Database. They are part of the
+// immutable StatefulSet selector, so they must never change.
+func (r *DatabaseReconciler) databaseSelectorLabels(database *libsqlv1.Database) map[string]string {
+	return map[string]string{
+		r.GetManagedByLabel(): database.Name,
+		"node":                "primary",
+	}
+}
+
+// getDatabasePodSelectorLabels returns the labels the pods of the Database and their data volumes
+// are found by. They are the selector labels of its StatefulSet, which keeps the selector it was
+// created with, e.g. under another managed-by label key. Without StatefulSet, or one that only
+// selects by expressions, the selector labels of the operator are returned.
+func (r *DatabaseReconciler) getDatabasePodSelectorLabels(ctx context.Context, database *libsqlv1.Database) (map[string]string, error) {
+	statefulSet := &appsv1.StatefulSet{}
+	if err := r.Get(ctx, types.NamespacedName{Name: database.Name, Namespace: database.Namespace}, statefulSet); err != nil {
+		if apierrors.IsNotFound(err) {
+			return r.databaseSelectorLabels(database), nil
+		}
+		return nil, err
+	}
+	if statefulSet.Spec.Selector == nil || len(statefulSet.Spec.Selector.MatchLabels) == 0 {
+		return r.databaseSelectorLabels(database), nil
+	}
+	return statefulSet.Spec.Selector.MatchLabels, nil
+}
+
+// databaseLabels returns the labels set on every resource generated for a Database, so
+// that `kubectl get all -l app.kubernetes.io/instance=<database>` finds all of them.
+// The labels mapped from the namespace are included, those of the operator take precedence.
+func (r *DatabaseReconciler) databaseLabels(database *libsqlv1.Database) map[string]string {
+	return r.databaseLabelsWithOverrides(database, nil)
+}
+
+// databaseLabelsWithOverrides returns the databaseLabels with labels set in the Database spec,
+// which take precedence over the labels mapped from the namespace but not over those of the operator.
+func (r *DatabaseReconciler) databaseLabelsWithOverrides(database *libsqlv1.Database, overrides map[string]string) map[string]string {
+	return utils.MergeLabels(r.namespaceResourceLabels(database.Namespace), overrides, map[string]string{
+		appNameLabel:      databaseServerName,
+		appInstanceLabel:  database.Name,
+		appManagedByLabel: operatorName,
+		appPartOfLabel:    databaseAppName,
+	}, r.databaseSelectorLabels(database))
+}
+
+// namespaceResourceLabels returns the labels mapped from the namespace by NamespaceLabelMappings.
+func (r *DatabaseReconciler) namespaceResourceLabels(namespace string) map[string]string {
+	if labels, ok := r.namespaceLabels.Load(namespace); ok {
+		return labels.(map[string]string)
+	}
+	return nil
+}
+
+// setNamespaceResourceLabels records the labels mapped from the namespace, for the resources
+// generated while reconciling the Databases in it.
+func (r *DatabaseReconciler) setNamespaceResourceLabels(namespace *corev1.Namespace) {
+	if len(r.NamespaceLabelMappings) == 0 || namespace == nil {
+		return
+	}
+	r.namespaceLabels.Store(namespace.Name, utils.MapLabels(namespace.Labels, r.NamespaceLabelMappings))
+}
+
+// MapNamespaceToReconcile returns the Databases of the namespace, to apply its mapped labels.
+func (r *DatabaseReconciler) MapNamespaceToReconcile(ctx context.Context, object client.Object) []reconcile.Request {
+	databases := &libsqlv1.DatabaseList{}
+	if err := r.List(ctx, databases, client.InNamespace(object.GetName())); err != nil {
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, database := range databases.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: database.Namespace, Name: database.Name},
+		})
+	}
+	return requests
+}
+
+// isOwnedByDatabase reports whether object has an owner reference to the Database.
+func isOwnedByDatabase(object metav1.Object, database *libsqlv1.Database) bool {
+	for _, ownerReference := range object.GetOwnerReferences() {
+		if ownerReference.Kind == databaseKind && ownerReference.UID == database.UID {
+			return true
+		}
+	}
+	return false
+}
+
+// withDatabaseOwnerReference returns the owner references with the one of the Database added, or
+// updated in place when they reference an earlier Database of the same name. The owner
+// references set by others are kept.
+func withDatabaseOwnerReference(ownerReferences []metav1.OwnerReference, owner metav1.OwnerReference) []metav1.OwnerReference {
+	result := make([]metav1.OwnerReference, 0, len(ownerReferences)+1)
+	found := false
+	for _, ownerReference := range ownerReferences {
+		if ownerReference.Kind == owner.Kind && ownerReference.Name == owner.Name {
+			ownerReference, found = owner, true
+		}
+		result = append(result, ownerReference)
+	}
+	if !found {
+		result = append(result, owner)
+	}
+	return result
+}
+
+// withManagedAnnotations returns the annotations along with databaseManagedAnnotationsAnnotation
+// listing their keys, so that replaceManagedAnnotations can remove them again once they are
+// dropped from the spec. It returns nil when there are no annotations.
+func withManagedAnnotations(annotations map[string]string) map[string]string {
+	if len(annotations) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(annotations))
+	for key := range annotations {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return utils.MergeLabels(annotations, map[string]string{databaseManagedAnnotationsAnnotation: strings.Join(keys, ",")})
+}
+
+// replaceManagedAnnotations returns the existing annotations with the keys set by the previous
+// update replaced by desired, keeping the annotations set by others.
+func replaceManagedAnnotations(existing map[string]string, desired map[string]string) map[string]string {
+	managed := append(strings.Split(existing[databaseManagedAnnotationsAnnotation], ","), databaseManagedAnnotationsAnnotation)
+	return utils.ReplaceAnnotations(existing, managed, withManagedAnnotations(desired))
+}
@@ -0,0 +1,192 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// databaseMaintenanceAnnotation switches the Ingress of a Database to a maintenance page
+	databaseMaintenanceAnnotation string = "libsql.ahti.io/maintenance"
+	// DefaultMaintenanceImage serves the maintenance page, it answers every request with a 503
+	DefaultMaintenanceImage string = "hashicorp/http-echo:1.0"
+
+	maintenanceAppName string = "maintenance-page"
+	maintenancePort    int32  = 8080
+)
+
+// isDatabaseInMaintenance reports whether the maintenance annotation of the Database is set.
+func isDatabaseInMaintenance(database *libsqlv1.Database) bool {
+	maintenance, _ := strconv.ParseBool(database.Annotations[databaseMaintenanceAnnotation])
+	return maintenance
+}
+
+// ReconcileDatabaseMaintenance runs a maintenance page Deployment and Service while the
+// libsql.ahti.io/maintenance annotation of the Database is set, and removes them once it is
+// cleared. The Ingress of the Database routes to the maintenance Service in the meantime.
+func (r *DatabaseReconciler) ReconcileDatabaseMaintenance(ctx context.Context, database *libsqlv1.Database) error {
+	log := log.FromContext(ctx)
+	deployment := r.ConstructDatabaseMaintenanceDeployment(database)
+	service := r.ConstructDatabaseMaintenanceService(database)
+	if !isDatabaseInMaintenance(database) {
+		// the Service and the Deployment are removed independently, either may be left over
+		// from an interrupted removal
+		deleted := false
+		for _, obj := range []client.Object{service, deployment} {
+			if err := r.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+				if client.IgnoreNotFound(err) != nil {
+					return err
+				}
+				continue
+			}
+			if err := r.Delete(ctx, obj); err != nil {
+				if client.IgnoreNotFound(err) != nil {
+					return err
+				}
+				continue
+			}
+			deleted = true
+		}
+		if deleted {
+			r.recorder(ctx).Event(database, utils.EventNormal, "MaintenanceEnded",
+				fmt.Sprintf("Ingress of Database %s is switched back to the database", database.Name))
+		}
+		return nil
+	}
+
+	foundDeployment := &appsv1.Deployment{}
+	if err := r.Get(ctx, types.NamespacedName{Name: deployment.Name, Namespace: deployment.Namespace}, foundDeployment); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		log.Info("Creating maintenance page Deployment")
+		if err := r.Create(ctx, deployment); err != nil {
+			return err
+		}
+		r.recorder(ctx).Event(database, utils.EventNormal, "MaintenanceStarted",
+			fmt.Sprintf("Ingress of Database %s is switched to the maintenance page", database.Name))
+	} else if foundDeployment.Spec.Template.Spec.Containers[0].Image != deployment.Spec.Template.Spec.Containers[0].Image {
+		patch := client.MergeFrom(foundDeployment.DeepCopy())
+		foundDeployment.Spec.Template = deployment.Spec.Template
+		if err := r.Patch(ctx, foundDeployment, patch); err != nil {
+			return err
+		}
+	}
+
+	if err := r.Get(ctx, types.NamespacedName{Name: service.Name, Namespace: service.Namespace}, &corev1.Service{}); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		if err := r.Create(ctx, service); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// databaseMaintenanceLabels select the maintenance page pods. They must not overlap with the
+// selector labels of the database pods, so the database Services never route to them.
+func databaseMaintenanceLabels(database *libsqlv1.Database) map[string]string {
+	return map[string]string{
+		appNameLabel:      maintenanceAppName,
+		appInstanceLabel:  database.Name,
+		appManagedByLabel: operatorName,
+		appPartOfLabel:    databaseAppName,
+	}
+}
+
+func (r *DatabaseReconciler) ConstructDatabaseMaintenanceDeployment(database *libsqlv1.Database) *appsv1.Deployment {
+	image := r.MaintenanceImage
+	if image == "" {
+		image = DefaultMaintenanceImage
+	}
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      utils.GetDatabaseMaintenanceName(database),
+			Namespace: database.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: databaseAPIVersion,
+					Kind:       databaseKind,
+					Name:       database.Name,
+					UID:        database.UID,
+				},
+			},
+			Labels: utils.MergeLabels(r.namespaceResourceLabels(database.Namespace), databaseMaintenanceLabels(database)),
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.To(int32(1)),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: databaseMaintenanceLabels(database),
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: utils.MergeLabels(r.namespaceResourceLabels(database.Namespace), databaseMaintenanceLabels(database)),
+				},
+				Spec: corev1.PodSpec{
+					ImagePullSecrets: r.getDatabaseImagePullSecrets(database),
+					Containers: []corev1.Container{
+						{
+							Name:  maintenanceAppName,
+							Image: r.rewriteImage(image),
+							Args: []string{
+								fmt.Sprintf("-listen=:%d", maintenancePort),
+								"-status-code=503",
+								fmt.Sprintf("-text=Database %s is under maintenance", database.Name),
+							},
+							Ports: []corev1.ContainerPort{
+								{
+									ContainerPort: maintenancePort,
+									Protocol:      corev1.ProtocolTCP,
+									Name:          "http",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (r *DatabaseReconciler) ConstructDatabaseMaintenanceService(database *libsqlv1.Database) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      utils.GetDatabaseMaintenanceName(database),
+			Namespace: database.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: databaseAPIVersion,
+					Kind:       databaseKind,
+					Name:       database.Name,
+					UID:        database.UID,
+				},
+			},
+			Labels: utils.MergeLabels(r.namespaceResourceLabels(database.Namespace), databaseMaintenanceLabels(database)),
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{
+					Port:       maintenancePort,
+					TargetPort: intstr.FromInt32(maintenancePort),
+					Protocol:   corev1.ProtocolTCP,
+					Name:       "http",
+				},
+			},
+			Selector: databaseMaintenanceLabels(database),
+		},
+	}
+}
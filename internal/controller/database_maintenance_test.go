@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+)
+
+var _ = Describe("Database maintenance", func() {
+	It("should remove a maintenance Service left without its Deployment", func() {
+		ctx := context.Background()
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "maintenance-database", Namespace: "default", UID: "maintenance-database-uid"},
+		}
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &DatabaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: recorder}
+		service := reconciler.ConstructDatabaseMaintenanceService(database)
+		Expect(k8sClient.Create(ctx, service)).To(Succeed())
+
+		Expect(reconciler.ReconcileDatabaseMaintenance(ctx, database)).To(Succeed())
+		err := k8sClient.Get(ctx, types.NamespacedName{Name: service.Name, Namespace: service.Namespace}, &corev1.Service{})
+		Expect(apierrors.IsNotFound(err)).Should(BeTrue())
+		Expect(recorder.Events).Should(Receive(ContainSubstring("MaintenanceEnded")))
+
+		By("Reconciling again without maintenance resources, nothing is reported")
+		Expect(reconciler.ReconcileDatabaseMaintenance(ctx, database)).To(Succeed())
+		err = k8sClient.Get(ctx, types.NamespacedName{Name: service.Name, Namespace: service.Namespace}, &appsv1.Deployment{})
+		Expect(apierrors.IsNotFound(err)).Should(BeTrue())
+		Expect(recorder.Events).ShouldNot(Receive())
+	})
+})
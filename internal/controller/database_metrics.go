@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// databaseTimeToReady observes how long Databases take from their creation until they first
+// become Available, e.g. to track provisioning SLOs across clusters
+var databaseTimeToReady = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "ahti_database_time_to_ready_seconds",
+	Help:    "Time from the creation of a Database until it first became Available.",
+	Buckets: []float64{10, 30, 60, 120, 300, 600, 1200, 1800, 3600},
+}, []string{"namespace"})
+
+func init() {
+	metrics.Registry.MustRegister(databaseTimeToReady)
+}
+
+// recordDatabaseReady sets the ready time of the Database once it first becomes Available,
+// observes the time it took and emits a DatabaseReady event. A Database that was Available
+// before its ready time was recorded, e.g. by an older operator, only gets the ready time.
+func (r *DatabaseReconciler) recordDatabaseReady(ctx context.Context, database *libsqlv1.Database, becameAvailable bool) {
+	if database.Status.ReadyTime != nil {
+		return
+	}
+	condition := meta.FindStatusCondition(database.Status.Conditions, typeAvailableDatabase)
+	database.Status.ReadyTime = condition.LastTransitionTime.DeepCopy()
+	if !becameAvailable {
+		return
+	}
+	elapsed := database.Status.ReadyTime.Sub(database.CreationTimestamp.Time)
+	databaseTimeToReady.WithLabelValues(database.Namespace).Observe(elapsed.Seconds())
+	r.recorder(ctx).Event(database, utils.EventNormal, "DatabaseReady",
+		fmt.Sprintf("Database %s became available %s after its creation", database.Name, elapsed.Round(time.Second)))
+}
@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+)
+
+var _ = Describe("Database time to ready", func() {
+	It("should record the time to ready only when the Database first becomes available", func() {
+		ctx := context.Background()
+		observations := func() uint64 {
+			metric := &dto.Metric{}
+			Expect(databaseTimeToReady.WithLabelValues("time-to-ready").(prometheus.Histogram).Write(metric)).To(Succeed())
+			return metric.GetHistogram().GetSampleCount()
+		}
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "timed-database", Namespace: "time-to-ready",
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-90 * time.Second))},
+		}
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &DatabaseReconciler{Recorder: recorder}
+		statefulSet := &appsv1.StatefulSet{
+			Spec:   appsv1.StatefulSetSpec{Replicas: ptr.To(int32(1))},
+			Status: appsv1.StatefulSetStatus{ReadyReplicas: 1, UpdatedReplicas: 1},
+		}
+		Expect(reconciler.ReconcileDatabaseAvailability(ctx, database, statefulSet)).To(Succeed())
+		Expect(database.Status.ReadyTime).NotTo(BeNil())
+		Expect(recorder.Events).Should(Receive(ContainSubstring("DatabaseReady Database timed-database became available 1m30s after its creation")))
+		Expect(observations()).Should(Equal(uint64(1)))
+
+		By("Reconciling the available Database again, nothing is recorded")
+		readyTime := database.Status.ReadyTime
+		Expect(reconciler.ReconcileDatabaseAvailability(ctx, database, statefulSet)).To(Succeed())
+		Expect(database.Status.ReadyTime).Should(Equal(readyTime))
+		Expect(recorder.Events).ShouldNot(Receive())
+		Expect(observations()).Should(Equal(uint64(1)))
+	})
+})
@@ -0,0 +1,323 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var prometheusRuleGVK = schema.GroupVersionKind{
+	Group:   "monitoring.coreos.com",
+	Version: "v1",
+	Kind:    "PrometheusRule",
+}
+
+var serviceMonitorGVK = schema.GroupVersionKind{
+	Group:   "monitoring.coreos.com",
+	Version: "v1",
+	Kind:    "ServiceMonitor",
+}
+
+// Names of the default alerts, which can be listed in spec.monitoring.prometheusRule.disabledAlerts
+const (
+	alertDatabaseDown          = "LibsqlDatabaseDown"
+	alertDatabasePodRestarting = "LibsqlDatabasePodRestarting"
+)
+
+const (
+	databasePrometheusRuleGroup = "libsql-database"
+	defaultDatabaseDownFor      = 5 * time.Minute
+	defaultPodRestartThreshold  = 3
+
+	// typePrometheusRuleUnsupported is True while spec.monitoring.prometheusRule is ignored because
+	// the PrometheusRule CRDs are not installed
+	typePrometheusRuleUnsupported = "PrometheusRuleUnsupported"
+	// typeServiceMonitorUnsupported is True while the metrics exporter is not scraped because the
+	// ServiceMonitor CRDs are not installed
+	typeServiceMonitorUnsupported = "ServiceMonitorUnsupported"
+
+	databaseMetricsExporterContainerName = "metrics-exporter"
+	databaseMetricsPortName              = "metrics"
+	defaultDatabaseMetricsExporterPort   = int32(9091)
+)
+
+// ReconcileDatabasePrometheusRule keeps a PrometheusRule with the default alerts of the Database
+// when spec.monitoring.prometheusRule is set, and deletes it otherwise. The alerts are based on
+// the kube-state-metrics series of the primary StatefulSet and its pod.
+// When the prometheus-operator CRDs are not installed the rule is skipped and the
+// PrometheusRuleUnsupported condition is set.
+func (r *DatabaseReconciler) ReconcileDatabasePrometheusRule(ctx context.Context, database *libsqlv1.Database) error {
+	found := &unstructured.Unstructured{}
+	found.SetGroupVersionKind(prometheusRuleGVK)
+	err := r.Get(ctx, types.NamespacedName{
+		Name:      utils.GetDatabasePrometheusRuleName(database),
+		Namespace: database.Namespace,
+	}, found)
+	if meta.IsNoMatchError(err) {
+		if database.Spec.Monitoring != nil && database.Spec.Monitoring.PrometheusRule != nil {
+			r.setDatabaseCRDNotInstalled(ctx, database, typePrometheusRuleUnsupported,
+				"PrometheusRule CRDs are not installed in the cluster, spec.monitoring.prometheusRule is ignored")
+		} else {
+			meta.RemoveStatusCondition(&database.Status.Conditions, typePrometheusRuleUnsupported)
+		}
+		return nil
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	meta.RemoveStatusCondition(&database.Status.Conditions, typePrometheusRuleUnsupported)
+	notFound := apierrors.IsNotFound(err)
+
+	if database.Spec.Monitoring == nil || database.Spec.Monitoring.PrometheusRule == nil {
+		if notFound {
+			return nil
+		}
+		return client.IgnoreNotFound(r.Delete(ctx, found))
+	}
+
+	prometheusRule := r.ConstructDatabasePrometheusRule(database)
+	if notFound {
+		if err := r.Create(ctx, prometheusRule); err != nil {
+			return err
+		}
+		r.recorder(ctx).Event(database, utils.EventNormal, "SuccessfulCreate",
+			fmt.Sprintf("create PrometheusRule %s is being created in the Namespace %s success",
+				prometheusRule.GetName(),
+				database.Namespace))
+		return nil
+	}
+	if equality.Semantic.DeepEqual(found.Object["spec"], prometheusRule.Object["spec"]) &&
+		equality.Semantic.DeepEqual(found.GetLabels(), prometheusRule.GetLabels()) {
+		return nil
+	}
+	patch := client.MergeFrom(found.DeepCopy())
+	found.Object["spec"] = prometheusRule.Object["spec"]
+	found.SetLabels(prometheusRule.GetLabels())
+	return r.Patch(ctx, found, patch)
+}
+
+func (r *DatabaseReconciler) ConstructDatabasePrometheusRule(database *libsqlv1.Database) *unstructured.Unstructured {
+	ruleSpec := database.Spec.Monitoring.PrometheusRule
+	downFor := ruleSpec.DownFor.Duration
+	if downFor == 0 {
+		downFor = defaultDatabaseDownFor
+	}
+	restartThreshold := ruleSpec.PodRestartThreshold
+	if restartThreshold == 0 {
+		restartThreshold = defaultPodRestartThreshold
+	}
+
+	rules := []interface{}{}
+	if !slices.Contains(ruleSpec.DisabledAlerts, alertDatabaseDown) {
+		rules = append(rules, map[string]interface{}{
+			"alert": alertDatabaseDown,
+			"expr": fmt.Sprintf(`kube_statefulset_status_replicas_ready{namespace="%s",statefulset="%s"} < 1`,
+				database.Namespace, database.Name),
+			"for": downFor.String(),
+			"labels": map[string]interface{}{
+				"severity": "critical",
+				"database": database.Name,
+			},
+			"annotations": map[string]interface{}{
+				"summary": fmt.Sprintf("Database %s/%s is down", database.Namespace, database.Name),
+				"description": fmt.Sprintf("The primary of Database %s/%s has not been ready for more than %s.",
+					database.Namespace, database.Name, downFor.String()),
+			},
+		})
+	}
+	if !slices.Contains(ruleSpec.DisabledAlerts, alertDatabasePodRestarting) {
+		rules = append(rules, map[string]interface{}{
+			"alert": alertDatabasePodRestarting,
+			"expr": fmt.Sprintf(`increase(kube_pod_container_status_restarts_total{namespace="%s",pod=~"%s-[0-9]+",container="%s"}[1h]) > %d`,
+				database.Namespace, database.Name, utils.GetDatabaseContainerName(database), restartThreshold),
+			"labels": map[string]interface{}{
+				"severity": "warning",
+				"database": database.Name,
+			},
+			"annotations": map[string]interface{}{
+				"summary": fmt.Sprintf("Database %s/%s is restarting", database.Namespace, database.Name),
+				"description": fmt.Sprintf("The primary of Database %s/%s restarted more than %d times in the last hour.",
+					database.Namespace, database.Name, restartThreshold),
+			},
+		})
+	}
+
+	prometheusRule := &unstructured.Unstructured{}
+	prometheusRule.SetGroupVersionKind(prometheusRuleGVK)
+	prometheusRule.SetName(utils.GetDatabasePrometheusRuleName(database))
+	prometheusRule.SetNamespace(database.Namespace)
+	prometheusRule.SetOwnerReferences([]metav1.OwnerReference{
+		{
+			APIVersion: databaseAPIVersion,
+			Kind:       databaseKind,
+			Name:       database.Name,
+			UID:        database.UID,
+		},
+	})
+	prometheusRule.SetLabels(r.databaseLabelsWithOverrides(database, ruleSpec.Labels))
+	prometheusRule.Object["spec"] = map[string]interface{}{
+		"groups": []interface{}{
+			map[string]interface{}{
+				"name":  databasePrometheusRuleGroup,
+				"rules": rules,
+			},
+		},
+	}
+	return prometheusRule
+}
+
+// getDatabaseMetricsExporter returns spec.monitoring.metricsExporter, nil when it is not set.
+func getDatabaseMetricsExporter(database *libsqlv1.Database) *libsqlv1.DatabaseMetricsExporter {
+	if database.Spec.Monitoring == nil {
+		return nil
+	}
+	return database.Spec.Monitoring.MetricsExporter
+}
+
+// getDatabaseMetricsExporterPort returns the port the exporter serves the metrics on, 9091 by default.
+func getDatabaseMetricsExporterPort(exporter *libsqlv1.DatabaseMetricsExporter) int32 {
+	if exporter.Port == 0 {
+		return defaultDatabaseMetricsExporterPort
+	}
+	return exporter.Port
+}
+
+// constructDatabaseMetricsExporterContainer returns the exporter sidecar, which reaches
+// libsql-server over the network of the pod.
+func (r *DatabaseReconciler) constructDatabaseMetricsExporterContainer(database *libsqlv1.Database, exporter *libsqlv1.DatabaseMetricsExporter) corev1.Container {
+	port := getDatabaseMetricsExporterPort(exporter)
+	return corev1.Container{
+		Name:            databaseMetricsExporterContainerName,
+		Image:           r.rewriteImage(exporter.Image),
+		ImagePullPolicy: getDatabaseImagePullPolicy(database),
+		Resources:       exporter.Resources,
+		Ports: []corev1.ContainerPort{
+			{
+				ContainerPort: port,
+				Protocol:      corev1.ProtocolTCP,
+				Name:          databaseMetricsPortName,
+			},
+		},
+		Env: []corev1.EnvVar{
+			{
+				Name:  "SQLD_URL",
+				Value: fmt.Sprintf("http://127.0.0.1:%d", getDatabaseHTTPPort(database)),
+			},
+			{
+				Name:  "METRICS_PORT",
+				Value: strconv.Itoa(int(port)),
+			},
+		},
+	}
+}
+
+func constructDatabaseMetricsServicePort(exporter *libsqlv1.DatabaseMetricsExporter) corev1.ServicePort {
+	return corev1.ServicePort{
+		Port:       getDatabaseMetricsExporterPort(exporter),
+		TargetPort: intstr.FromString(databaseMetricsPortName),
+		Protocol:   corev1.ProtocolTCP,
+		Name:       databaseMetricsPortName,
+	}
+}
+
+// ReconcileDatabaseServiceMonitor keeps a ServiceMonitor scraping the metrics exporter of the
+// Database when spec.monitoring.metricsExporter is set, and deletes it otherwise.
+// When the prometheus-operator CRDs are not installed the monitor is skipped and the
+// ServiceMonitorUnsupported condition is set.
+func (r *DatabaseReconciler) ReconcileDatabaseServiceMonitor(ctx context.Context, database *libsqlv1.Database) error {
+	found := &unstructured.Unstructured{}
+	found.SetGroupVersionKind(serviceMonitorGVK)
+	err := r.Get(ctx, types.NamespacedName{
+		Name:      utils.GetDatabaseServiceMonitorName(database),
+		Namespace: database.Namespace,
+	}, found)
+	if meta.IsNoMatchError(err) {
+		if getDatabaseMetricsExporter(database) != nil {
+			r.setDatabaseCRDNotInstalled(ctx, database, typeServiceMonitorUnsupported,
+				"ServiceMonitor CRDs are not installed in the cluster, the metrics exporter is not scraped")
+		} else {
+			meta.RemoveStatusCondition(&database.Status.Conditions, typeServiceMonitorUnsupported)
+		}
+		return nil
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	meta.RemoveStatusCondition(&database.Status.Conditions, typeServiceMonitorUnsupported)
+	notFound := apierrors.IsNotFound(err)
+
+	if getDatabaseMetricsExporter(database) == nil {
+		if notFound {
+			return nil
+		}
+		return client.IgnoreNotFound(r.Delete(ctx, found))
+	}
+
+	serviceMonitor := r.ConstructDatabaseServiceMonitor(database)
+	if notFound {
+		if err := r.Create(ctx, serviceMonitor); err != nil {
+			return err
+		}
+		r.recorder(ctx).Event(database, utils.EventNormal, "SuccessfulCreate",
+			fmt.Sprintf("create ServiceMonitor %s is being created in the Namespace %s success",
+				serviceMonitor.GetName(),
+				database.Namespace))
+		return nil
+	}
+	if equality.Semantic.DeepEqual(found.Object["spec"], serviceMonitor.Object["spec"]) &&
+		equality.Semantic.DeepEqual(found.GetLabels(), serviceMonitor.GetLabels()) {
+		return nil
+	}
+	patch := client.MergeFrom(found.DeepCopy())
+	found.Object["spec"] = serviceMonitor.Object["spec"]
+	found.SetLabels(serviceMonitor.GetLabels())
+	return r.Patch(ctx, found, patch)
+}
+
+// ConstructDatabaseServiceMonitor selects the Services of the Database by their labels. Only the
+// client facing Service has the metrics port, so the headless Service adds no targets.
+func (r *DatabaseReconciler) ConstructDatabaseServiceMonitor(database *libsqlv1.Database) *unstructured.Unstructured {
+	matchLabels := map[string]interface{}{}
+	for key, value := range r.databaseLabels(database) {
+		matchLabels[key] = value
+	}
+	serviceMonitor := &unstructured.Unstructured{}
+	serviceMonitor.SetGroupVersionKind(serviceMonitorGVK)
+	serviceMonitor.SetName(utils.GetDatabaseServiceMonitorName(database))
+	serviceMonitor.SetNamespace(database.Namespace)
+	serviceMonitor.SetOwnerReferences([]metav1.OwnerReference{
+		{
+			APIVersion: databaseAPIVersion,
+			Kind:       databaseKind,
+			Name:       database.Name,
+			UID:        database.UID,
+		},
+	})
+	serviceMonitor.SetLabels(r.databaseLabelsWithOverrides(database, database.Spec.Monitoring.MetricsExporter.Labels))
+	serviceMonitor.Object["spec"] = map[string]interface{}{
+		"selector": map[string]interface{}{
+			"matchLabels": matchLabels,
+		},
+		"endpoints": []interface{}{
+			map[string]interface{}{
+				"port": databaseMetricsPortName,
+			},
+		},
+	}
+	return serviceMonitor
+}
@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type orphanCandidates struct {
+	list    client.ObjectList
+	kind    string
+	desired []string
+}
+
+// DeleteOrphanedDatabaseResources deletes the resources labeled and owned by the Database that
+// its spec no longer asks for, e.g. the gRPC Service once spec.service.grpc is removed or the
+// resources of a naming scheme that changed. Owner references only clean them up along with
+// the Database. Secrets are left out, they hold the auth keys.
+func (r *DatabaseReconciler) DeleteOrphanedDatabaseResources(ctx context.Context, database *libsqlv1.Database) error {
+	services := []string{utils.GetDatabaseServiceName(database, true), utils.GetDatabaseServiceName(database, false)}
+	if isDatabaseGRPCServiceSeparate(database) {
+		services = append(services, utils.GetDatabaseGRPCServiceName(database))
+	}
+	ingresses := []string{}
+	if database.Spec.Ingress != nil {
+		ingresses = append(ingresses, utils.GetDatabaseIngressName(database))
+	}
+	for _, additional := range database.Spec.AdditionalIngresses {
+		ingresses = append(ingresses, utils.GetDatabaseAdditionalIngressName(database, additional.Name))
+	}
+	configMaps := []string{}
+	if database.Spec.Monitoring != nil && database.Spec.Monitoring.GrafanaDashboard != nil {
+		configMaps = append(configMaps, utils.GetDatabaseGrafanaDashboardName(database))
+	}
+	if database.Spec.ConnectionConfigMap != nil {
+		configMaps = append(configMaps, utils.GetDatabaseConnectionConfigMapName(database))
+	}
+	if database.Spec.PublishJWKS {
+		configMaps = append(configMaps, utils.GetDatabaseJWKSConfigMapName(database))
+	}
+
+	for _, candidates := range []orphanCandidates{
+		{&corev1.ServiceList{}, "Service", services},
+		{&networkingv1.IngressList{}, "Ingress", ingresses},
+		{&corev1.ConfigMapList{}, "ConfigMap", configMaps},
+	} {
+		if err := r.List(ctx, candidates.list, client.InNamespace(database.Namespace),
+			client.MatchingLabels{r.GetManagedByLabel(): database.Name}); err != nil {
+			return err
+		}
+		if err := meta.EachListItem(candidates.list, func(item runtime.Object) error {
+			object := item.(client.Object)
+			if slices.Contains(candidates.desired, object.GetName()) || !isOwnedByDatabase(object, database) {
+				return nil
+			}
+			if err := r.Delete(ctx, object); client.IgnoreNotFound(err) != nil {
+				return err
+			}
+			r.recorder(ctx).Event(database, utils.EventNormal, "SuccessfulDelete",
+				fmt.Sprintf("delete %s %s is being deleted from the Namespace %s success",
+					candidates.kind,
+					object.GetName(),
+					database.Namespace))
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
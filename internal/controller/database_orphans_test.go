@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+)
+
+var _ = Describe("Database orphaned resources", func() {
+	ctx := context.Background()
+
+	It("should delete the labeled resources the spec no longer asks for", func() {
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orphans-database", Namespace: "default"},
+			Spec: libsqlv1.DatabaseSpec{
+				Image:   "ghcr.io/tursodatabase/libsql-server:v0.24.21",
+				Storage: libsqlv1.DatabaseStorage{Size: resource.MustParse("1Gi")},
+				Ingress: &libsqlv1.AhtiDatabaseIngressSpec{Host: "orphans.ahti.io"},
+			},
+		}
+		Expect(k8sClient.Create(ctx, database)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, database)
+		reconciler := &DatabaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: MockEventRecorder{}}
+
+		objectMeta := func(name string, owned bool) metav1.ObjectMeta {
+			objectMeta := metav1.ObjectMeta{Name: name, Namespace: database.Namespace, Labels: reconciler.databaseLabels(database)}
+			if owned {
+				objectMeta.OwnerReferences = []metav1.OwnerReference{
+					{APIVersion: databaseAPIVersion, Kind: databaseKind, Name: database.Name, UID: database.UID},
+				}
+			}
+			return objectMeta
+		}
+		ingressSpec := networkingv1.IngressSpec{
+			DefaultBackend: &networkingv1.IngressBackend{
+				Service: &networkingv1.IngressServiceBackend{Name: database.Name, Port: networkingv1.ServiceBackendPort{Number: 8080}},
+			},
+		}
+		desiredIngress := &networkingv1.Ingress{ObjectMeta: objectMeta(utils.GetDatabaseIngressName(database), true), Spec: ingressSpec}
+		staleIngress := &networkingv1.Ingress{ObjectMeta: objectMeta("orphans-database-legacy-ingress", true), Spec: ingressSpec}
+		staleConfigMap := &corev1.ConfigMap{ObjectMeta: objectMeta("orphans-database-legacy-dashboard", true)}
+		foreignConfigMap := &corev1.ConfigMap{ObjectMeta: objectMeta("orphans-database-user-config", false)}
+		for _, object := range []client.Object{desiredIngress, staleIngress, staleConfigMap, foreignConfigMap} {
+			Expect(k8sClient.Create(ctx, object)).To(Succeed())
+		}
+
+		Expect(reconciler.DeleteOrphanedDatabaseResources(ctx, database)).To(Succeed())
+
+		exists := func(object client.Object) bool {
+			return k8sClient.Get(ctx, types.NamespacedName{Name: object.GetName(), Namespace: object.GetNamespace()}, object) == nil
+		}
+		Expect(exists(desiredIngress)).Should(BeTrue())
+		Expect(exists(staleIngress)).Should(BeFalse())
+		Expect(exists(staleConfigMap)).Should(BeFalse())
+		By("Keeping the resources that are not owned by the Database, even when labeled")
+		Expect(exists(foreignConfigMap)).Should(BeTrue())
+		Expect(k8sClient.Delete(ctx, desiredIngress)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, foreignConfigMap)).To(Succeed())
+	})
+})
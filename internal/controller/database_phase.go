@@ -0,0 +1,37 @@
+package controller
+
+import (
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetDatabasePhase derives the phase of the Database from its conditions:
+//   - Terminating once the Database is marked to be deleted
+//   - Degraded when the Degraded condition is True, or the Database is unavailable for
+//     any other reason than its pods rolling out
+//   - Running when the Available condition is True
+//   - Provisioning while the pods are rolling out or their volume is pending
+//   - Pending before the Available condition is known
+func GetDatabasePhase(database *libsqlv1.Database) libsqlv1.DatabasePhase {
+	if !database.GetDeletionTimestamp().IsZero() {
+		return libsqlv1.DatabasePhaseTerminating
+	}
+	if meta.IsStatusConditionTrue(database.Status.Conditions, typeDegradedDatabase) {
+		return libsqlv1.DatabasePhaseDegraded
+	}
+	available := meta.FindStatusCondition(database.Status.Conditions, typeAvailableDatabase)
+	if available == nil {
+		return libsqlv1.DatabasePhasePending
+	}
+	switch available.Status {
+	case metav1.ConditionTrue:
+		return libsqlv1.DatabasePhaseRunning
+	case metav1.ConditionFalse:
+		if available.Reason == reasonRolloutInProgress || available.Reason == reasonPVCPending {
+			return libsqlv1.DatabasePhaseProvisioning
+		}
+		return libsqlv1.DatabasePhaseDegraded
+	}
+	return libsqlv1.DatabasePhasePending
+}
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+)
+
+var _ = Describe("Database phase", func() {
+	now := metav1.Now()
+
+	DescribeTable("deriving the phase from the conditions",
+		func(deletionTimestamp *metav1.Time, conditions []metav1.Condition, phase libsqlv1.DatabasePhase) {
+			database := &libsqlv1.Database{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: deletionTimestamp},
+				Status:     libsqlv1.DatabaseStatus{Conditions: conditions},
+			}
+			Expect(GetDatabasePhase(database)).Should(Equal(phase))
+		},
+		Entry("without conditions", nil, nil, libsqlv1.DatabasePhasePending),
+		Entry("while starting reconciliation", nil, []metav1.Condition{
+			{Type: typeAvailableDatabase, Status: metav1.ConditionUnknown, Reason: reasonReconciling},
+		}, libsqlv1.DatabasePhasePending),
+		Entry("while rolling out", nil, []metav1.Condition{
+			{Type: typeAvailableDatabase, Status: metav1.ConditionFalse, Reason: reasonRolloutInProgress},
+		}, libsqlv1.DatabasePhaseProvisioning),
+		Entry("while the volume is pending", nil, []metav1.Condition{
+			{Type: typeAvailableDatabase, Status: metav1.ConditionFalse, Reason: reasonPVCPending},
+		}, libsqlv1.DatabasePhaseProvisioning),
+		Entry("once available", nil, []metav1.Condition{
+			{Type: typeAvailableDatabase, Status: metav1.ConditionTrue, Reason: reasonReady},
+			{Type: typeDegradedDatabase, Status: metav1.ConditionFalse, Reason: reasonContainersRunning},
+		}, libsqlv1.DatabasePhaseRunning),
+		Entry("with an invalid spec", nil, []metav1.Condition{
+			{Type: typeAvailableDatabase, Status: metav1.ConditionFalse, Reason: reasonInvalidSpec},
+		}, libsqlv1.DatabasePhaseDegraded),
+		Entry("when a container is crash looping", nil, []metav1.Condition{
+			{Type: typeAvailableDatabase, Status: metav1.ConditionFalse, Reason: "CrashLoopBackOff"},
+			{Type: typeDegradedDatabase, Status: metav1.ConditionTrue, Reason: "CrashLoopBackOff"},
+		}, libsqlv1.DatabasePhaseDegraded),
+		Entry("when available but degraded", nil, []metav1.Condition{
+			{Type: typeAvailableDatabase, Status: metav1.ConditionTrue, Reason: reasonReady},
+			{Type: typeDegradedDatabase, Status: metav1.ConditionTrue, Reason: reasonResourceQuotaExceeded},
+		}, libsqlv1.DatabasePhaseDegraded),
+		Entry("once marked to be deleted", &now, []metav1.Condition{
+			{Type: typeAvailableDatabase, Status: metav1.ConditionTrue, Reason: reasonReady},
+		}, libsqlv1.DatabasePhaseTerminating),
+	)
+})
@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// waiting reasons of a container that will not recover without user intervention
+var failingContainerReasons = map[string]bool{
+	"CrashLoopBackOff": true,
+	"ImagePullBackOff": true,
+	"ErrImagePull":     true,
+}
+
+// ReconcileDatabasePods inspects the containers of the database pods and marks the Database
+// Degraded when one of them is crash looping or cannot pull its image.
+// It returns whether a failing container was found.
+func (r *DatabaseReconciler) ReconcileDatabasePods(ctx context.Context, database *libsqlv1.Database) (failing bool, err error) {
+	selectorLabels, err := r.getDatabasePodSelectorLabels(ctx, database)
+	if err != nil {
+		return false, err
+	}
+	podList := &corev1.PodList{}
+	if err := r.List(ctx, podList,
+		client.InNamespace(database.Namespace),
+		client.MatchingLabels(selectorLabels),
+	); err != nil {
+		return false, err
+	}
+	for _, pod := range podList.Items {
+		containerStatuses := append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+		containerStatuses = append(containerStatuses, pod.Status.ContainerStatuses...)
+		for _, containerStatus := range containerStatuses {
+			waiting := containerStatus.State.Waiting
+			if waiting == nil || !failingContainerReasons[waiting.Reason] {
+				continue
+			}
+			message := fmt.Sprintf("Container %s of pod %s is in %s", containerStatus.Name, pod.Name, waiting.Reason)
+			if terminated := containerStatus.LastTerminationState.Terminated; terminated != nil {
+				message = fmt.Sprintf("%s, last terminated with reason %s and exit code %d", message, terminated.Reason, terminated.ExitCode)
+			} else if waiting.Message != "" {
+				message = fmt.Sprintf("%s: %s", message, waiting.Message)
+			}
+			changed := meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{Type: typeDegradedDatabase,
+				Status: metav1.ConditionTrue, Reason: waiting.Reason, Message: message})
+			changed = meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{Type: typeAvailableDatabase,
+				Status: metav1.ConditionFalse, Reason: waiting.Reason, Message: message}) || changed
+			if changed {
+				r.recorder(ctx).Event(database, utils.EventWarning, waiting.Reason, message)
+			}
+			return true, nil
+		}
+	}
+	condition := meta.FindStatusCondition(database.Status.Conditions, typeDegradedDatabase)
+	if condition != nil && condition.Status == metav1.ConditionTrue && failingContainerReasons[condition.Reason] {
+		meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{Type: typeDegradedDatabase,
+			Status: metav1.ConditionFalse, Reason: reasonContainersRunning, Message: "Database containers are running"})
+	}
+	return false, nil
+}
+
+func (r *DatabaseReconciler) MapDatabasePodsToReconcile(ctx context.Context, object client.Object) []reconcile.Request {
+	pod := object.(*corev1.Pod)
+	databaseName, ok := pod.Labels[r.GetManagedByLabel()]
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{
+		{
+			NamespacedName: types.NamespacedName{Namespace: pod.Namespace, Name: databaseName},
+		},
+	}
+}
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+// applyDatabasePodTemplateOverrides patches the generated pod template with
+// spec.podTemplateOverrides, a strategic merge patch or a list of JSON patch operations.
+func applyDatabasePodTemplateOverrides(database *libsqlv1.Database, template *corev1.PodTemplateSpec) error {
+	overrides := database.Spec.PodTemplateOverrides
+	if overrides == nil || len(overrides.Raw) == 0 {
+		return nil
+	}
+	original, err := json.Marshal(template)
+	if err != nil {
+		return err
+	}
+	var patched []byte
+	if bytes.HasPrefix(bytes.TrimSpace(overrides.Raw), []byte("[")) {
+		patch, err := jsonpatch.DecodePatch(overrides.Raw)
+		if err != nil {
+			return fmt.Errorf("spec.podTemplateOverrides is not a valid JSON patch: %w", err)
+		}
+		if patched, err = patch.Apply(original); err != nil {
+			return fmt.Errorf("spec.podTemplateOverrides cannot be applied: %w", err)
+		}
+	} else {
+		if patched, err = strategicpatch.StrategicMergePatch(original, overrides.Raw, corev1.PodTemplateSpec{}); err != nil {
+			return fmt.Errorf("spec.podTemplateOverrides cannot be applied: %w", err)
+		}
+	}
+	result := corev1.PodTemplateSpec{}
+	if err := json.Unmarshal(patched, &result); err != nil {
+		return fmt.Errorf("spec.podTemplateOverrides does not result in a valid pod template: %w", err)
+	}
+
+	container := utils.GetContainer(&result.Spec, utils.GetDatabaseContainerName(database))
+	if container == nil {
+		return fmt.Errorf("spec.podTemplateOverrides removes the %s container", utils.GetDatabaseContainerName(database))
+	}
+	dataVolumeMounted := false
+	for _, volumeMount := range container.VolumeMounts {
+		if volumeMount.Name == utils.GetDatabasePVCName(database) {
+			dataVolumeMounted = true
+		}
+	}
+	if !dataVolumeMounted {
+		return fmt.Errorf("spec.podTemplateOverrides removes the %s volume mount of the %s container",
+			utils.GetDatabasePVCName(database), utils.GetDatabaseContainerName(database))
+	}
+	*template = result
+	return nil
+}
@@ -5,33 +5,30 @@ import (
 
 	libsqlv1 "github.com/ahti-database/operator/api/v1"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/apimachinery/pkg/selection"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
+// DeleteDatabasePVC deletes the data volumes of the Database. The StatefulSet names them after
+// its volume claim template, the StatefulSet and the ordinal of the pod, so they are found by
+// the selector labels of the StatefulSet rather than by name, whatever the number of replicas.
 func (r *DatabaseReconciler) DeleteDatabasePVC(ctx context.Context, database *libsqlv1.Database) error {
 	log := log.FromContext(ctx)
-	databasePVCList := &corev1.PersistentVolumeClaimList{}
-	pvcLabels := labels.NewSelector()
-	controlledByRequirement, err := labels.NewRequirement(databaseLabel, selection.Equals, []string{database.Name})
+	selectorLabels, err := r.getDatabasePodSelectorLabels(ctx, database)
 	if err != nil {
-		log.Error(err, "error trying to select app labels")
 		return err
 	}
-	pvcLabels.Add(
-		*controlledByRequirement,
-	)
-	if err := r.List(ctx, databasePVCList, &client.ListOptions{
-		LabelSelector: pvcLabels,
-	}); err != nil {
-		log.Error(err, "pvc resources not found. Ignoring since object must be deleted")
+	databasePVCList := &corev1.PersistentVolumeClaimList{}
+	if err := r.List(ctx, databasePVCList,
+		client.InNamespace(database.Namespace),
+		client.MatchingLabels(selectorLabels),
+	); err != nil {
+		log.Error(err, "Failed to list the PVCs of the Database")
 		return err
 	}
-	for _, databasePVC := range databasePVCList.Items {
-		if err := r.Delete(ctx, &databasePVC); err != nil {
-			log.Error(err, "pvc resources not found. Ignoring since object must be deleted")
+	for i := range databasePVCList.Items {
+		if err := r.Delete(ctx, &databasePVCList.Items[i]); client.IgnoreNotFound(err) != nil {
+			log.Error(err, "Failed to delete PVC", "PVC.Name", databasePVCList.Items[i].Name)
 		}
 	}
 
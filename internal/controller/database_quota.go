@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ReconcileDatabaseResourceQuota checks whether the database pod fits in the namespace
+// ResourceQuotas while the StatefulSet has not created any pod yet. If it clearly does not,
+// the Database is marked Degraded so users do not have to dig through FailedCreate events.
+func (r *DatabaseReconciler) ReconcileDatabaseResourceQuota(ctx context.Context, database *libsqlv1.Database, statefulSet *appsv1.StatefulSet) error {
+	if statefulSet.Status.Replicas > 0 {
+		r.clearDatabaseResourceQuotaCondition(database)
+		return nil
+	}
+	resourceQuotaList := &corev1.ResourceQuotaList{}
+	if err := r.List(ctx, resourceQuotaList, client.InNamespace(database.Namespace)); err != nil {
+		return err
+	}
+	exceeded := []string{}
+	requested := databasePodQuotaUsage(database, &statefulSet.Spec.Template.Spec)
+	for _, resourceQuota := range resourceQuotaList.Items {
+		// scoped quotas only count the pods they match, e.g. by priority class, which is not evaluated
+		if len(resourceQuota.Spec.Scopes) > 0 || resourceQuota.Spec.ScopeSelector != nil {
+			continue
+		}
+		for name, hard := range resourceQuota.Status.Hard {
+			request, ok := requested[name]
+			if !ok {
+				continue
+			}
+			total := resourceQuota.Status.Used[name].DeepCopy()
+			total.Add(request)
+			if total.Cmp(hard) > 0 {
+				used := resourceQuota.Status.Used[name]
+				exceeded = append(exceeded, fmt.Sprintf("%s (requested %s, used %s, hard %s in ResourceQuota %s)",
+					name, request.String(), used.String(), hard.String(), resourceQuota.Name))
+			}
+		}
+	}
+	if len(exceeded) == 0 {
+		r.clearDatabaseResourceQuotaCondition(database)
+		return nil
+	}
+	message := fmt.Sprintf("Database pod does not fit in the namespace quota: %s", strings.Join(exceeded, ", "))
+	changed := meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{Type: typeDegradedDatabase,
+		Status: metav1.ConditionTrue, Reason: reasonResourceQuotaExceeded, Message: message})
+	if changed {
+		r.recorder(ctx).Event(database, utils.EventWarning, reasonResourceQuotaExceeded, message)
+	}
+	return nil
+}
+
+func (r *DatabaseReconciler) clearDatabaseResourceQuotaCondition(database *libsqlv1.Database) {
+	condition := meta.FindStatusCondition(database.Status.Conditions, typeDegradedDatabase)
+	if condition == nil || condition.Reason != reasonResourceQuotaExceeded {
+		return
+	}
+	meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{Type: typeDegradedDatabase,
+		Status: metav1.ConditionFalse, Reason: reasonResourceQuotaSatisfied, Message: "Database pod fits in the namespace quota"})
+}
+
+// databasePodQuotaUsage returns how much of each quota resource a single database pod and its PVC
+// consume. The requests and limits of the pod are those of all its containers, the sidecars
+// included, or of its largest init container when that is more.
+func databasePodQuotaUsage(database *libsqlv1.Database, podSpec *corev1.PodSpec) corev1.ResourceList {
+	usage := corev1.ResourceList{
+		corev1.ResourcePods:                   resource.MustParse("1"),
+		corev1.ResourcePersistentVolumeClaims: resource.MustParse("1"),
+		corev1.ResourceRequestsStorage:        database.Spec.Storage.Size,
+	}
+	requests, limits := corev1.ResourceList{}, corev1.ResourceList{}
+	for _, container := range podSpec.Containers {
+		addContainerQuotaUsage(requests, limits, container.Resources)
+	}
+	for _, container := range podSpec.InitContainers {
+		initRequests, initLimits := corev1.ResourceList{}, corev1.ResourceList{}
+		addContainerQuotaUsage(initRequests, initLimits, container.Resources)
+		maxQuotaUsage(requests, initRequests)
+		maxQuotaUsage(limits, initLimits)
+	}
+	for name, request := range requests {
+		usage[name] = request
+		usage[corev1.ResourceName("requests."+string(name))] = request
+	}
+	for name, limit := range limits {
+		usage[corev1.ResourceName("limits."+string(name))] = limit
+	}
+	return usage
+}
+
+// addContainerQuotaUsage adds the cpu and memory requests and limits of a container.
+func addContainerQuotaUsage(requests, limits corev1.ResourceList, resources corev1.ResourceRequirements) {
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		// requests default to the limits when only limits are set
+		request, ok := resources.Requests[name]
+		if !ok {
+			request, ok = resources.Limits[name]
+		}
+		if ok {
+			total := requests[name]
+			total.Add(request)
+			requests[name] = total
+		}
+		if limit, ok := resources.Limits[name]; ok {
+			total := limits[name]
+			total.Add(limit)
+			limits[name] = total
+		}
+	}
+}
+
+// maxQuotaUsage raises the quantities of usage to those of other that are larger.
+func maxQuotaUsage(usage, other corev1.ResourceList) {
+	for name, quantity := range other {
+		if current, ok := usage[name]; !ok || quantity.Cmp(current) > 0 {
+			usage[name] = quantity
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+)
+
+var _ = Describe("Database resource quota", func() {
+	ctx := context.Background()
+
+	It("should count every container of the pod against the unscoped quotas", func() {
+		namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "quota-namespace"}}
+		Expect(k8sClient.Create(ctx, namespace)).To(Succeed())
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "quota-database", Namespace: namespace.Name},
+			Spec:       libsqlv1.DatabaseSpec{Storage: libsqlv1.DatabaseStorage{Size: resource.MustParse("1Gi")}},
+		}
+		statefulSet := &appsv1.StatefulSet{Spec: appsv1.StatefulSetSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "libsql-server", Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}}},
+				{Name: databaseMetricsExporterContainerName, Resources: corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("200m")}}},
+			},
+		}}}}
+		usage := databasePodQuotaUsage(database, &statefulSet.Spec.Template.Spec)
+		Expect(usage.Name(corev1.ResourceRequestsCPU, resource.DecimalSI).String()).Should(Equal("700m"))
+		Expect(usage.Name(corev1.ResourceLimitsCPU, resource.DecimalSI).String()).Should(Equal("200m"))
+
+		newResourceQuota := func(name string, scopes ...corev1.ResourceQuotaScope) {
+			resourceQuota := &corev1.ResourceQuota{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace.Name},
+				Spec: corev1.ResourceQuotaSpec{
+					Hard:   corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("600m")},
+					Scopes: scopes,
+				},
+			}
+			Expect(k8sClient.Create(ctx, resourceQuota)).To(Succeed())
+			resourceQuota.Status = corev1.ResourceQuotaStatus{
+				Hard: resourceQuota.Spec.Hard,
+				Used: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("0")},
+			}
+			Expect(k8sClient.Status().Update(ctx, resourceQuota)).To(Succeed())
+		}
+		reconciler := &DatabaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: MockEventRecorder{}}
+
+		By("Skipping a quota scoped to other pods")
+		newResourceQuota("not-best-effort", corev1.ResourceQuotaScopeNotBestEffort)
+		Expect(reconciler.ReconcileDatabaseResourceQuota(ctx, database, statefulSet)).To(Succeed())
+		Expect(meta.FindStatusCondition(database.Status.Conditions, typeDegradedDatabase)).Should(BeNil())
+
+		By("Marking the Database Degraded when the sidecar exceeds an unscoped quota")
+		newResourceQuota("compute")
+		Expect(reconciler.ReconcileDatabaseResourceQuota(ctx, database, statefulSet)).To(Succeed())
+		condition := meta.FindStatusCondition(database.Status.Conditions, typeDegradedDatabase)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Reason).Should(Equal(reasonResourceQuotaExceeded))
+		Expect(condition.Message).Should(ContainSubstring("ResourceQuota compute"))
+		Expect(condition.Message).ShouldNot(ContainSubstring("not-best-effort"))
+	})
+})
@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// databaseReadinessPathPrefix is followed by <namespace>/<name> of the Database
+const databaseReadinessPathPrefix = "/ready/"
+
+// DatabaseReadinessServer serves the readiness of each Database for external health checks,
+// e.g. of a global load balancer, on GET /ready/<namespace>/<name>. It answers 200 while the
+// Available condition of the Database is True, 503 while it is not and 404 for an unknown
+// Database. Every replica of the operator serves it, not only the leader.
+type DatabaseReadinessServer struct {
+	Addr   string
+	Client client.Reader
+}
+
+// Start implements manager.Runnable.
+func (s *DatabaseReadinessServer) Start(ctx context.Context) error {
+	server := &http.Server{
+		Addr:              s.Addr,
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		if err := server.Shutdown(context.Background()); err != nil {
+			log.FromContext(ctx).Error(err, "Failed to shut down the database readiness server")
+		}
+	}()
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+func (s *DatabaseReadinessServer) NeedLeaderElection() bool {
+	return false
+}
+
+func (s *DatabaseReadinessServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	path, hasPrefix := strings.CutPrefix(req.URL.Path, databaseReadinessPathPrefix)
+	namespace, name, ok := strings.Cut(path, "/")
+	if !hasPrefix || !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+		http.Error(w, "expected /ready/<namespace>/<name>", http.StatusNotFound)
+		return
+	}
+	database := &libsqlv1.Database{}
+	if err := s.Client.Get(req.Context(), types.NamespacedName{Namespace: namespace, Name: name}, database); err != nil {
+		if apierrors.IsNotFound(err) {
+			http.Error(w, fmt.Sprintf("Database %s/%s not found", namespace, name), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !meta.IsStatusConditionTrue(database.Status.Conditions, typeAvailableDatabase) {
+		http.Error(w, fmt.Sprintf("Database %s/%s is not available", namespace, name), http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintf(w, "Database %s/%s is available\n", namespace, name)
+}
@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+)
+
+var _ = Describe("Database readiness server", func() {
+	ctx := context.Background()
+
+	It("should answer 200 only while the Database is available", func() {
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "ready-database", Namespace: "default"},
+			Spec: libsqlv1.DatabaseSpec{
+				Image:   "ghcr.io/tursodatabase/libsql-server:v0.24.21",
+				Storage: libsqlv1.DatabaseStorage{Size: resource.MustParse("1Gi")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, database)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, database)
+		server := &DatabaseReadinessServer{Client: k8sClient}
+		get := func(path string) int {
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+			return recorder.Code
+		}
+
+		Expect(get("/ready/default/ready-database")).Should(Equal(http.StatusServiceUnavailable))
+		Expect(get("/ready/default/missing-database")).Should(Equal(http.StatusNotFound))
+		Expect(get("/ready/default")).Should(Equal(http.StatusNotFound))
+
+		By("Marking the Database available")
+		meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{Type: typeAvailableDatabase,
+			Status: metav1.ConditionTrue, Reason: "Reconciling", Message: "available"})
+		Expect(k8sClient.Status().Update(ctx, database)).To(Succeed())
+		Expect(get("/ready/default/ready-database")).Should(Equal(http.StatusOK))
+	})
+})
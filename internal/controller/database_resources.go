@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// maxManagedResources bounds the resources listed in the status of a Database
+const maxManagedResources = 32
+
+type managedResourceCandidate struct {
+	object client.Object
+	name   string
+}
+
+// ReconcileDatabaseManagedResources lists the resources that exist for the Database in its
+// status. Resources of CRDs that are not installed are left out. The built-in kinds are read from
+// the cache, the resources of CRDs are read from the API server, so they are only looked up when
+// the spec enables them.
+func (r *DatabaseReconciler) ReconcileDatabaseManagedResources(ctx context.Context, database *libsqlv1.Database) error {
+	candidates := []managedResourceCandidate{
+		{&appsv1.StatefulSet{}, database.Name},
+		{&corev1.PersistentVolumeClaim{}, utils.GetDatabaseStatefulSetPVCName(database, 0)},
+		{&corev1.Service{}, utils.GetDatabaseServiceName(database, true)},
+		{&corev1.Service{}, utils.GetDatabaseServiceName(database, false)},
+		{&corev1.Service{}, utils.GetDatabaseGRPCServiceName(database)},
+		{&corev1.Service{}, utils.GetDatabaseMaintenanceName(database)},
+		{&appsv1.Deployment{}, utils.GetDatabaseMaintenanceName(database)},
+		{&networkingv1.Ingress{}, utils.GetDatabaseIngressName(database)},
+		{&corev1.Secret{}, utils.GetAuthSecretName(database)},
+		{&corev1.Secret{}, utils.GetAuthPublicKeySecretName(database)},
+		{&corev1.Secret{}, utils.GetDatabaseInitSQLName(database)},
+		{&batchv1.Job{}, utils.GetDatabaseInitSQLName(database)},
+		{&corev1.ConfigMap{}, utils.GetDatabaseGrafanaDashboardName(database)},
+		{&corev1.ConfigMap{}, utils.GetDatabaseConnectionConfigMapName(database)},
+		{&corev1.ConfigMap{}, utils.GetDatabaseJWKSConfigMapName(database)},
+	}
+	if database.Spec.ExternalAuthSecret != nil {
+		candidates = append(candidates, managedResourceCandidate{newUnstructured(externalSecretGVK), utils.GetAuthSecretName(database)})
+	}
+	if database.Spec.Monitoring != nil && database.Spec.Monitoring.PrometheusRule != nil {
+		candidates = append(candidates, managedResourceCandidate{newUnstructured(prometheusRuleGVK), utils.GetDatabasePrometheusRuleName(database)})
+	}
+	if getDatabaseMetricsExporter(database) != nil {
+		candidates = append(candidates, managedResourceCandidate{newUnstructured(serviceMonitorGVK), utils.GetDatabaseServiceMonitorName(database)})
+	}
+	if database.Spec.Istio != nil && database.Spec.Istio.PeerAuthentication != nil {
+		candidates = append(candidates, managedResourceCandidate{newUnstructured(peerAuthenticationGVK), database.Name})
+	}
+	if database.Spec.Storage.CloneFrom != "" {
+		candidates = append(candidates, managedResourceCandidate{newUnstructured(volumeSnapshotGVK), utils.GetDatabaseCloneSnapshotName(database)})
+	}
+	for _, additional := range database.Spec.AdditionalIngresses {
+		candidates = append(candidates, managedResourceCandidate{&networkingv1.Ingress{},
+			utils.GetDatabaseAdditionalIngressName(database, additional.Name)})
+	}
+	if database.Status.LastSnapshot != nil {
+		candidates = append(candidates, managedResourceCandidate{newUnstructured(volumeSnapshotGVK), database.Status.LastSnapshot.Name})
+	}
+
+	managedResources := []libsqlv1.DatabaseManagedResource{}
+	for _, candidate := range candidates {
+		if err := r.Get(ctx, types.NamespacedName{Name: candidate.name, Namespace: database.Namespace}, candidate.object); err != nil {
+			if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+				continue
+			}
+			return err
+		}
+		// the data volume is owned by the StatefulSet rather than the Database
+		if _, isPVC := candidate.object.(*corev1.PersistentVolumeClaim); !isPVC && !isOwnedByDatabase(candidate.object, database) {
+			continue
+		}
+		gvk, err := r.GroupVersionKindFor(candidate.object)
+		if err != nil {
+			return err
+		}
+		managedResource := libsqlv1.DatabaseManagedResource{Kind: gvk.Kind, Name: candidate.name}
+		if !slices.Contains(managedResources, managedResource) {
+			managedResources = append(managedResources, managedResource)
+		}
+	}
+	slices.SortFunc(managedResources, func(a, b libsqlv1.DatabaseManagedResource) int {
+		if a.Kind != b.Kind {
+			return strings.Compare(a.Kind, b.Kind)
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	if len(managedResources) > maxManagedResources {
+		managedResources = managedResources[:maxManagedResources]
+	}
+	database.Status.ManagedResources = managedResources
+	return nil
+}
+
+func newUnstructured(gvk schema.GroupVersionKind) *unstructured.Unstructured {
+	object := &unstructured.Unstructured{}
+	object.SetGroupVersionKind(gvk)
+	return object
+}
@@ -2,11 +2,14 @@ package controller
 
 import (
 	"context"
+	"crypto/ed25519"
 	"encoding/base64"
+	"fmt"
 
 	libsqlv1 "github.com/ahti-database/operator/api/v1"
 	"github.com/ahti-database/operator/internal/utils"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -18,12 +21,18 @@ import (
 
 func (r *DatabaseReconciler) ReconcileDatabaseSecrets(ctx context.Context, database *libsqlv1.Database) (*corev1.Secret, error) {
 	log := log.FromContext(ctx)
+	if database.Spec.SharedAuthSecretRef != nil {
+		return r.reconcileDatabaseSharedAuthSecret(ctx, database)
+	}
 	authSecret := &corev1.Secret{}
 	if err := r.Get(ctx, types.NamespacedName{
 		Name:      utils.GetAuthSecretName(database),
 		Namespace: database.Namespace,
 	}, authSecret); err != nil {
-		if database.Spec.Auth && apierrors.IsNotFound(err) {
+		if database.Spec.Auth && apierrors.IsNotFound(err) && database.Spec.ExternalAuthSecret != nil {
+			// the keys are synced by the External Secrets Operator, there is no secret until they are
+			return nil, r.ReconcileDatabaseExternalSecret(ctx, database)
+		} else if database.Spec.Auth && apierrors.IsNotFound(err) {
 			log.Info("Creating Auth Secret")
 			publicKey, privateKey, err := utils.GenerateAsymmetricKeys()
 			if err != nil {
@@ -41,6 +50,8 @@ func (r *DatabaseReconciler) ReconcileDatabaseSecrets(ctx context.Context, datab
 							UID:        database.UID,
 						},
 					},
+					Labels:      r.databaseLabels(database),
+					Annotations: withManagedAnnotations(database.Spec.SecretAnnotations),
 				},
 				StringData: map[string]string{
 					"PUBLIC_KEY":  base64.URLEncoding.WithPadding(base64.NoPadding).EncodeToString(publicKey),
@@ -50,38 +61,238 @@ func (r *DatabaseReconciler) ReconcileDatabaseSecrets(ctx context.Context, datab
 			if err := r.Create(ctx, authSecret); err != nil {
 				return nil, err
 			}
+			r.recorder(ctx).Event(database, utils.EventNormal, "SuccessfulCreate",
+				fmt.Sprintf("create Secret %s is being created in the Namespace %s success",
+					authSecret.Name,
+					database.Namespace))
 		} else if !database.Spec.Auth && apierrors.IsNotFound(err) {
-			return nil, nil
+			if err := r.deleteDatabaseExternalSecret(ctx, database); err != nil {
+				return nil, err
+			}
+			return nil, r.deleteDatabasePublicKeySecret(ctx, database)
 		} else {
 			return nil, err
 		}
 	}
 	if !database.Spec.Auth {
 		// delete secret if database does not need auth
-		if err := r.Delete(ctx, authSecret); err != nil {
+		if err := r.deleteDatabaseExternalSecret(ctx, database); err != nil {
+			return nil, err
+		}
+		if database.Spec.RetainAuthSecret {
+			log.Info("Retaining Auth Secret while auth is turned off")
+		} else if err := r.Delete(ctx, authSecret); err != nil {
+			return nil, err
+		}
+		if err := r.deleteDatabasePublicKeySecret(ctx, database); err != nil {
 			return nil, err
 		}
 		return nil, nil
 	}
+	if database.Spec.ExternalAuthSecret != nil {
+		if err := r.ReconcileDatabaseExternalSecret(ctx, database); err != nil {
+			return nil, err
+		}
+	} else if err := r.repairDatabaseAuthSecret(ctx, database, authSecret); err != nil {
+		return nil, err
+	} else if err := r.reconcileDatabaseSecretAnnotations(ctx, database, authSecret); err != nil {
+		return nil, err
+	}
+	if database.Spec.SeparatePublicKeySecret {
+		if _, err := r.reconcileDatabasePublicKeySecret(ctx, database, authSecret); err != nil {
+			return nil, err
+		}
+	} else if err := r.deleteDatabasePublicKeySecret(ctx, database); err != nil {
+		return nil, err
+	}
 	return authSecret, nil
 }
 
+// reconcileDatabaseSharedAuthSecret returns the shared auth secret the Database verifies tokens
+// with, or nil while it does not exist. The secret is shared with other Databases, so unlike
+// the generated one it is never repaired, owned or deleted.
+func (r *DatabaseReconciler) reconcileDatabaseSharedAuthSecret(ctx context.Context, database *libsqlv1.Database) (*corev1.Secret, error) {
+	if !database.Spec.Auth {
+		return nil, r.deleteDatabasePublicKeySecret(ctx, database)
+	}
+	sharedSecret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      database.Spec.SharedAuthSecretRef.Name,
+		Namespace: database.Namespace,
+	}, sharedSecret); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	if len(getSecretValue(sharedSecret, "PUBLIC_KEY")) == 0 {
+		return nil, fmt.Errorf("shared auth Secret %s has no PUBLIC_KEY", sharedSecret.Name)
+	}
+	if database.Spec.SeparatePublicKeySecret {
+		if _, err := r.reconcileDatabasePublicKeySecret(ctx, database, sharedSecret); err != nil {
+			return nil, err
+		}
+	} else if err := r.deleteDatabasePublicKeySecret(ctx, database); err != nil {
+		return nil, err
+	}
+	return sharedSecret, nil
+}
+
+// repairDatabaseAuthSecret makes sure the auth secret holds a valid key pair, as the database pods
+// cannot start without PUBLIC_KEY. A missing or corrupted PUBLIC_KEY is derived again from
+// PRIVATE_KEY, otherwise a new key pair is generated, which invalidates the issued tokens.
+// The pods are rolled through the auth key hash of their template.
+func (r *DatabaseReconciler) repairDatabaseAuthSecret(ctx context.Context, database *libsqlv1.Database, authSecret *corev1.Secret) error {
+	encoding := base64.URLEncoding.WithPadding(base64.NoPadding)
+	publicKey, publicKeyErr := encoding.DecodeString(string(getSecretValue(authSecret, "PUBLIC_KEY")))
+	privateKey, privateKeyErr := utils.ParsePrivateKey(getSecretValue(authSecret, "PRIVATE_KEY"))
+	if privateKeyErr == nil && publicKeyErr == nil && privateKey.Public().(ed25519.PublicKey).Equal(ed25519.PublicKey(publicKey)) {
+		return nil
+	}
+	message := fmt.Sprintf("PUBLIC_KEY of Secret %s was missing or invalid, it was derived again from PRIVATE_KEY", authSecret.Name)
+	if privateKeyErr != nil {
+		generatedPublicKey, generatedPrivateKey, err := utils.GenerateAsymmetricKeys()
+		if err != nil {
+			return err
+		}
+		privateKey = generatedPrivateKey
+		publicKey = generatedPublicKey
+		message = fmt.Sprintf("PRIVATE_KEY of Secret %s was missing or invalid, a new key pair was generated and the issued tokens are no longer valid", authSecret.Name)
+	} else {
+		publicKey = privateKey.Public().(ed25519.PublicKey)
+	}
+	patch := client.MergeFrom(authSecret.DeepCopy())
+	if authSecret.Data == nil {
+		authSecret.Data = map[string][]byte{}
+	}
+	authSecret.Data["PUBLIC_KEY"] = []byte(encoding.EncodeToString(publicKey))
+	authSecret.Data["PRIVATE_KEY"] = []byte(encoding.EncodeToString(privateKey))
+	authSecret.StringData = nil
+	if err := r.Patch(ctx, authSecret, patch); err != nil {
+		return err
+	}
+	r.recorder(ctx).Event(database, utils.EventWarning, "AuthSecretRepaired", message)
+	return nil
+}
+
+// getSecretValue returns the value of key in the secret, including the StringData of a secret
+// that was just created.
+func getSecretValue(secret *corev1.Secret, key string) []byte {
+	if value, ok := secret.Data[key]; ok {
+		return value
+	}
+	return []byte(secret.StringData[key])
+}
+
+// reconcileDatabasePublicKeySecret keeps a copy of the auth secret's PUBLIC_KEY in a
+// separate secret, so consumers that only verify tokens never need access to the private key.
+func (r *DatabaseReconciler) reconcileDatabasePublicKeySecret(ctx context.Context, database *libsqlv1.Database, authSecret *corev1.Secret) (*corev1.Secret, error) {
+	log := log.FromContext(ctx)
+	publicKey := getSecretValue(authSecret, "PUBLIC_KEY")
+	publicKeySecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      utils.GetAuthPublicKeySecretName(database),
+			Namespace: database.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: databaseAPIVersion,
+					Kind:       databaseKind,
+					Name:       database.Name,
+					UID:        database.UID,
+				},
+			},
+			Labels:      r.databaseLabels(database),
+			Annotations: withManagedAnnotations(database.Spec.SecretAnnotations),
+		},
+		Data: map[string][]byte{
+			"PUBLIC_KEY": publicKey,
+		},
+	}
+	found := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      publicKeySecret.Name,
+		Namespace: publicKeySecret.Namespace,
+	}, found); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Info("Creating Auth Public Key Secret")
+			if err := r.Create(ctx, publicKeySecret); err != nil {
+				return nil, err
+			}
+			return publicKeySecret, nil
+		}
+		return nil, err
+	}
+	annotations := replaceManagedAnnotations(found.Annotations, database.Spec.SecretAnnotations)
+	if string(found.Data["PUBLIC_KEY"]) != string(publicKey) || !equality.Semantic.DeepEqual(found.Annotations, annotations) {
+		patch := client.MergeFrom(found.DeepCopy())
+		found.Data = publicKeySecret.Data
+		found.Annotations = annotations
+		if err := r.Patch(ctx, found, patch); err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// reconcileDatabaseSecretAnnotations sets spec.secretAnnotations on the generated auth secret.
+func (r *DatabaseReconciler) reconcileDatabaseSecretAnnotations(ctx context.Context, database *libsqlv1.Database, authSecret *corev1.Secret) error {
+	annotations := replaceManagedAnnotations(authSecret.Annotations, database.Spec.SecretAnnotations)
+	if equality.Semantic.DeepEqual(authSecret.Annotations, annotations) {
+		return nil
+	}
+	patch := client.MergeFrom(authSecret.DeepCopy())
+	authSecret.Annotations = annotations
+	return r.Patch(ctx, authSecret, patch)
+}
+
+func (r *DatabaseReconciler) deleteDatabasePublicKeySecret(ctx context.Context, database *libsqlv1.Database) error {
+	publicKeySecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      utils.GetAuthPublicKeySecretName(database),
+			Namespace: database.Namespace,
+		},
+	}
+	return client.IgnoreNotFound(r.Delete(ctx, publicKeySecret))
+}
+
 func (r *DatabaseReconciler) MapAuthSecretsToReconcile(ctx context.Context, object client.Object) []reconcile.Request {
 	authSecret := object.(*corev1.Secret)
+	// a shared auth secret is not owned by the Databases referencing it, it may be owned by
+	// another controller though, e.g. the External Secrets Operator
+	requests := r.mapSharedAuthSecretToReconcile(ctx, authSecret)
 	gvk, err := apiutil.GVKForObject(&libsqlv1.Database{}, r.Scheme)
 	if err != nil {
+		return requests
+	}
+	for _, ownerReference := range authSecret.ObjectMeta.OwnerReferences {
+		if ownerReference.APIVersion == gvk.GroupVersion().String() {
+			return append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: authSecret.Namespace, Name: ownerReference.Name},
+			})
+		}
+	}
+	// the owner references may have been stripped before the secret was deleted, the label of
+	// the generated secret still names its Database so that the keys are recreated
+	name, ok := authSecret.Labels[r.GetManagedByLabel()]
+	if ok && authSecret.Name == utils.GetAuthSecretName(&libsqlv1.Database{ObjectMeta: metav1.ObjectMeta{Name: name}}) {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: authSecret.Namespace, Name: name},
+		})
+	}
+	return requests
+}
+
+// mapSharedAuthSecretToReconcile returns the Databases of the namespace that reference the secret
+// as their shared auth secret.
+func (r *DatabaseReconciler) mapSharedAuthSecretToReconcile(ctx context.Context, secret *corev1.Secret) []reconcile.Request {
+	databases := &libsqlv1.DatabaseList{}
+	if err := r.List(ctx, databases, client.InNamespace(secret.Namespace)); err != nil {
 		return nil
 	}
-	if len(authSecret.ObjectMeta.OwnerReferences) > 0 {
-		for _, ownerReference := range authSecret.ObjectMeta.OwnerReferences {
-			if ownerReference.APIVersion == gvk.GroupVersion().String() {
-				return []reconcile.Request{
-					{
-						NamespacedName: types.NamespacedName{Namespace: authSecret.Namespace, Name: ownerReference.Name},
-					},
-				}
-			}
+	requests := []reconcile.Request{}
+	for _, database := range databases.Items {
+		if database.Spec.SharedAuthSecretRef != nil && database.Spec.SharedAuthSecretRef.Name == secret.Name {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: database.Namespace, Name: database.Name},
+			})
 		}
 	}
-	return nil
+	return requests
 }
@@ -0,0 +1,185 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+)
+
+var _ = Describe("Database auth secret", func() {
+	ctx := context.Background()
+
+	It("should keep the keys when auth is turned off and on with retainAuthSecret", func() {
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "retained-auth-database", Namespace: "default"},
+			Spec: libsqlv1.DatabaseSpec{
+				Image:            "ghcr.io/tursodatabase/libsql-server:v0.24.21",
+				Auth:             true,
+				RetainAuthSecret: true,
+				Storage:          libsqlv1.DatabaseStorage{Size: resource.MustParse("1Gi")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, database)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, database)
+		reconciler := &DatabaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: MockEventRecorder{}}
+		secretName := types.NamespacedName{Name: utils.GetAuthSecretName(database), Namespace: database.Namespace}
+
+		_, err := reconciler.ReconcileDatabaseSecrets(ctx, database)
+		Expect(err).NotTo(HaveOccurred())
+		secret := &corev1.Secret{}
+		Expect(k8sClient.Get(ctx, secretName, secret)).To(Succeed())
+		keys := secret.Data
+
+		By("Turning auth off, the secret is kept but not used")
+		database.Spec.Auth = false
+		authSecret, err := reconciler.ReconcileDatabaseSecrets(ctx, database)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(authSecret).To(BeNil())
+		Expect(k8sClient.Get(ctx, secretName, secret)).To(Succeed())
+
+		By("Turning auth back on, the same keys are used")
+		database.Spec.Auth = true
+		authSecret, err = reconciler.ReconcileDatabaseSecrets(ctx, database)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(authSecret.Data).Should(Equal(keys))
+	})
+
+	It("should use a shared auth secret without taking it over", func() {
+		sharedSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "fleet-auth-key", Namespace: "default"},
+			StringData: map[string]string{"PUBLIC_KEY": "fleet-public-key"},
+		}
+		Expect(k8sClient.Create(ctx, sharedSecret)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, sharedSecret)
+		reconciler := &DatabaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: MockEventRecorder{}}
+
+		databases := []*libsqlv1.Database{}
+		for _, name := range []string{"fleet-database-a", "fleet-database-b"} {
+			database := &libsqlv1.Database{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Spec: libsqlv1.DatabaseSpec{
+					Image:               "ghcr.io/tursodatabase/libsql-server:v0.24.21",
+					Auth:                true,
+					SharedAuthSecretRef: &corev1.LocalObjectReference{Name: sharedSecret.Name},
+					Storage:             libsqlv1.DatabaseStorage{Size: resource.MustParse("1Gi")},
+				},
+			}
+			Expect(k8sClient.Create(ctx, database)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, database)
+			databases = append(databases, database)
+
+			authSecret, err := reconciler.ReconcileDatabaseSecrets(ctx, database)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(authSecret.Name).Should(Equal(sharedSecret.Name))
+			statefulSet, err := reconciler.ConstructDatabaseStatefulSet(ctx, database, authSecret)
+			Expect(err).NotTo(HaveOccurred())
+			container := utils.GetContainer(&statefulSet.Spec.Template.Spec, utils.GetDatabaseContainerName(database))
+			Expect(container.Env).Should(ContainElement(HaveField("ValueFrom.SecretKeyRef.Name", sharedSecret.Name)))
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: utils.GetAuthSecretName(database), Namespace: database.Namespace}, &corev1.Secret{})).NotTo(Succeed())
+		}
+
+		By("Checking a change of the shared secret reconciles every Database referencing it")
+		Expect(reconciler.MapAuthSecretsToReconcile(ctx, sharedSecret)).Should(HaveLen(2))
+
+		By("Turning auth off for one Database, the shared secret is left alone")
+		databases[0].Spec.Auth = false
+		authSecret, err := reconciler.ReconcileDatabaseSecrets(ctx, databases[0])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(authSecret).To(BeNil())
+		found := &corev1.Secret{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: sharedSecret.Name, Namespace: "default"}, found)).To(Succeed())
+		Expect(found.OwnerReferences).Should(BeEmpty())
+		Expect(found.Data).Should(HaveKeyWithValue("PUBLIC_KEY", []byte("fleet-public-key")))
+	})
+	It("should recreate a deleted auth secret and roll the pods", func() {
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "deleted-auth-database", Namespace: "default"},
+			Spec: libsqlv1.DatabaseSpec{
+				Image:   "ghcr.io/tursodatabase/libsql-server:v0.24.21",
+				Auth:    true,
+				Storage: libsqlv1.DatabaseStorage{Size: resource.MustParse("1Gi")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, database)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, database)
+		reconciler := &DatabaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: MockEventRecorder{}}
+		typeNamespacedName := types.NamespacedName{Name: database.Name, Namespace: database.Namespace}
+		secretName := types.NamespacedName{Name: utils.GetAuthSecretName(database), Namespace: database.Namespace}
+
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		Expect(err).NotTo(HaveOccurred())
+		statefulSet := &appsv1.StatefulSet{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, statefulSet)).To(Succeed())
+		keyHash := statefulSet.Spec.Template.Annotations[databaseAuthKeyHashAnnotation]
+		Expect(keyHash).NotTo(BeEmpty())
+
+		By("Deleting the secret after its owner references were stripped")
+		secret := &corev1.Secret{}
+		Expect(k8sClient.Get(ctx, secretName, secret)).To(Succeed())
+		secret.OwnerReferences = nil
+		Expect(k8sClient.Update(ctx, secret)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, secret)).To(Succeed())
+		Expect(reconciler.MapAuthSecretsToReconcile(ctx, secret)).Should(ConsistOf(reconcile.Request{NamespacedName: typeNamespacedName}))
+
+		_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("Checking the secret was recreated with new keys and the pod template changed")
+		Expect(k8sClient.Get(ctx, secretName, secret)).To(Succeed())
+		Expect(secret.OwnerReferences).Should(ContainElement(HaveField("UID", database.UID)))
+		Expect(k8sClient.Get(ctx, typeNamespacedName, statefulSet)).To(Succeed())
+		Expect(statefulSet.Spec.Template.Annotations).Should(HaveKeyWithValue(databaseAuthKeyHashAnnotation, utils.HashValue(secret.Data["PUBLIC_KEY"])))
+		Expect(statefulSet.Spec.Template.Annotations[databaseAuthKeyHashAnnotation]).ShouldNot(Equal(keyHash))
+	})
+	It("should set spec.secretAnnotations and keep the annotations of others", func() {
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "annotated-auth-database", Namespace: "default"},
+			Spec: libsqlv1.DatabaseSpec{
+				Image:                   "ghcr.io/tursodatabase/libsql-server:v0.24.21",
+				Auth:                    true,
+				SeparatePublicKeySecret: true,
+				SecretAnnotations:       map[string]string{"reflector.v2.k8s.emberstack.com/reflection-allowed": "true"},
+				Storage:                 libsqlv1.DatabaseStorage{Size: resource.MustParse("1Gi")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, database)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, database)
+		reconciler := &DatabaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: MockEventRecorder{}}
+		secretNames := []types.NamespacedName{
+			{Name: utils.GetAuthSecretName(database), Namespace: database.Namespace},
+			{Name: utils.GetAuthPublicKeySecretName(database), Namespace: database.Namespace},
+		}
+
+		_, err := reconciler.ReconcileDatabaseSecrets(ctx, database)
+		Expect(err).NotTo(HaveOccurred())
+		for _, secretName := range secretNames {
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, secretName, secret)).To(Succeed())
+			Expect(secret.Annotations).Should(HaveKeyWithValue("reflector.v2.k8s.emberstack.com/reflection-allowed", "true"))
+			secret.Annotations["example.com/owner"] = "team-a"
+			Expect(k8sClient.Update(ctx, secret)).To(Succeed())
+		}
+
+		By("Replacing the annotations of the spec, the ones added by others are kept")
+		database.Spec.SecretAnnotations = map[string]string{"reflector.v2.k8s.emberstack.com/reflection-allowed-namespaces": "apps"}
+		_, err = reconciler.ReconcileDatabaseSecrets(ctx, database)
+		Expect(err).NotTo(HaveOccurred())
+		for _, secretName := range secretNames {
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, secretName, secret)).To(Succeed())
+			Expect(secret.Annotations).Should(HaveKeyWithValue("reflector.v2.k8s.emberstack.com/reflection-allowed-namespaces", "apps"))
+			Expect(secret.Annotations).Should(HaveKeyWithValue("example.com/owner", "team-a"))
+			Expect(secret.Annotations).ShouldNot(HaveKey("reflector.v2.k8s.emberstack.com/reflection-allowed"))
+		}
+	})
+})
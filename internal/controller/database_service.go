@@ -3,36 +3,74 @@ package controller
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 
 	libsqlv1 "github.com/ahti-database/operator/api/v1"
 	"github.com/ahti-database/operator/internal/utils"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+const (
+	databaseHTTPPort int32 = 8080
+	databaseGRPCPort int32 = 5001
+
+	// databaseManagedAnnotationsAnnotation lists the annotations of a generated resource taken from
+	// the spec, e.g. spec.service.grpc.annotations of the gRPC Service
+	databaseManagedAnnotationsAnnotation string = "libsql.ahti.io/managed-annotations"
+)
+
+// ReconcileDatabaseService keeps the headless and the client facing service of the Database, and
+// records the headless service address on the status for replicas to discover the primary through.
+// With spec.service.grpc the gRPC port is served by a separate Service.
 func (r *DatabaseReconciler) ReconcileDatabaseService(ctx context.Context, database *libsqlv1.Database) (reconciledHeadlessService *corev1.Service, reconciledService *corev1.Service, reconcileErr error) {
-	headlessService, err := r.reconcileDatabaseService(ctx, database, true)
+	headlessService, err := r.reconcileDatabaseService(ctx, database, r.ConstructDatabaseService(ctx, database, true), nil)
 	if err != nil {
 		return nil, nil, err
 	}
-	service, err := r.reconcileDatabaseService(ctx, database, false)
+	database.Status.PrimaryEndpoint = &libsqlv1.DatabaseEndpoint{
+		Host:     utils.GetDatabaseServiceFQDN(database, true),
+		GRPCPort: databaseGRPCPort,
+	}
+	service, err := r.reconcileDatabaseService(ctx, database, r.ConstructDatabaseService(ctx, database, false), externalDNSAnnotations)
 	if err != nil {
 		return headlessService, nil, err
 	}
+	if isDatabaseGRPCServiceSeparate(database) {
+		found := &corev1.Service{}
+		if err := r.Get(ctx, types.NamespacedName{Name: utils.GetDatabaseGRPCServiceName(database), Namespace: database.Namespace}, found); client.IgnoreNotFound(err) != nil {
+			return headlessService, service, err
+		}
+		// the annotations applied by the previous update are replaced, so removed ones are removed
+		managedAnnotations := append(strings.Split(found.Annotations[databaseManagedAnnotationsAnnotation], ","),
+			databaseManagedAnnotationsAnnotation)
+		if _, err := r.reconcileDatabaseService(ctx, database, r.ConstructDatabaseGRPCService(database), managedAnnotations); err != nil {
+			return headlessService, service, err
+		}
+	}
 	return headlessService, service, nil
 }
 
-func (r *DatabaseReconciler) reconcileDatabaseService(ctx context.Context, database *libsqlv1.Database, headless bool) (*corev1.Service, error) {
+func isDatabaseGRPCServiceSeparate(database *libsqlv1.Database) bool {
+	return database.Spec.Service != nil && database.Spec.Service.GRPC != nil
+}
+
+// reconcileDatabaseService creates or updates service. Of the annotations only the managed keys
+// are replaced, so the ones set by others are kept.
+func (r *DatabaseReconciler) reconcileDatabaseService(ctx context.Context, database *libsqlv1.Database, service *corev1.Service, managedAnnotations []string) (*corev1.Service, error) {
 	found := &corev1.Service{}
-	service := r.ConstructDatabaseService(ctx, database, headless)
 	if err := r.Get(
 		ctx,
 		types.NamespacedName{
-			Name:      utils.GetDatabaseServiceName(database, headless),
-			Namespace: database.Namespace,
+			Name:      service.Name,
+			Namespace: service.Namespace,
 		},
 		found,
 	); err != nil {
@@ -40,19 +78,56 @@ func (r *DatabaseReconciler) reconcileDatabaseService(ctx context.Context, datab
 			if err := r.Create(ctx, service); err != nil {
 				return nil, err
 			}
-			r.Recorder.Event(database, utils.EventNormal, "SuccessfulCreate",
+			r.recorder(ctx).Event(database, utils.EventNormal, "SuccessfulCreate",
 				fmt.Sprintf("create Service %s is being created in the Namespace %s success",
-					utils.GetDatabaseServiceName(database, headless),
+					service.Name,
 					database.Namespace))
-		} else {
-			return nil, err
+			return service, nil
 		}
+		return nil, err
+	}
+	// patch the found service, so the annotations and allocated fields set by others are kept
+	patch := client.MergeFrom(found.DeepCopy())
+	adopted, err := r.adoptDatabaseResource(ctx, database, found, "Service")
+	if err != nil {
+		return nil, err
+	}
+	annotations := utils.ReplaceAnnotations(found.Annotations, managedAnnotations, service.Annotations)
+	serviceType := service.Spec.Type
+	if serviceType == "" {
+		serviceType = found.Spec.Type
 	}
-	// patch the service
-	if err := r.Update(ctx, service); err != nil {
+	// the IP families are allocated by the cluster when unset, those of the found service are kept
+	ipFamilies, ipFamilyPolicy := service.Spec.IPFamilies, service.Spec.IPFamilyPolicy
+	if len(ipFamilies) == 0 {
+		ipFamilies = found.Spec.IPFamilies
+	}
+	if ipFamilyPolicy == nil {
+		ipFamilyPolicy = found.Spec.IPFamilyPolicy
+	}
+	labels := utils.MergeLabels(found.Labels, service.Labels)
+	if !adopted && equality.Semantic.DeepEqual(found.Spec.Ports, service.Spec.Ports) &&
+		equality.Semantic.DeepEqual(found.Spec.Selector, service.Spec.Selector) &&
+		found.Spec.Type == serviceType &&
+		found.Spec.PublishNotReadyAddresses == service.Spec.PublishNotReadyAddresses &&
+		equality.Semantic.DeepEqual(found.Spec.IPFamilies, ipFamilies) &&
+		equality.Semantic.DeepEqual(found.Spec.IPFamilyPolicy, ipFamilyPolicy) &&
+		equality.Semantic.DeepEqual(found.Labels, labels) &&
+		equality.Semantic.DeepEqual(found.Annotations, annotations) {
+		return found, nil
+	}
+	found.Spec.Ports = service.Spec.Ports
+	found.Spec.Selector = service.Spec.Selector
+	found.Spec.Type = serviceType
+	found.Spec.PublishNotReadyAddresses = service.Spec.PublishNotReadyAddresses
+	found.Spec.IPFamilies = ipFamilies
+	found.Spec.IPFamilyPolicy = ipFamilyPolicy
+	found.Labels = labels
+	found.Annotations = annotations
+	if err := r.Patch(ctx, found, patch); err != nil {
 		return nil, err
 	}
-	return service, nil
+	return found, nil
 }
 
 func (r *DatabaseReconciler) ConstructDatabaseService(ctx context.Context, database *libsqlv1.Database, headless bool) *corev1.Service {
@@ -68,34 +143,113 @@ func (r *DatabaseReconciler) ConstructDatabaseService(ctx context.Context, datab
 					UID:        database.UID,
 				},
 			},
-			Labels: map[string]string{
-				databaseLabel: database.Name,
-				"node":        "primary",
-			},
+			Labels: r.databaseLabels(database),
 		},
 		Spec: corev1.ServiceSpec{
 			Ports: []corev1.ServicePort{
-				{
-					Port:       int32(8080),
-					TargetPort: intstr.FromInt32(int32(8080)),
-					Protocol:   corev1.ProtocolTCP,
-					Name:       "primary-http",
-				},
-				{
-					Port:       int32(5001),
-					TargetPort: intstr.FromInt32(int32(5001)),
-					Protocol:   corev1.ProtocolTCP,
-					Name:       "primary-grpc",
-				},
-			},
-			Selector: map[string]string{
-				databaseLabel: database.Name,
-				"node":        "primary",
+				constructDatabaseHTTPServicePort(database),
+				constructDatabaseGRPCServicePort(database),
 			},
+			Selector: r.databaseSelectorLabels(database),
 		},
 	}
+	setDatabaseServiceIPFamilies(database, service)
 	if headless {
 		service.Spec.ClusterIP = "None"
+		service.Spec.PublishNotReadyAddresses = database.Spec.Service != nil && database.Spec.Service.PublishNotReadyAddresses
+	} else if database.Spec.Service != nil {
+		service.Annotations = constructExternalDNSAnnotations(database.Spec.Service.ExternalDNS)
+		if isDatabaseGRPCServiceSeparate(database) {
+			service.Spec.Ports = service.Spec.Ports[:1]
+		}
+	}
+	if exporter := getDatabaseMetricsExporter(database); exporter != nil && !headless {
+		service.Spec.Ports = append(service.Spec.Ports, constructDatabaseMetricsServicePort(exporter))
 	}
 	return service
 }
+
+// setDatabaseServiceIPFamilies sets the IP families of spec.service on the service.
+func setDatabaseServiceIPFamilies(database *libsqlv1.Database, service *corev1.Service) {
+	if database.Spec.Service == nil {
+		return
+	}
+	service.Spec.IPFamilies = database.Spec.Service.IPFamilies
+	service.Spec.IPFamilyPolicy = database.Spec.Service.IPFamilyPolicy
+}
+
+func (r *DatabaseReconciler) ConstructDatabaseGRPCService(database *libsqlv1.Database) *corev1.Service {
+	grpc := database.Spec.Service.GRPC
+	annotations := utils.MergeLabels(grpc.Annotations)
+	managedAnnotations := make([]string, 0, len(grpc.Annotations))
+	for key := range grpc.Annotations {
+		managedAnnotations = append(managedAnnotations, key)
+	}
+	slices.Sort(managedAnnotations)
+	annotations[databaseManagedAnnotationsAnnotation] = strings.Join(managedAnnotations, ",")
+	serviceType := grpc.Type
+	if serviceType == "" {
+		serviceType = corev1.ServiceTypeClusterIP
+	}
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      utils.GetDatabaseGRPCServiceName(database),
+			Namespace: database.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: databaseAPIVersion,
+					Kind:       databaseKind,
+					Name:       database.Name,
+					UID:        database.UID,
+				},
+			},
+			Labels:      r.databaseLabels(database),
+			Annotations: annotations,
+		},
+		Spec: corev1.ServiceSpec{
+			Type: serviceType,
+			Ports: []corev1.ServicePort{
+				constructDatabaseGRPCServicePort(database),
+			},
+			Selector: r.databaseSelectorLabels(database),
+		},
+	}
+	setDatabaseServiceIPFamilies(database, service)
+	return service
+}
+
+func constructDatabaseHTTPServicePort(database *libsqlv1.Database) corev1.ServicePort {
+	appProtocol := ptr.To("http")
+	if database.Spec.Service != nil && database.Spec.Service.HTTPAppProtocol != nil {
+		appProtocol = database.Spec.Service.HTTPAppProtocol
+	}
+	return corev1.ServicePort{
+		Port:        databaseHTTPPort,
+		TargetPort:  intstr.FromInt32(getDatabaseHTTPPort(database)),
+		Protocol:    corev1.ProtocolTCP,
+		Name:        "primary-http",
+		AppProtocol: emptyToNil(appProtocol),
+	}
+}
+
+func constructDatabaseGRPCServicePort(database *libsqlv1.Database) corev1.ServicePort {
+	appProtocol := ptr.To("grpc")
+	if database.Spec.Service != nil && database.Spec.Service.GRPCAppProtocol != nil {
+		appProtocol = database.Spec.Service.GRPCAppProtocol
+	}
+	return corev1.ServicePort{
+		Port:        databaseGRPCPort,
+		TargetPort:  intstr.FromInt32(databaseGRPCPort),
+		Protocol:    corev1.ProtocolTCP,
+		Name:        "primary-grpc",
+		AppProtocol: emptyToNil(appProtocol),
+	}
+}
+
+// emptyToNil returns nil for a pointer to an empty string.
+func emptyToNil(value *string) *string {
+	if value == nil || *value == "" {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+)
+
+var _ = Describe("Database Service", func() {
+	It("should set the IP families of spec.service on every Service", func() {
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "default"},
+			Spec: libsqlv1.DatabaseSpec{
+				Service: &libsqlv1.DatabaseServiceSpec{
+					GRPC:           &libsqlv1.DatabaseGRPCServiceSpec{},
+					IPFamilies:     []corev1.IPFamily{corev1.IPv6Protocol, corev1.IPv4Protocol},
+					IPFamilyPolicy: ptr.To(corev1.IPFamilyPolicyPreferDualStack),
+				},
+			},
+		}
+		reconciler := &DatabaseReconciler{}
+		for _, service := range []*corev1.Service{
+			reconciler.ConstructDatabaseService(context.Background(), database, true),
+			reconciler.ConstructDatabaseService(context.Background(), database, false),
+			reconciler.ConstructDatabaseGRPCService(database),
+		} {
+			Expect(service.Spec.IPFamilies).Should(Equal([]corev1.IPFamily{corev1.IPv6Protocol, corev1.IPv4Protocol}))
+			Expect(service.Spec.IPFamilyPolicy).Should(HaveValue(Equal(corev1.IPFamilyPolicyPreferDualStack)))
+		}
+	})
+
+	It("should leave the IP families to the cluster by default", func() {
+		database := &libsqlv1.Database{ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "default"}}
+		service := (&DatabaseReconciler{}).ConstructDatabaseService(context.Background(), database, false)
+		Expect(service.Spec.IPFamilies).Should(BeEmpty())
+		Expect(service.Spec.IPFamilyPolicy).Should(BeNil())
+	})
+	It("should only publish the not ready addresses of the headless service", func() {
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "default"},
+			Spec: libsqlv1.DatabaseSpec{
+				Service: &libsqlv1.DatabaseServiceSpec{PublishNotReadyAddresses: true},
+			},
+		}
+		reconciler := &DatabaseReconciler{}
+		Expect(reconciler.ConstructDatabaseService(context.Background(), database, true).Spec.PublishNotReadyAddresses).Should(BeTrue())
+		Expect(reconciler.ConstructDatabaseService(context.Background(), database, false).Spec.PublishNotReadyAddresses).Should(BeFalse())
+
+		database.Spec.Service = nil
+		Expect(reconciler.ConstructDatabaseService(context.Background(), database, true).Spec.PublishNotReadyAddresses).Should(BeFalse())
+	})
+	It("should keep the fields set by others when the Service is patched", func() {
+		ctx := context.Background()
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "patched-service-database", Namespace: "default"},
+			Spec: libsqlv1.DatabaseSpec{
+				Image:   "ghcr.io/tursodatabase/libsql-server:v0.24.21",
+				Storage: libsqlv1.DatabaseStorage{Size: resource.MustParse("1Gi")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, database)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, database)
+		reconciler := &DatabaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: MockEventRecorder{}}
+		_, service, err := reconciler.ReconcileDatabaseService(ctx, database)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("Setting fields of the Service the operator does not generate")
+		service.Annotations = map[string]string{"cloud.example.com/internal": "true"}
+		service.Labels["argocd.argoproj.io/instance"] = "databases"
+		service.Spec.SessionAffinity = corev1.ServiceAffinityClientIP
+		Expect(k8sClient.Update(ctx, service)).To(Succeed())
+
+		database.Spec.Service = &libsqlv1.DatabaseServiceSpec{HTTPAppProtocol: ptr.To("")}
+		_, _, err = reconciler.ReconcileDatabaseService(ctx, database)
+		Expect(err).NotTo(HaveOccurred())
+
+		found := &corev1.Service{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: service.Name, Namespace: service.Namespace}, found)).To(Succeed())
+		Expect(found.Spec.Ports[0].AppProtocol).Should(BeNil())
+		Expect(found.Annotations).Should(HaveKeyWithValue("cloud.example.com/internal", "true"))
+		Expect(found.Labels).Should(HaveKeyWithValue("argocd.argoproj.io/instance", "databases"))
+		Expect(found.Spec.SessionAffinity).Should(Equal(corev1.ServiceAffinityClientIP))
+	})
+})
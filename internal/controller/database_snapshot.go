@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// typeVolumeSnapshotUnsupported is True while the snapshot annotation is ignored because the
+// VolumeSnapshot CRDs are not installed
+const typeVolumeSnapshotUnsupported = "VolumeSnapshotUnsupported"
+
+var volumeSnapshotGVK = schema.GroupVersionKind{
+	Group:   "snapshot.storage.k8s.io",
+	Version: "v1",
+	Kind:    "VolumeSnapshot",
+}
+
+// ReconcileDatabaseSnapshot takes a VolumeSnapshot of the primary data volume when the
+// libsql.ahti.io/snapshot annotation names one that does not exist yet, and keeps the
+// readiness of the last requested snapshot in the status.
+// The VolumeSnapshots are not owned by the Database, so they outlive it and can be used
+// to restore a new Database through spec.storage.restoreFromSnapshot.
+func (r *DatabaseReconciler) ReconcileDatabaseSnapshot(ctx context.Context, database *libsqlv1.Database) error {
+	snapshotName, ok := database.Annotations[databaseSnapshotAnnotation]
+	if !ok || snapshotName == "" {
+		meta.RemoveStatusCondition(&database.Status.Conditions, typeVolumeSnapshotUnsupported)
+		return nil
+	}
+	snapshot := &unstructured.Unstructured{}
+	snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+	if err := r.Get(ctx, types.NamespacedName{Name: snapshotName, Namespace: database.Namespace}, snapshot); err != nil {
+		if meta.IsNoMatchError(err) {
+			r.setDatabaseCRDNotInstalled(ctx, database, typeVolumeSnapshotUnsupported,
+				"VolumeSnapshot CRDs are not installed in the cluster, the snapshot annotation is ignored")
+			return nil
+		}
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		snapshot = r.ConstructDatabaseSnapshot(database, snapshotName)
+		if err := r.Create(ctx, snapshot); err != nil {
+			return err
+		}
+		r.recorder(ctx).Event(database, utils.EventNormal, "SuccessfulCreate",
+			fmt.Sprintf("create VolumeSnapshot %s is being created in the Namespace %s success",
+				snapshotName,
+				database.Namespace))
+	}
+	meta.RemoveStatusCondition(&database.Status.Conditions, typeVolumeSnapshotUnsupported)
+	readyToUse, _, err := unstructured.NestedBool(snapshot.Object, "status", "readyToUse")
+	if err != nil {
+		return err
+	}
+	database.Status.LastSnapshot = &libsqlv1.DatabaseSnapshotStatus{Name: snapshotName, ReadyToUse: readyToUse}
+	return nil
+}
+
+func (r *DatabaseReconciler) ConstructDatabaseSnapshot(database *libsqlv1.Database, snapshotName string) *unstructured.Unstructured {
+	snapshot := &unstructured.Unstructured{}
+	snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+	snapshot.SetName(snapshotName)
+	snapshot.SetNamespace(database.Namespace)
+	snapshot.SetLabels(r.databaseLabels(database))
+	spec := map[string]interface{}{
+		"source": map[string]interface{}{
+			"persistentVolumeClaimName": utils.GetDatabaseStatefulSetPVCName(database, 0),
+		},
+	}
+	if database.Spec.Storage.VolumeSnapshotClassName != nil {
+		spec["volumeSnapshotClassName"] = *database.Spec.Storage.VolumeSnapshotClassName
+	}
+	snapshot.Object["spec"] = spec
+	return snapshot
+}
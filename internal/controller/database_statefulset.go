@@ -2,14 +2,22 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
 
 	libsqlv1 "github.com/ahti-database/operator/api/v1"
 	"github.com/ahti-database/operator/internal/utils"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	nodev1 "k8s.io/api/node/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/utils/ptr"
@@ -19,9 +27,53 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
-func (r *DatabaseReconciler) ReconcileDatabaseStatefulSets(ctx context.Context, database *libsqlv1.Database) (*appsv1.StatefulSet, error) {
+// databasePrimaryReplicas is the replica count of the primary StatefulSet. sqld has a single
+// writer, so the primary is never scaled and manual scaling is reverted on every reconcile.
+const databasePrimaryReplicas int32 = 1
+
+// restartedAtAnnotation is set on the pod template by kubectl rollout restart
+const restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+// databaseTemplateAnnotations are the pod template annotations generated by the operator, they
+// are removed from the StatefulSet once they are no longer generated
+var databaseTemplateAnnotations = []string{databaseAuthKeyHashAnnotation, databaseConfigHashAnnotation}
+
+// databaseStartupProbePeriodSeconds is how often the startup probe checks the database
+const databaseStartupProbePeriodSeconds int32 = 10
+
+// Probe settings of spec.probe.slowStart, see its documentation
+const (
+	slowStartTimeoutSeconds           int32 = 30 * 60
+	slowStartLivenessPeriodSeconds    int32 = 20
+	slowStartLivenessFailureThreshold int32 = 6
+	slowStartMinReadySeconds          int32 = 30
+)
+
+// Defaults of spec.drain and spec.terminationGracePeriodSeconds
+const (
+	defaultDatabaseDrainPeriodSeconds            int32 = 15
+	defaultDatabaseTerminationGracePeriodSeconds int64 = 30
+)
+
+func (r *DatabaseReconciler) ReconcileDatabaseStatefulSets(ctx context.Context, database *libsqlv1.Database, authSecret *corev1.Secret) (*appsv1.StatefulSet, error) {
+	if err := r.checkDatabaseRuntimeClass(ctx, database); err != nil {
+		return nil, err
+	}
 	found := &appsv1.StatefulSet{}
-	primaryStatefulSet := r.ConstructDatabaseStatefulSet(ctx, database)
+	primaryStatefulSet, err := r.ConstructDatabaseStatefulSet(ctx, database, authSecret)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.setDatabaseConfigHash(ctx, database, &primaryStatefulSet.Spec.Template); err != nil {
+		return nil, err
+	}
+	// the hash covers the generated spec only, the immutable fields kept from the found
+	// StatefulSet are left out
+	specHash, err := hashDatabaseStatefulSet(primaryStatefulSet)
+	if err != nil {
+		return nil, err
+	}
+	primaryStatefulSet.Annotations = map[string]string{databaseSpecHashAnnotation: specHash}
 	if err := r.Get(
 		ctx,
 		types.NamespacedName{
@@ -32,24 +84,243 @@ func (r *DatabaseReconciler) ReconcileDatabaseStatefulSets(ctx context.Context,
 	); err != nil {
 		if apierrors.IsNotFound(err) {
 			if err := r.Create(ctx, primaryStatefulSet); err != nil {
-				return nil, err
+				return nil, &ReconcileError{Reason: reasonStatefulSetCreateFailed, Err: err}
 			}
-			r.Recorder.Event(database, utils.EventNormal, "SuccessfulCreate",
+			r.recorder(ctx).Event(database, utils.EventNormal, "SuccessfulCreate",
 				fmt.Sprintf("create StatefulSet %s is being created in the Namespace %s success",
 					database.Name,
 					database.Namespace))
-		} else {
-			return nil, err
+			return primaryStatefulSet, nil
 		}
+		return nil, err
 	}
-	// patch the statefulset
-	if err := r.Update(ctx, primaryStatefulSet); err != nil {
+	if err := r.checkDatabaseStatefulSetController(ctx, database, found); err != nil {
 		return nil, err
 	}
-	return primaryStatefulSet, nil
+	// checked before adopting it, so it is only adopted once it can be patched
+	keepDatabaseStatefulSetImmutableFields(found, primaryStatefulSet)
+	if err := r.checkDatabaseStatefulSetCompatible(ctx, database, found, primaryStatefulSet); err != nil {
+		return nil, err
+	}
+	// patch the generated fields of the found statefulset, so the annotations and owner
+	// references set by others are kept
+	patch := client.MergeFrom(found.DeepCopy())
+	if _, err := r.adoptDatabaseResource(ctx, database, found, "StatefulSet"); err != nil {
+		return nil, err
+	}
+	if replicas := ptr.Deref(found.Spec.Replicas, databasePrimaryReplicas); replicas != databasePrimaryReplicas {
+		r.recorder(ctx).Event(database, utils.EventWarning, "PrimaryScaled",
+			fmt.Sprintf("StatefulSet %s was scaled to %d replicas, scaling it back to %d as the primary is single-writer",
+				found.Name,
+				replicas,
+				databasePrimaryReplicas))
+	} else if found.Annotations[databaseSpecHashAnnotation] == specHash && isDatabaseStatefulSetInSync(found, primaryStatefulSet) {
+		// nothing changed since the last update, neither in the Database nor on the StatefulSet
+		return found, nil
+	}
+	found.Labels = utils.MergeLabels(found.Labels, primaryStatefulSet.Labels)
+	found.Annotations = utils.MergeLabels(found.Annotations, primaryStatefulSet.Annotations)
+	// the template annotations set by others are kept, e.g. the one of kubectl rollout restart so
+	// that the pods are not restarted again
+	templateAnnotations := utils.ReplaceAnnotations(found.Spec.Template.Annotations,
+		databaseTemplateAnnotations, primaryStatefulSet.Spec.Template.Annotations)
+	// volumeClaimTemplates of a StatefulSet are immutable, keep the ones it was created with
+	volumeClaimTemplates := found.Spec.VolumeClaimTemplates
+	found.Spec = primaryStatefulSet.Spec
+	found.Spec.VolumeClaimTemplates = volumeClaimTemplates
+	found.Spec.Template.Annotations = templateAnnotations
+	if err := r.Patch(ctx, found, patch); err != nil {
+		return nil, err
+	}
+	return found, nil
 }
 
-func (r *DatabaseReconciler) ConstructDatabaseStatefulSet(ctx context.Context, database *libsqlv1.Database) *appsv1.StatefulSet {
+// checkDatabaseStatefulSetController marks the Database Degraded instead of updating the found
+// StatefulSet while another controller, e.g. another operator, controls it. Both updating it
+// would fight over its spec. The returned error has the reconcile retried, so the condition is
+// cleared once the other controller lets go of the StatefulSet.
+func (r *DatabaseReconciler) checkDatabaseStatefulSetController(ctx context.Context, database *libsqlv1.Database, found *appsv1.StatefulSet) error {
+	controller := metav1.GetControllerOf(found)
+	if controller == nil || controller.UID == database.UID {
+		condition := meta.FindStatusCondition(database.Status.Conditions, typeDegradedDatabase)
+		if condition != nil && condition.Reason == reasonForeignController {
+			meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{Type: typeDegradedDatabase,
+				Status: metav1.ConditionFalse, Reason: reasonControllerOwned,
+				Message: fmt.Sprintf("StatefulSet %s is no longer controlled by another controller", found.Name)})
+		}
+		return nil
+	}
+	message := fmt.Sprintf("StatefulSet %s is controlled by %s %s, it is not updated until the controller reference is removed",
+		found.Name, controller.Kind, controller.Name)
+	changed := meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{Type: typeDegradedDatabase,
+		Status: metav1.ConditionTrue, Reason: reasonForeignController, Message: message})
+	if changed {
+		r.recorder(ctx).Event(database, utils.EventWarning, reasonForeignController, message)
+	}
+	return &ReconcileError{Reason: reasonForeignController, Err: errors.New(message)}
+}
+
+// keepDatabaseStatefulSetImmutableFields keeps the fields of the found StatefulSet that cannot be
+// changed, e.g. of a StatefulSet created under another managed-by label key or written by hand
+// before it was adopted. The labels of its selector are added to the pod template, so that the
+// pods still match it. The volume claim templates are kept when the StatefulSet is patched.
+func keepDatabaseStatefulSetImmutableFields(found, generated *appsv1.StatefulSet) {
+	generated.Spec.ServiceName = found.Spec.ServiceName
+	generated.Spec.PodManagementPolicy = found.Spec.PodManagementPolicy
+	if found.Spec.Selector == nil {
+		return
+	}
+	generated.Spec.Selector = found.Spec.Selector.DeepCopy()
+	generated.Spec.Template.Labels = utils.MergeLabels(generated.Spec.Template.Labels, found.Spec.Selector.MatchLabels)
+}
+
+// checkDatabaseStatefulSetCompatible marks the Database Degraded when the found StatefulSet cannot
+// be patched into the generated one, e.g. a StatefulSet written by hand with another volume claim
+// template name, since the immutable fields it was created with are kept. The StatefulSet has to
+// be recreated, keeping its volumes, for the Database to take it over.
+func (r *DatabaseReconciler) checkDatabaseStatefulSetCompatible(ctx context.Context, database *libsqlv1.Database, found, generated *appsv1.StatefulSet) error {
+	message := ""
+	selector, err := metav1.LabelSelectorAsSelector(generated.Spec.Selector)
+	if err != nil {
+		return err
+	}
+	claimTemplate := utils.GetDatabasePVCName(database)
+	if !selector.Matches(labels.Set(generated.Spec.Template.Labels)) {
+		message = fmt.Sprintf("its selector %s does not match the labels of the database pods", selector)
+	} else if !slices.ContainsFunc(found.Spec.VolumeClaimTemplates, func(claim corev1.PersistentVolumeClaim) bool {
+		return claim.Name == claimTemplate
+	}) {
+		message = fmt.Sprintf("it has no volume claim template %s for the data volume", claimTemplate)
+	}
+	if message == "" {
+		condition := meta.FindStatusCondition(database.Status.Conditions, typeDegradedDatabase)
+		if condition != nil && condition.Reason == reasonIncompatibleStatefulSet {
+			meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{Type: typeDegradedDatabase,
+				Status: metav1.ConditionFalse, Reason: reasonCompatibleStatefulSet,
+				Message: fmt.Sprintf("StatefulSet %s is compatible with Database %s", found.Name, database.Name)})
+		}
+		return nil
+	}
+	message = fmt.Sprintf("Cannot update StatefulSet %s: %s, recreate it to have Database %s take it over", found.Name, message, database.Name)
+	changed := meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{Type: typeDegradedDatabase,
+		Status: metav1.ConditionTrue, Reason: reasonIncompatibleStatefulSet, Message: message})
+	if changed {
+		r.recorder(ctx).Event(database, utils.EventWarning, reasonIncompatibleStatefulSet, message)
+	}
+	return &ReconcileError{Reason: reasonIncompatibleStatefulSet, Err: errors.New(message)}
+}
+
+// hashDatabaseStatefulSet hashes the generated labels and spec of the StatefulSet. Comparing it
+// with the hash recorded on the existing StatefulSet avoids comparing against the fields the API
+// server defaults.
+func hashDatabaseStatefulSet(statefulSet *appsv1.StatefulSet) (string, error) {
+	generated, err := json.Marshal(struct {
+		Labels map[string]string
+		Spec   appsv1.StatefulSetSpec
+	}{statefulSet.Labels, statefulSet.Spec})
+	if err != nil {
+		return "", err
+	}
+	return utils.HashValue(generated), nil
+}
+
+// isDatabaseStatefulSetInSync reports whether the found StatefulSet still has the generated labels
+// and spec, so that changes made to it directly, e.g. with kubectl edit, are reverted. Fields the
+// generated spec leaves unset are not compared, the API server defaults them. Changes of the
+// Database that unset a field are caught by the spec hash instead.
+func isDatabaseStatefulSetInSync(found, generated *appsv1.StatefulSet) bool {
+	spec := generated.Spec.DeepCopy()
+	// volumeClaimTemplates are immutable, the found ones are kept
+	spec.VolumeClaimTemplates = nil
+	for i := range spec.Template.Spec.Containers {
+		container := &spec.Template.Spec.Containers[i]
+		if foundContainer := utils.GetContainer(&found.Spec.Template.Spec, container.Name); foundContainer != nil {
+			setDefaultedProbeTimings(container.LivenessProbe, foundContainer.LivenessProbe)
+			setDefaultedProbeTimings(container.ReadinessProbe, foundContainer.ReadinessProbe)
+			setDefaultedProbeTimings(container.StartupProbe, foundContainer.StartupProbe)
+		}
+	}
+	return equality.Semantic.DeepDerivative(generated.Labels, found.Labels) &&
+		equality.Semantic.DeepDerivative(*spec, found.Spec)
+}
+
+// setDefaultedProbeTimings copies the timings of the found probe onto the generated probe where
+// it leaves them unset, they are plain integers the API server defaults.
+func setDefaultedProbeTimings(generated *corev1.Probe, found *corev1.Probe) {
+	if generated == nil || found == nil {
+		return
+	}
+	for _, timing := range []struct{ generated, found *int32 }{
+		{&generated.InitialDelaySeconds, &found.InitialDelaySeconds},
+		{&generated.TimeoutSeconds, &found.TimeoutSeconds},
+		{&generated.PeriodSeconds, &found.PeriodSeconds},
+		{&generated.SuccessThreshold, &found.SuccessThreshold},
+		{&generated.FailureThreshold, &found.FailureThreshold},
+	} {
+		if *timing.generated == 0 {
+			*timing.generated = *timing.found
+		}
+	}
+}
+
+// checkDatabaseRuntimeClass warns when the RuntimeClass of the Database does not exist, as the
+// pods cannot be created until it does. It does not block the reconcile since the RuntimeClass
+// may be installed later.
+func (r *DatabaseReconciler) checkDatabaseRuntimeClass(ctx context.Context, database *libsqlv1.Database) error {
+	if database.Spec.RuntimeClassName == nil {
+		return nil
+	}
+	runtimeClass := &nodev1.RuntimeClass{}
+	if err := r.Get(ctx, types.NamespacedName{Name: *database.Spec.RuntimeClassName}, runtimeClass); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.recorder(ctx).Event(database, utils.EventWarning, "RuntimeClassNotFound",
+				fmt.Sprintf("RuntimeClass %s does not exist, database pods cannot be created until it does",
+					*database.Spec.RuntimeClassName))
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// ReconcileDatabaseAvailability derives the Available and Progressing conditions of the Database
+// from the rollout of the primary StatefulSet and the phase of its data volume.
+func (r *DatabaseReconciler) ReconcileDatabaseAvailability(ctx context.Context, database *libsqlv1.Database, statefulSet *appsv1.StatefulSet) error {
+	replicas := ptr.Deref(statefulSet.Spec.Replicas, 1)
+	status := statefulSet.Status
+	if status.ObservedGeneration >= statefulSet.Generation && status.UpdatedReplicas >= replicas && status.ReadyReplicas >= replicas {
+		becameAvailable := meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{Type: typeAvailableDatabase,
+			Status: metav1.ConditionTrue, Reason: reasonReady,
+			Message: fmt.Sprintf("Database %s is ready", database.Name)})
+		r.recordDatabaseReady(ctx, database, becameAvailable)
+		meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{Type: typeProgressingDatabase,
+			Status: metav1.ConditionFalse, Reason: reasonReady,
+			Message: fmt.Sprintf("Rollout of database %s is complete", database.Name)})
+		return nil
+	}
+	reason := reasonRolloutInProgress
+	message := fmt.Sprintf("Waiting for database pods to be ready: %d of %d ready, %d of %d updated",
+		status.ReadyReplicas, replicas, status.UpdatedReplicas, replicas)
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      utils.GetDatabaseStatefulSetPVCName(database, 0),
+		Namespace: database.Namespace,
+	}, pvc); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+	} else if pvc.Status.Phase == corev1.ClaimPending {
+		reason = reasonPVCPending
+		message = fmt.Sprintf("Waiting for PersistentVolumeClaim %s to be bound", pvc.Name)
+	}
+	meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{Type: typeAvailableDatabase,
+		Status: metav1.ConditionFalse, Reason: reason, Message: message})
+	meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{Type: typeProgressingDatabase,
+		Status: metav1.ConditionTrue, Reason: reason, Message: message})
+	return nil
+}
+
+func (r *DatabaseReconciler) ConstructDatabaseStatefulSet(ctx context.Context, database *libsqlv1.Database, authSecret *corev1.Secret) (*appsv1.StatefulSet, error) {
 	log := log.FromContext(ctx)
 	primaryStatefulSet := &appsv1.StatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
@@ -63,49 +334,45 @@ func (r *DatabaseReconciler) ConstructDatabaseStatefulSet(ctx context.Context, d
 					UID:        database.UID,
 				},
 			},
-			Labels: map[string]string{
-				databaseLabel: database.Name,
-				"node":        "primary",
-			},
+			Labels: r.databaseLabels(database),
 		},
 		Spec: appsv1.StatefulSetSpec{
 			Selector: &metav1.LabelSelector{
-				MatchLabels: map[string]string{
-					databaseLabel: database.Name,
-					"node":        "primary",
-				},
+				MatchLabels: r.databaseSelectorLabels(database),
 			},
-			ServiceName: utils.GetDatabaseServiceName(database, true),
-			Replicas:    ptr.To(int32(1)),
+			ServiceName:     utils.GetDatabaseServiceName(database, true),
+			Replicas:        ptr.To(databasePrimaryReplicas),
+			MinReadySeconds: getDatabaseMinReadySeconds(database),
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{
-						databaseLabel: database.Name,
-						"node":        "primary",
-					},
+					Labels: utils.MergeLabels(database.Spec.PodLabels, databaseIstioPodLabels(database), databaseAntiAffinityPodLabels(database),
+						r.databaseLabels(database)),
 				},
 				Spec: corev1.PodSpec{
-					NodeSelector:                 database.Spec.NodeSelector,
-					ServiceAccountName:           database.Spec.ServiceAccountName,
-					AutomountServiceAccountToken: database.Spec.AutomountServiceAccountToken,
-					ImagePullSecrets:             database.Spec.ImagePullSecrets,
-					Affinity:                     database.Spec.Affinity,
-					SchedulerName:                database.Spec.SchedulerName,
-					Tolerations:                  database.Spec.Tolerations,
+					NodeSelector:                  database.Spec.NodeSelector,
+					ServiceAccountName:            database.Spec.ServiceAccountName,
+					AutomountServiceAccountToken:  database.Spec.AutomountServiceAccountToken,
+					ImagePullSecrets:              r.getDatabaseImagePullSecrets(database),
+					Affinity:                      r.getDatabaseAffinity(database),
+					SchedulerName:                 database.Spec.SchedulerName,
+					Tolerations:                   utils.MergeTolerations(database.Spec.Tolerations, r.DefaultTolerations),
+					RuntimeClassName:              database.Spec.RuntimeClassName,
+					HostAliases:                   database.Spec.HostAliases,
+					TerminationGracePeriodSeconds: getDatabaseTerminationGracePeriodSeconds(database),
 					Containers: []corev1.Container{
 						{
-							Image:           database.Spec.Image,
-							ImagePullPolicy: corev1.PullPolicy(database.Spec.ImagePullPolicy),
-							Name:            "libsql-server",
+							Image:           r.rewriteImage(r.GetDatabaseImage(database)),
+							ImagePullPolicy: getDatabaseImagePullPolicy(database),
+							Name:            utils.GetDatabaseContainerName(database),
 							Resources:       database.Spec.Resource,
 							Ports: []corev1.ContainerPort{
 								{
-									ContainerPort: 8080,
+									ContainerPort: getDatabaseHTTPPort(database),
 									Protocol:      corev1.ProtocolTCP,
 									Name:          "primary-http",
 								},
 								{
-									ContainerPort: 5001,
+									ContainerPort: databaseGRPCPort,
 									Protocol:      corev1.ProtocolTCP,
 									Name:          "primary-grpc",
 								},
@@ -115,27 +382,15 @@ func (r *DatabaseReconciler) ConstructDatabaseStatefulSet(ctx context.Context, d
 									Name:  "SQLD_NODE",
 									Value: "primary",
 								},
-							},
-							LivenessProbe: &corev1.Probe{
-								ProbeHandler: corev1.ProbeHandler{
-									HTTPGet: &corev1.HTTPGetAction{
-										Path: "/health",
-										Port: intstr.IntOrString{
-											IntVal: 8080,
-										},
-									},
-								},
-							},
-							ReadinessProbe: &corev1.Probe{
-								ProbeHandler: corev1.ProbeHandler{
-									HTTPGet: &corev1.HTTPGetAction{
-										Path: "/health",
-										Port: intstr.IntOrString{
-											IntVal: 8080,
-										},
-									},
+								{
+									Name:  "SQLD_HTTP_LISTEN_ADDR",
+									Value: fmt.Sprintf("0.0.0.0:%d", getDatabaseHTTPPort(database)),
 								},
 							},
+							LivenessProbe:  constructDatabaseLivenessProbe(database),
+							StartupProbe:   constructDatabaseStartupProbe(database),
+							ReadinessProbe: constructDatabaseReadinessProbe(database),
+							Lifecycle:      constructDatabaseLifecycle(database),
 							VolumeMounts: []corev1.VolumeMount{
 								{
 									Name:      utils.GetDatabasePVCName(database),
@@ -149,16 +404,17 @@ func (r *DatabaseReconciler) ConstructDatabaseStatefulSet(ctx context.Context, d
 			VolumeClaimTemplates: []corev1.PersistentVolumeClaim{
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: utils.GetDatabasePVCName(database),
-						Labels: map[string]string{
-							databaseLabel: database.Name,
-							"node":        "primary",
-						},
+						Name:        utils.GetDatabasePVCName(database),
+						Labels:      r.databaseLabelsWithOverrides(database, database.Spec.Storage.PVCLabels),
+						Annotations: database.Spec.Storage.PVCAnnotations,
 					},
 					Spec: corev1.PersistentVolumeClaimSpec{
 						AccessModes: []corev1.PersistentVolumeAccessMode{
 							corev1.ReadWriteOnce,
 						},
+						StorageClassName: database.Spec.Storage.StorageClassName,
+						VolumeName:       database.Spec.Storage.VolumeName,
+						Selector:         database.Spec.Storage.Selector,
 						Resources: corev1.VolumeResourceRequirements{
 							Requests: corev1.ResourceList{
 								corev1.ResourceStorage: database.Spec.Storage.Size,
@@ -169,27 +425,265 @@ func (r *DatabaseReconciler) ConstructDatabaseStatefulSet(ctx context.Context, d
 			},
 		},
 	}
+	restoreFromSnapshot := database.Spec.Storage.RestoreFromSnapshot
+	if database.Spec.Storage.CloneFrom != "" {
+		restoreFromSnapshot = utils.GetDatabaseCloneSnapshotName(database)
+	}
+	if restoreFromSnapshot != "" {
+		primaryStatefulSet.Spec.VolumeClaimTemplates[0].Spec.DataSource = &corev1.TypedLocalObjectReference{
+			APIGroup: ptr.To(volumeSnapshotGVK.Group),
+			Kind:     volumeSnapshotGVK.Kind,
+			Name:     restoreFromSnapshot,
+		}
+	}
+	container := utils.GetContainer(&primaryStatefulSet.Spec.Template.Spec, utils.GetDatabaseContainerName(database))
+	if database.Spec.Auth && authSecret != nil {
+		// env values read from a secret are only resolved when a pod starts
+		primaryStatefulSet.Spec.Template.Annotations = map[string]string{
+			databaseAuthKeyHashAnnotation: utils.HashValue(getSecretValue(authSecret, "PUBLIC_KEY")),
+		}
+	}
 	if database.Spec.Auth {
-		primaryStatefulSet.Spec.Template.Spec.Containers[0].Env = append(primaryStatefulSet.Spec.Template.Spec.Containers[0].Env, corev1.EnvVar{
+		container.Env = append(container.Env, corev1.EnvVar{
 			Name: "SQLD_AUTH_JWT_KEY",
 			ValueFrom: &corev1.EnvVarSource{
 				SecretKeyRef: &corev1.SecretKeySelector{
 					LocalObjectReference: corev1.LocalObjectReference{
-						Name: utils.GetAuthSecretName(database),
+						Name: utils.GetAuthPublicKeySecretRef(database),
 					},
 					Key: "PUBLIC_KEY",
 				},
 			},
 		})
 	}
+	reservedEnv := []string{"SQLD_NODE", "SQLD_AUTH_JWT_KEY", "SQLD_HTTP_LISTEN_ADDR"}
+	if database.Spec.MaxConcurrentConnections != nil {
+		reservedEnv = append(reservedEnv, "SQLD_MAX_CONCURRENT_CONNECTIONS")
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  "SQLD_MAX_CONCURRENT_CONNECTIONS",
+			Value: strconv.Itoa(int(*database.Spec.MaxConcurrentConnections)),
+		})
+	}
+	if database.Spec.MaxConcurrentRequests != nil {
+		reservedEnv = append(reservedEnv, "SQLD_MAX_CONCURRENT_REQUESTS")
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  "SQLD_MAX_CONCURRENT_REQUESTS",
+			Value: strconv.Itoa(int(*database.Spec.MaxConcurrentRequests)),
+		})
+	}
 	for _, env := range database.Spec.Env {
-		if !(env.Name == "SQLD_NODE" || env.Name == "SQLD_AUTH_JWT_KEY") {
-			primaryStatefulSet.Spec.Template.Spec.Containers[0].Env = append(primaryStatefulSet.Spec.Template.Spec.Containers[0].Env, env)
+		if !slices.Contains(reservedEnv, env.Name) {
+			container.Env = append(container.Env, env)
 		} else {
 			log.Info(fmt.Sprintf("overwriting provided env %v with default generated values", env.Name))
 		}
 	}
-	return primaryStatefulSet
+	if !slices.ContainsFunc(database.Spec.Env, func(env corev1.EnvVar) bool { return env.Name == "RUST_LOG" }) {
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  "RUST_LOG",
+			Value: string(getDatabaseLogLevel(database)),
+		})
+	}
+	if exporter := getDatabaseMetricsExporter(database); exporter != nil {
+		primaryStatefulSet.Spec.Template.Spec.Containers = append(primaryStatefulSet.Spec.Template.Spec.Containers,
+			r.constructDatabaseMetricsExporterContainer(database, exporter))
+	}
+	if err := applyDatabasePodTemplateOverrides(database, &primaryStatefulSet.Spec.Template); err != nil {
+		return nil, err
+	}
+	return primaryStatefulSet, nil
+}
+
+// getDatabaseImagePullSecrets returns the pull secrets of the Database followed by the operator
+// default pull secrets it does not list already.
+func (r *DatabaseReconciler) getDatabaseImagePullSecrets(database *libsqlv1.Database) []corev1.LocalObjectReference {
+	imagePullSecrets := append([]corev1.LocalObjectReference{}, database.Spec.ImagePullSecrets...)
+	for _, name := range r.DefaultImagePullSecrets {
+		if !slices.Contains(imagePullSecrets, corev1.LocalObjectReference{Name: name}) {
+			imagePullSecrets = append(imagePullSecrets, corev1.LocalObjectReference{Name: name})
+		}
+	}
+	if len(imagePullSecrets) == 0 {
+		return nil
+	}
+	return imagePullSecrets
+}
+
+// getDatabaseLogLevel returns the log level of libsql-server, info by default.
+func getDatabaseLogLevel(database *libsqlv1.Database) libsqlv1.DatabaseLogLevel {
+	if database.Spec.LogLevel == "" {
+		return libsqlv1.DatabaseLogLevelInfo
+	}
+	return database.Spec.LogLevel
+}
+
+// getDatabaseImagePullPolicy returns the pull policy of the database container, IfNotPresent by default.
+func getDatabaseImagePullPolicy(database *libsqlv1.Database) corev1.PullPolicy {
+	if database.Spec.ImagePullPolicy == "" {
+		return corev1.PullIfNotPresent
+	}
+	return database.Spec.ImagePullPolicy
+}
+
+// GetDatabaseImage returns the image of the Database, falling back to the operator default image.
+func (r *DatabaseReconciler) GetDatabaseImage(database *libsqlv1.Database) string {
+	if database.Spec.Image != "" {
+		return database.Spec.Image
+	}
+	return r.DefaultImage
+}
+
+// rewriteImage applies the registry rewrites of the operator to image.
+func (r *DatabaseReconciler) rewriteImage(image string) string {
+	return utils.RewriteImageRegistry(image, r.ImageRegistryRewrites)
+}
+
+// constructDatabaseLivenessProbe returns nil when the liveness probe is disabled.
+func constructDatabaseLivenessProbe(database *libsqlv1.Database) *corev1.Probe {
+	if database.Spec.Probe != nil && database.Spec.Probe.DisableLiveness {
+		return nil
+	}
+	probe := &corev1.Probe{
+		ProbeHandler: constructDatabaseProbeHandler(database),
+	}
+	if database.Spec.Probe != nil && database.Spec.Probe.SlowStart {
+		probe.PeriodSeconds = slowStartLivenessPeriodSeconds
+		probe.FailureThreshold = slowStartLivenessFailureThreshold
+	}
+	return probe
+}
+
+// constructDatabaseReadinessProbe checks the gRPC port when spec.probe.grpcReadiness is set.
+func constructDatabaseReadinessProbe(database *libsqlv1.Database) *corev1.Probe {
+	if database.Spec.Probe != nil && database.Spec.Probe.GRPCReadiness {
+		return &corev1.Probe{
+			ProbeHandler: corev1.ProbeHandler{
+				GRPC: &corev1.GRPCAction{Port: databaseGRPCPort},
+			},
+		}
+	}
+	return &corev1.Probe{
+		ProbeHandler: constructDatabaseProbeHandler(database),
+	}
+}
+
+// constructDatabaseStartupProbe returns a startup probe covering spec.probe.startupTimeoutSeconds,
+// or the slow start timeout, and nil when neither is configured.
+func constructDatabaseStartupProbe(database *libsqlv1.Database) *corev1.Probe {
+	if database.Spec.Probe == nil {
+		return nil
+	}
+	startupTimeoutSeconds := database.Spec.Probe.StartupTimeoutSeconds
+	if startupTimeoutSeconds == nil && database.Spec.Probe.SlowStart {
+		startupTimeoutSeconds = ptr.To(slowStartTimeoutSeconds)
+	}
+	if startupTimeoutSeconds == nil {
+		return nil
+	}
+	periodSeconds := databaseStartupProbePeriodSeconds
+	return &corev1.Probe{
+		ProbeHandler:     constructDatabaseProbeHandler(database),
+		PeriodSeconds:    periodSeconds,
+		FailureThreshold: (*startupTimeoutSeconds + periodSeconds - 1) / periodSeconds,
+	}
+}
+
+// getDatabaseHTTPPort returns the port libsql-server serves HTTP on, 8080 by default.
+func getDatabaseHTTPPort(database *libsqlv1.Database) int32 {
+	if database.Spec.HTTPPort == 0 {
+		return databaseHTTPPort
+	}
+	return database.Spec.HTTPPort
+}
+
+// getDatabaseProbePort returns the port checked by the HTTP and TCP probes, which follows the
+// HTTP port unless spec.probe.port overrides it.
+func getDatabaseProbePort(database *libsqlv1.Database) int32 {
+	if database.Spec.Probe != nil && database.Spec.Probe.Port != nil {
+		return *database.Spec.Probe.Port
+	}
+	return getDatabaseHTTPPort(database)
+}
+
+// getDatabaseDrainPeriodSeconds returns the drain period of spec.drain, 0 without draining.
+func getDatabaseDrainPeriodSeconds(database *libsqlv1.Database) int32 {
+	if database.Spec.Drain == nil {
+		return 0
+	}
+	if database.Spec.Drain.PeriodSeconds == 0 {
+		return defaultDatabaseDrainPeriodSeconds
+	}
+	return database.Spec.Drain.PeriodSeconds
+}
+
+// getDatabaseTerminationGracePeriodSeconds returns spec.terminationGracePeriodSeconds, or the
+// default grace period after the drain period. Nil leaves the Kubernetes default.
+func getDatabaseTerminationGracePeriodSeconds(database *libsqlv1.Database) *int64 {
+	if database.Spec.TerminationGracePeriodSeconds != nil {
+		return database.Spec.TerminationGracePeriodSeconds
+	}
+	if database.Spec.Drain == nil {
+		return nil
+	}
+	return ptr.To(int64(getDatabaseDrainPeriodSeconds(database)) + defaultDatabaseTerminationGracePeriodSeconds)
+}
+
+// constructDatabaseLifecycle returns the preStop hook waiting for the drain period, nil without
+// spec.drain. The pod is out of the Service endpoints while it waits.
+func constructDatabaseLifecycle(database *libsqlv1.Database) *corev1.Lifecycle {
+	if database.Spec.Drain == nil {
+		return nil
+	}
+	return &corev1.Lifecycle{
+		PreStop: &corev1.LifecycleHandler{
+			Exec: &corev1.ExecAction{
+				Command: []string{"sleep", strconv.Itoa(int(getDatabaseDrainPeriodSeconds(database)))},
+			},
+		},
+	}
+}
+
+// getDatabaseMinReadySeconds returns spec.minReadySeconds, or the slow start default.
+func getDatabaseMinReadySeconds(database *libsqlv1.Database) int32 {
+	if database.Spec.MinReadySeconds == 0 && database.Spec.Probe != nil && database.Spec.Probe.SlowStart {
+		return slowStartMinReadySeconds
+	}
+	return database.Spec.MinReadySeconds
+}
+
+// constructDatabaseProbeHandler builds the probe handler selected by the Database probe type,
+// falling back to an HTTP GET on /health when no probe is configured.
+func constructDatabaseProbeHandler(database *libsqlv1.Database) corev1.ProbeHandler {
+	probeType := libsqlv1.DatabaseProbeTypeHTTP
+	if database.Spec.Probe != nil && database.Spec.Probe.Type != "" {
+		probeType = database.Spec.Probe.Type
+	}
+	switch probeType {
+	case libsqlv1.DatabaseProbeTypeTCP:
+		return corev1.ProbeHandler{
+			TCPSocket: &corev1.TCPSocketAction{
+				Port: intstr.FromInt32(getDatabaseProbePort(database)),
+			},
+		}
+	case libsqlv1.DatabaseProbeTypeExec:
+		return corev1.ProbeHandler{
+			Exec: &corev1.ExecAction{
+				Command: database.Spec.Probe.Command,
+			},
+		}
+	default:
+		scheme := corev1.URISchemeHTTP
+		if database.Spec.Probe != nil && database.Spec.Probe.Scheme != "" {
+			scheme = database.Spec.Probe.Scheme
+		}
+		return corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Scheme: scheme,
+				Path:   "/health",
+				Port:   intstr.FromInt32(getDatabaseProbePort(database)),
+			},
+		}
+	}
 }
 
 func (r *DatabaseReconciler) MapDatabaseStatefulSetsToReconcile(ctx context.Context, object client.Object) []reconcile.Request {
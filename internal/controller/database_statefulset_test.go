@@ -0,0 +1,471 @@
+package controller
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+)
+
+var _ = Describe("Database StatefulSet", func() {
+	constructContainer := func(spec libsqlv1.DatabaseSpec) *corev1.Container {
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "default"},
+			Spec:       spec,
+		}
+		database.Spec.Image = "ghcr.io/tursodatabase/libsql-server:v0.24.21"
+		database.Spec.Storage = libsqlv1.DatabaseStorage{Size: resource.MustParse("1Gi")}
+		statefulSet, err := (&DatabaseReconciler{}).ConstructDatabaseStatefulSet(context.Background(), database, nil)
+		Expect(err).NotTo(HaveOccurred())
+		return utils.GetContainer(&statefulSet.Spec.Template.Spec, utils.GetDatabaseContainerName(database))
+	}
+
+	It("should probe the default HTTP port", func() {
+		container := constructContainer(libsqlv1.DatabaseSpec{})
+		Expect(container.Ports[0].ContainerPort).Should(Equal(int32(8080)))
+		Expect(container.ReadinessProbe.HTTPGet.Port).Should(Equal(intstr.FromInt32(8080)))
+		Expect(container.LivenessProbe.HTTPGet.Port).Should(Equal(intstr.FromInt32(8080)))
+	})
+
+	It("should move the probes with the HTTP port", func() {
+		container := constructContainer(libsqlv1.DatabaseSpec{HTTPPort: 9090})
+		Expect(container.Ports[0].ContainerPort).Should(Equal(int32(9090)))
+		Expect(container.Env).Should(ContainElement(corev1.EnvVar{Name: "SQLD_HTTP_LISTEN_ADDR", Value: "0.0.0.0:9090"}))
+		Expect(container.ReadinessProbe.HTTPGet.Port).Should(Equal(intstr.FromInt32(9090)))
+		Expect(container.LivenessProbe.HTTPGet.Port).Should(Equal(intstr.FromInt32(9090)))
+
+		container = constructContainer(libsqlv1.DatabaseSpec{
+			HTTPPort: 9090,
+			Probe:    &libsqlv1.DatabaseProbe{Type: libsqlv1.DatabaseProbeTypeTCP},
+		})
+		Expect(container.ReadinessProbe.TCPSocket.Port).Should(Equal(intstr.FromInt32(9090)))
+	})
+
+	It("should probe the port of spec.probe.port", func() {
+		container := constructContainer(libsqlv1.DatabaseSpec{
+			HTTPPort: 9090,
+			Probe:    &libsqlv1.DatabaseProbe{Port: ptr.To(int32(9000))},
+		})
+		Expect(container.Ports[0].ContainerPort).Should(Equal(int32(9090)))
+		Expect(container.ReadinessProbe.HTTPGet.Port).Should(Equal(intstr.FromInt32(9000)))
+	})
+
+	It("should check the gRPC port for readiness with spec.probe.grpcReadiness", func() {
+		container := constructContainer(libsqlv1.DatabaseSpec{
+			Probe: &libsqlv1.DatabaseProbe{GRPCReadiness: true},
+		})
+		Expect(container.ReadinessProbe.GRPC).ShouldNot(BeNil())
+		Expect(container.ReadinessProbe.GRPC.Port).Should(Equal(int32(5001)))
+		Expect(container.ReadinessProbe.HTTPGet).Should(BeNil())
+		Expect(container.LivenessProbe.HTTPGet.Port).Should(Equal(intstr.FromInt32(8080)))
+	})
+
+	It("should drain connections before the database is stopped with spec.drain", func() {
+		container := constructContainer(libsqlv1.DatabaseSpec{})
+		Expect(container.Lifecycle).Should(BeNil())
+
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "default"},
+			Spec: libsqlv1.DatabaseSpec{
+				Image:   "ghcr.io/tursodatabase/libsql-server:v0.24.21",
+				Storage: libsqlv1.DatabaseStorage{Size: resource.MustParse("1Gi")},
+				Drain:   &libsqlv1.DatabaseDrain{},
+			},
+		}
+		statefulSet, err := (&DatabaseReconciler{}).ConstructDatabaseStatefulSet(context.Background(), database, nil)
+		Expect(err).NotTo(HaveOccurred())
+		container = utils.GetContainer(&statefulSet.Spec.Template.Spec, utils.GetDatabaseContainerName(database))
+		Expect(container.Lifecycle.PreStop.Exec.Command).Should(Equal([]string{"sleep", "15"}))
+		Expect(statefulSet.Spec.Template.Spec.TerminationGracePeriodSeconds).Should(Equal(ptr.To(int64(45))))
+
+		database.Spec.Drain.PeriodSeconds = 60
+		database.Spec.TerminationGracePeriodSeconds = ptr.To(int64(120))
+		statefulSet, err = (&DatabaseReconciler{}).ConstructDatabaseStatefulSet(context.Background(), database, nil)
+		Expect(err).NotTo(HaveOccurred())
+		container = utils.GetContainer(&statefulSet.Spec.Template.Spec, utils.GetDatabaseContainerName(database))
+		Expect(container.Lifecycle.PreStop.Exec.Command).Should(Equal([]string{"sleep", "60"}))
+		Expect(statefulSet.Spec.Template.Spec.TerminationGracePeriodSeconds).Should(Equal(ptr.To(int64(120))))
+	})
+
+	It("should pass spec.maxConcurrentConnections to sqld", func() {
+		container := constructContainer(libsqlv1.DatabaseSpec{})
+		Expect(container.Env).ShouldNot(ContainElement(HaveField("Name", "SQLD_MAX_CONCURRENT_CONNECTIONS")))
+
+		container = constructContainer(libsqlv1.DatabaseSpec{
+			MaxConcurrentConnections: ptr.To(int32(512)),
+			Env:                      []corev1.EnvVar{{Name: "SQLD_MAX_CONCURRENT_CONNECTIONS", Value: "64"}},
+		})
+		Expect(container.Env).Should(ContainElement(corev1.EnvVar{Name: "SQLD_MAX_CONCURRENT_CONNECTIONS", Value: "512"}))
+		Expect(container.Env).ShouldNot(ContainElement(corev1.EnvVar{Name: "SQLD_MAX_CONCURRENT_CONNECTIONS", Value: "64"}))
+	})
+
+	It("should add the metrics exporter sidecar and its Service port", func() {
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "default"},
+			Spec: libsqlv1.DatabaseSpec{
+				Image:   "ghcr.io/tursodatabase/libsql-server:v0.24.21",
+				Storage: libsqlv1.DatabaseStorage{Size: resource.MustParse("1Gi")},
+				Monitoring: &libsqlv1.DatabaseMonitoring{
+					MetricsExporter: &libsqlv1.DatabaseMetricsExporter{Image: "registry.example.com/sqld-exporter:v1"},
+				},
+			},
+		}
+		reconciler := &DatabaseReconciler{}
+		statefulSet, err := reconciler.ConstructDatabaseStatefulSet(context.Background(), database, nil)
+		Expect(err).NotTo(HaveOccurred())
+		exporter := utils.GetContainer(&statefulSet.Spec.Template.Spec, databaseMetricsExporterContainerName)
+		Expect(exporter).NotTo(BeNil())
+		Expect(exporter.Image).Should(Equal("registry.example.com/sqld-exporter:v1"))
+		Expect(exporter.Ports).Should(ConsistOf(HaveField("ContainerPort", int32(9091))))
+		Expect(exporter.Env).Should(ContainElement(corev1.EnvVar{Name: "SQLD_URL", Value: "http://127.0.0.1:8080"}))
+
+		Expect(reconciler.ConstructDatabaseService(context.Background(), database, false).Spec.Ports).
+			Should(ContainElement(HaveField("Name", "metrics")))
+		Expect(reconciler.ConstructDatabaseService(context.Background(), database, true).Spec.Ports).
+			ShouldNot(ContainElement(HaveField("Name", "metrics")))
+	})
+
+	It("should add spec.podLabels to the pods but not to the selector", func() {
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "default"},
+			Spec: libsqlv1.DatabaseSpec{
+				Image:     "ghcr.io/tursodatabase/libsql-server:v0.24.21",
+				Storage:   libsqlv1.DatabaseStorage{Size: resource.MustParse("1Gi")},
+				PodLabels: map[string]string{"version": "v2"},
+			},
+		}
+		statefulSet, err := (&DatabaseReconciler{}).ConstructDatabaseStatefulSet(context.Background(), database, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statefulSet.Spec.Template.Labels).Should(HaveKeyWithValue("version", "v2"))
+		Expect(statefulSet.Spec.Selector.MatchLabels).ShouldNot(HaveKey("version"))
+		Expect(statefulSet.Labels).ShouldNot(HaveKey("version"))
+	})
+
+	It("should pass spec.maxConcurrentRequests to sqld", func() {
+		container := constructContainer(libsqlv1.DatabaseSpec{})
+		Expect(container.Env).ShouldNot(ContainElement(HaveField("Name", "SQLD_MAX_CONCURRENT_REQUESTS")))
+
+		container = constructContainer(libsqlv1.DatabaseSpec{
+			MaxConcurrentRequests: ptr.To(int32(32)),
+			Env:                   []corev1.EnvVar{{Name: "SQLD_MAX_CONCURRENT_REQUESTS", Value: "1024"}},
+		})
+		Expect(container.Env).Should(ContainElement(corev1.EnvVar{Name: "SQLD_MAX_CONCURRENT_REQUESTS", Value: "32"}))
+		Expect(container.Env).ShouldNot(ContainElement(corev1.EnvVar{Name: "SQLD_MAX_CONCURRENT_REQUESTS", Value: "1024"}))
+	})
+
+	It("should merge the default tolerations of the operator", func() {
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "default"},
+			Spec: libsqlv1.DatabaseSpec{
+				Image:   "ghcr.io/tursodatabase/libsql-server:v0.24.21",
+				Storage: libsqlv1.DatabaseStorage{Size: resource.MustParse("1Gi")},
+				Tolerations: []corev1.Toleration{
+					{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "analytics", Effect: corev1.TaintEffectNoSchedule},
+				},
+			},
+		}
+		reconciler := &DatabaseReconciler{DefaultTolerations: []corev1.Toleration{
+			{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "databases", Effect: corev1.TaintEffectNoSchedule},
+			{Key: "spot", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute},
+		}}
+		statefulSet, err := reconciler.ConstructDatabaseStatefulSet(context.Background(), database, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statefulSet.Spec.Template.Spec.Tolerations).Should(Equal([]corev1.Toleration{
+			database.Spec.Tolerations[0],
+			reconciler.DefaultTolerations[1],
+		}))
+	})
+
+	It("should label the resources with the labels mapped from the namespace", func() {
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "team-storage"},
+			Spec: libsqlv1.DatabaseSpec{
+				Image: "ghcr.io/tursodatabase/libsql-server:v0.24.21",
+				Storage: libsqlv1.DatabaseStorage{
+					Size:      resource.MustParse("1Gi"),
+					PVCLabels: map[string]string{"billing.ahti.io/cost-center": "shared-storage"},
+				},
+			},
+		}
+		reconciler := &DatabaseReconciler{NamespaceLabelMappings: map[string]string{
+			"team":        "ahti.io/team",
+			"cost-center": "billing.ahti.io/cost-center",
+			"instance":    "app.kubernetes.io/instance",
+		}}
+		reconciler.setNamespaceResourceLabels(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:   database.Namespace,
+			Labels: map[string]string{"team": "storage", "cost-center": "cc-42", "instance": "production"},
+		}})
+		statefulSet, err := reconciler.ConstructDatabaseStatefulSet(context.Background(), database, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statefulSet.Labels).Should(HaveKeyWithValue("ahti.io/team", "storage"))
+		Expect(statefulSet.Labels).Should(HaveKeyWithValue("billing.ahti.io/cost-center", "cc-42"))
+		Expect(statefulSet.Spec.Template.Labels).Should(HaveKeyWithValue("ahti.io/team", "storage"))
+		By("Keeping the labels of the operator and of the Database spec over the mapped ones")
+		Expect(statefulSet.Labels).Should(HaveKeyWithValue("app.kubernetes.io/instance", database.Name))
+		pvcLabels := statefulSet.Spec.VolumeClaimTemplates[0].Labels
+		Expect(pvcLabels).Should(HaveKeyWithValue("billing.ahti.io/cost-center", "shared-storage"))
+		Expect(pvcLabels).Should(HaveKeyWithValue("ahti.io/team", "storage"))
+	})
+	It("should keep the pods apart from the other databases of the tenant", func() {
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "default", Labels: map[string]string{"ahti.io/tenant": "acme"}},
+			Spec: libsqlv1.DatabaseSpec{
+				Image:   "ghcr.io/tursodatabase/libsql-server:v0.24.21",
+				Storage: libsqlv1.DatabaseStorage{Size: resource.MustParse("1Gi")},
+				Affinity: &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{
+					PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{{
+						Weight:          10,
+						PodAffinityTerm: corev1.PodAffinityTerm{TopologyKey: corev1.LabelTopologyZone},
+					}},
+				}},
+				PodAntiAffinity: &libsqlv1.DatabasePodAntiAffinity{
+					Scope:       libsqlv1.DatabaseAntiAffinityScopeTenant,
+					TenantLabel: "ahti.io/tenant",
+					Required:    true,
+				},
+			},
+		}
+		reconciler := &DatabaseReconciler{}
+		Expect(reconciler.ValidateDatabase(database)).To(Succeed())
+		statefulSet, err := reconciler.ConstructDatabaseStatefulSet(context.Background(), database, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statefulSet.Spec.Template.Labels).Should(HaveKeyWithValue("ahti.io/tenant", "acme"))
+		antiAffinity := statefulSet.Spec.Template.Spec.Affinity.PodAntiAffinity
+		Expect(antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution).Should(HaveLen(1))
+		Expect(antiAffinity.RequiredDuringSchedulingIgnoredDuringExecution).Should(ConsistOf(corev1.PodAffinityTerm{
+			LabelSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"node": "primary", "ahti.io/tenant": "acme"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: reconciler.GetManagedByLabel(), Operator: metav1.LabelSelectorOpExists},
+				},
+			},
+			NamespaceSelector: &metav1.LabelSelector{},
+			TopologyKey:       corev1.LabelHostname,
+		}))
+		Expect(database.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution).Should(BeEmpty())
+
+		By("Rejecting the Tenant scope for a Database without the tenant label")
+		database.Labels = nil
+		Expect(reconciler.ValidateDatabase(database)).NotTo(Succeed())
+	})
+	It("should keep the fields set by others when the StatefulSet is patched", func() {
+		ctx := context.Background()
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "patched-database", Namespace: "default"},
+			Spec: libsqlv1.DatabaseSpec{
+				Image:   "ghcr.io/tursodatabase/libsql-server:v0.24.21",
+				Storage: libsqlv1.DatabaseStorage{Size: resource.MustParse("1Gi")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, database)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, database)
+		reconciler := &DatabaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: MockEventRecorder{}}
+		statefulSet, err := reconciler.ReconcileDatabaseStatefulSets(ctx, database, nil)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(k8sClient.Delete, ctx, statefulSet)
+
+		By("Setting fields of the StatefulSet as other controllers and kubectl rollout restart do")
+		metav1.SetMetaDataAnnotation(&statefulSet.ObjectMeta, "argocd.argoproj.io/sync-wave", "1")
+		metav1.SetMetaDataAnnotation(&statefulSet.Spec.Template.ObjectMeta, restartedAtAnnotation, "2024-05-01T10:00:00Z")
+		metav1.SetMetaDataAnnotation(&statefulSet.Spec.Template.ObjectMeta, "cluster-autoscaler.kubernetes.io/safe-to-evict", "false")
+		metav1.SetMetaDataLabel(&statefulSet.ObjectMeta, "argocd.argoproj.io/instance", "databases")
+		Expect(k8sClient.Update(ctx, statefulSet)).To(Succeed())
+
+		database.Spec.LogLevel = "debug"
+		_, err = reconciler.ReconcileDatabaseStatefulSets(ctx, database, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		found := &appsv1.StatefulSet{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: database.Name, Namespace: database.Namespace}, found)).To(Succeed())
+		container := utils.GetContainer(&found.Spec.Template.Spec, utils.GetDatabaseContainerName(database))
+		Expect(container.Env).Should(ContainElement(corev1.EnvVar{Name: "RUST_LOG", Value: "debug"}))
+		Expect(found.Annotations).Should(HaveKeyWithValue("argocd.argoproj.io/sync-wave", "1"))
+		Expect(found.Spec.Template.Annotations).Should(HaveKeyWithValue(restartedAtAnnotation, "2024-05-01T10:00:00Z"))
+		Expect(found.Spec.Template.Annotations).Should(HaveKeyWithValue("cluster-autoscaler.kubernetes.io/safe-to-evict", "false"))
+		Expect(found.Labels).Should(HaveKeyWithValue("argocd.argoproj.io/instance", "databases"))
+	})
+	It("should revert changes made to the StatefulSet directly", func() {
+		ctx := context.Background()
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "drifted-database", Namespace: "default"},
+			Spec: libsqlv1.DatabaseSpec{
+				Image:   "ghcr.io/tursodatabase/libsql-server:v0.24.21",
+				Storage: libsqlv1.DatabaseStorage{Size: resource.MustParse("1Gi")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, database)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, database)
+		reconciler := &DatabaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: MockEventRecorder{}}
+		statefulSet, err := reconciler.ReconcileDatabaseStatefulSets(ctx, database, nil)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(k8sClient.Delete, ctx, statefulSet)
+
+		By("Leaving the StatefulSet alone while it is in sync")
+		found := &appsv1.StatefulSet{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: database.Name, Namespace: database.Namespace}, found)).To(Succeed())
+		resourceVersion := found.ResourceVersion
+		_, err = reconciler.ReconcileDatabaseStatefulSets(ctx, database, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: database.Name, Namespace: database.Namespace}, found)).To(Succeed())
+		Expect(found.ResourceVersion).Should(Equal(resourceVersion))
+
+		By("Editing the image of the database container")
+		container := utils.GetContainer(&found.Spec.Template.Spec, utils.GetDatabaseContainerName(database))
+		container.Image = "ghcr.io/tursodatabase/libsql-server:latest"
+		Expect(k8sClient.Update(ctx, found)).To(Succeed())
+		_, err = reconciler.ReconcileDatabaseStatefulSets(ctx, database, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: database.Name, Namespace: database.Namespace}, found)).To(Succeed())
+		container = utils.GetContainer(&found.Spec.Template.Spec, utils.GetDatabaseContainerName(database))
+		Expect(container.Image).Should(Equal("ghcr.io/tursodatabase/libsql-server:v0.24.21"))
+	})
+
+	It("should keep the selector of a StatefulSet created under another managed-by label key", func() {
+		ctx := context.Background()
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "relabeled-database", Namespace: "default"},
+			Spec: libsqlv1.DatabaseSpec{
+				Image:   "ghcr.io/tursodatabase/libsql-server:v0.24.21",
+				Storage: libsqlv1.DatabaseStorage{Size: resource.MustParse("1Gi")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, database)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, database)
+		reconciler := &DatabaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: MockEventRecorder{}}
+		statefulSet, err := reconciler.ReconcileDatabaseStatefulSets(ctx, database, nil)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(k8sClient.Delete, ctx, statefulSet)
+		selector := statefulSet.Spec.Selector.DeepCopy()
+		pvc := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "data-relabeled-database-0", Namespace: database.Namespace,
+				Labels: selector.MatchLabels},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, pvc)).To(Succeed())
+
+		By("Reconciling with another managed-by label key")
+		relabeled := &DatabaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: MockEventRecorder{},
+			ManagedByLabel: "example.com/database"}
+		found, err := relabeled.ReconcileDatabaseStatefulSets(ctx, database, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found.Spec.Selector).Should(Equal(selector))
+		Expect(found.Spec.Template.Labels).Should(HaveKeyWithValue("example.com/database", database.Name))
+		for key, value := range selector.MatchLabels {
+			Expect(found.Spec.Template.Labels).Should(HaveKeyWithValue(key, value))
+		}
+
+		By("Finding the data volumes by the selector of the StatefulSet")
+		Expect(relabeled.DeleteDatabasePVC(ctx, database)).To(Succeed())
+		err = k8sClient.Get(ctx, types.NamespacedName{Name: pvc.Name, Namespace: pvc.Namespace}, pvc)
+		Expect(err == nil && pvc.DeletionTimestamp != nil || apierrors.IsNotFound(err)).Should(BeTrue())
+	})
+
+	It("should roll the pods when a ConfigMap read by spec.env changes", func() {
+		ctx := context.Background()
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "database-tuning", Namespace: "default"},
+			Data:       map[string]string{"max-connections": "64", "unrelated": "1"},
+		}
+		Expect(k8sClient.Create(ctx, configMap)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, configMap)
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "configured-database", Namespace: "default"},
+			Spec: libsqlv1.DatabaseSpec{
+				Image:   "ghcr.io/tursodatabase/libsql-server:v0.24.21",
+				Storage: libsqlv1.DatabaseStorage{Size: resource.MustParse("1Gi")},
+				Env: []corev1.EnvVar{{
+					Name: "SQLD_MAX_CONCURRENT_CONNECTIONS",
+					ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: configMap.Name},
+						Key:                  "max-connections",
+					}},
+				}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, database)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, database)
+		Expect(IndexDatabaseConfigRefs(database)).Should(Equal([]string{"ConfigMap/database-tuning"}))
+		reconciler := &DatabaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: MockEventRecorder{}}
+		statefulSet, err := reconciler.ReconcileDatabaseStatefulSets(ctx, database, nil)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(k8sClient.Delete, ctx, statefulSet)
+		configHash := statefulSet.Spec.Template.Annotations[databaseConfigHashAnnotation]
+		Expect(configHash).NotTo(BeEmpty())
+
+		By("Changing a key the Database does not read, the pods are kept")
+		configMap.Data["unrelated"] = "2"
+		Expect(k8sClient.Update(ctx, configMap)).To(Succeed())
+		statefulSet, err = reconciler.ReconcileDatabaseStatefulSets(ctx, database, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statefulSet.Spec.Template.Annotations).Should(HaveKeyWithValue(databaseConfigHashAnnotation, configHash))
+
+		By("Changing the key the Database reads, the pods are rolled")
+		configMap.Data["max-connections"] = "128"
+		Expect(k8sClient.Update(ctx, configMap)).To(Succeed())
+		statefulSet, err = reconciler.ReconcileDatabaseStatefulSets(ctx, database, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statefulSet.Spec.Template.Annotations[databaseConfigHashAnnotation]).ShouldNot(Equal(configHash))
+	})
+	It("should mark the Database Degraded instead of updating a StatefulSet of another controller", func() {
+		ctx := context.Background()
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "contested-database", Namespace: "default"},
+			Spec: libsqlv1.DatabaseSpec{
+				Image:   "ghcr.io/tursodatabase/libsql-server:v0.24.21",
+				Storage: libsqlv1.DatabaseStorage{Size: resource.MustParse("1Gi")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, database)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, database)
+		reconciler := &DatabaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: MockEventRecorder{}}
+		statefulSet, err := reconciler.ReconcileDatabaseStatefulSets(ctx, database, nil)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(k8sClient.Delete, ctx, statefulSet)
+
+		By("Handing the StatefulSet to another controller")
+		foreignController := metav1.OwnerReference{APIVersion: "apps.example.com/v1", Kind: "DatabaseCluster",
+			Name: "legacy", UID: types.UID("d0e8a3b2-1c4f-4d7e-9a5b-2f6c8e1d3a70"), Controller: ptr.To(true)}
+		statefulSet.OwnerReferences = append(statefulSet.OwnerReferences, foreignController)
+		Expect(k8sClient.Update(ctx, statefulSet)).To(Succeed())
+
+		database.Spec.LogLevel = "debug"
+		_, err = reconciler.ReconcileDatabaseStatefulSets(ctx, database, nil)
+		Expect(err).To(HaveOccurred())
+		reconcileErr := &ReconcileError{}
+		Expect(errors.As(err, &reconcileErr)).Should(BeTrue())
+		Expect(reconcileErr.Reason).Should(Equal(reasonForeignController))
+		Expect(meta.IsStatusConditionTrue(database.Status.Conditions, typeDegradedDatabase)).Should(BeTrue())
+		found := &appsv1.StatefulSet{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: database.Name, Namespace: database.Namespace}, found)).To(Succeed())
+		container := utils.GetContainer(&found.Spec.Template.Spec, utils.GetDatabaseContainerName(database))
+		Expect(container.Env).ShouldNot(ContainElement(corev1.EnvVar{Name: "RUST_LOG", Value: "debug"}))
+
+		By("Removing the controller reference, the StatefulSet is updated again")
+		found.OwnerReferences = found.OwnerReferences[:1]
+		Expect(k8sClient.Update(ctx, found)).To(Succeed())
+		statefulSet, err = reconciler.ReconcileDatabaseStatefulSets(ctx, database, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(meta.FindStatusCondition(database.Status.Conditions, typeDegradedDatabase).Reason).Should(Equal(reasonControllerOwned))
+		container = utils.GetContainer(&statefulSet.Spec.Template.Spec, utils.GetDatabaseContainerName(database))
+		Expect(container.Env).Should(ContainElement(corev1.EnvVar{Name: "RUST_LOG", Value: "debug"}))
+	})
+})
@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// typeStoragePressureDatabase is True while the data volume is fuller than spec.storage.pressureThreshold
+	typeStoragePressureDatabase = "StoragePressure"
+	reasonStorageUsageHigh      = "StorageUsageHigh"
+	reasonStorageUsageNormal    = "StorageUsageNormal"
+	// storagePressureCheckInterval is how often the usage of the data volume is read
+	storagePressureCheckInterval = 5 * time.Minute
+)
+
+// VolumeUsage is the space used on a volume and its capacity, in bytes.
+type VolumeUsage struct {
+	UsedBytes     int64
+	CapacityBytes int64
+}
+
+// VolumeStatsReader reads the usage of the volumes mounted by a pod.
+type VolumeStatsReader interface {
+	// GetPVCUsage returns the usage of the PVC mounted by the pod, or nil when it is not known yet.
+	GetPVCUsage(ctx context.Context, pod *corev1.Pod, pvcName string) (*VolumeUsage, error)
+}
+
+// KubeletVolumeStatsReader reads the volume stats the kubelet of the node of a pod reports on its
+// summary API, through the node proxy of the API server.
+type KubeletVolumeStatsReader struct {
+	// Client is a REST client of the core API group.
+	Client rest.Interface
+}
+
+// kubeletStatsSummary holds the fields of the kubelet summary API the volume usage is read from.
+type kubeletStatsSummary struct {
+	Pods []struct {
+		PodRef struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"podRef"`
+		Volumes []struct {
+			PVCRef *struct {
+				Name string `json:"name"`
+			} `json:"pvcRef,omitempty"`
+			UsedBytes     *int64 `json:"usedBytes,omitempty"`
+			CapacityBytes *int64 `json:"capacityBytes,omitempty"`
+		} `json:"volume,omitempty"`
+	} `json:"pods"`
+}
+
+func (reader *KubeletVolumeStatsReader) GetPVCUsage(ctx context.Context, pod *corev1.Pod, pvcName string) (*VolumeUsage, error) {
+	body, err := reader.Client.Get().AbsPath("/api/v1/nodes", pod.Spec.NodeName, "proxy", "stats", "summary").DoRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	summary := kubeletStatsSummary{}
+	if err := json.Unmarshal(body, &summary); err != nil {
+		return nil, err
+	}
+	for _, podStats := range summary.Pods {
+		if podStats.PodRef.Name != pod.Name || podStats.PodRef.Namespace != pod.Namespace {
+			continue
+		}
+		for _, volume := range podStats.Volumes {
+			if volume.PVCRef != nil && volume.PVCRef.Name == pvcName && volume.UsedBytes != nil && volume.CapacityBytes != nil {
+				return &VolumeUsage{UsedBytes: *volume.UsedBytes, CapacityBytes: *volume.CapacityBytes}, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// ReconcileDatabaseStoragePressure sets the StoragePressure condition from the usage of the data
+// volume when spec.storage.pressureThreshold is set, so the volume can be expanded before sqld
+// runs out of space. It returns when to read the usage again. The usage is read from the kubelet
+// at most once per storagePressureCheckInterval, as recorded in status.storageUsageCheckTime.
+// Failing to read it is not an error of the Database, the condition is kept until the next check.
+func (r *DatabaseReconciler) ReconcileDatabaseStoragePressure(ctx context.Context, database *libsqlv1.Database) (time.Duration, error) {
+	log := log.FromContext(ctx)
+	threshold := database.Spec.Storage.PressureThreshold
+	if threshold == nil || r.VolumeStats == nil {
+		meta.RemoveStatusCondition(&database.Status.Conditions, typeStoragePressureDatabase)
+		database.Status.StorageUsageCheckTime = nil
+		return 0, nil
+	}
+	if checkTime := database.Status.StorageUsageCheckTime; checkTime != nil {
+		if elapsed := time.Since(checkTime.Time); elapsed >= 0 && elapsed < storagePressureCheckInterval {
+			return storagePressureCheckInterval - elapsed, nil
+		}
+	}
+	pod := &corev1.Pod{}
+	if err := r.Get(ctx, types.NamespacedName{Name: utils.GetDatabasePodName(database, 0), Namespace: database.Namespace}, pod); err != nil {
+		if apierrors.IsNotFound(err) {
+			return storagePressureCheckInterval, nil
+		}
+		return 0, err
+	}
+	if pod.Spec.NodeName == "" {
+		return storagePressureCheckInterval, nil
+	}
+	pvcName := utils.GetDatabaseStatefulSetPVCName(database, 0)
+	now := metav1.Now()
+	database.Status.StorageUsageCheckTime = &now
+	usage, err := r.VolumeStats.GetPVCUsage(ctx, pod, pvcName)
+	if err != nil {
+		log.Error(err, "Failed to read the usage of the data volume", "PVC.Name", pvcName)
+		return storagePressureCheckInterval, nil
+	}
+	if usage == nil || usage.CapacityBytes <= 0 {
+		return storagePressureCheckInterval, nil
+	}
+
+	percent := usage.UsedBytes * 100 / usage.CapacityBytes
+	if percent < int64(*threshold) {
+		meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{Type: typeStoragePressureDatabase,
+			Status: metav1.ConditionFalse, Reason: reasonStorageUsageNormal,
+			Message: fmt.Sprintf("Data volume %s is %d%% full", pvcName, percent)})
+		return storagePressureCheckInterval, nil
+	}
+	message := fmt.Sprintf("Data volume %s is %d%% full, above the threshold of %d%%", pvcName, percent, *threshold)
+	if !meta.IsStatusConditionTrue(database.Status.Conditions, typeStoragePressureDatabase) {
+		r.recorder(ctx).Event(database, utils.EventWarning, typeStoragePressureDatabase, message)
+	}
+	meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{Type: typeStoragePressureDatabase,
+		Status: metav1.ConditionTrue, Reason: reasonStorageUsageHigh, Message: message})
+	return storagePressureCheckInterval, nil
+}
@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+)
+
+type mockVolumeStatsReader struct {
+	usage *VolumeUsage
+}
+
+func (reader *mockVolumeStatsReader) GetPVCUsage(context.Context, *corev1.Pod, string) (*VolumeUsage, error) {
+	return reader.usage, nil
+}
+
+var _ = Describe("Database storage pressure", func() {
+	ctx := context.Background()
+
+	It("should set the StoragePressure condition from the usage of the data volume", func() {
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "pressure-database", Namespace: "default"},
+			Spec: libsqlv1.DatabaseSpec{
+				Image:   "ghcr.io/tursodatabase/libsql-server:v0.24.21",
+				Storage: libsqlv1.DatabaseStorage{Size: resource.MustParse("1Gi"), PressureThreshold: ptr.To(int32(85))},
+			},
+		}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: utils.GetDatabasePodName(database, 0), Namespace: database.Namespace},
+			Spec: corev1.PodSpec{
+				NodeName:   "node-a",
+				Containers: []corev1.Container{{Name: utils.DefaultContainerName, Image: database.Spec.Image}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, pod)
+		volumeStats := &mockVolumeStatsReader{usage: &VolumeUsage{UsedBytes: 90, CapacityBytes: 100}}
+		reconciler := &DatabaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: MockEventRecorder{}, VolumeStats: volumeStats}
+
+		checkAfter, err := reconciler.ReconcileDatabaseStoragePressure(ctx, database)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checkAfter).Should(Equal(storagePressureCheckInterval))
+		condition := meta.FindStatusCondition(database.Status.Conditions, typeStoragePressureDatabase)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).Should(Equal(metav1.ConditionTrue))
+		Expect(condition.Message).Should(ContainSubstring("90% full"))
+
+		Expect(database.Status.StorageUsageCheckTime).NotTo(BeNil())
+
+		By("Not reading the usage again before the check interval has passed")
+		volumeStats.usage = &VolumeUsage{UsedBytes: 40, CapacityBytes: 100}
+		checkAfter, err = reconciler.ReconcileDatabaseStoragePressure(ctx, database)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checkAfter).Should(BeNumerically("<=", storagePressureCheckInterval))
+		Expect(meta.IsStatusConditionTrue(database.Status.Conditions, typeStoragePressureDatabase)).Should(BeTrue())
+
+		By("Clearing the condition once the usage is below the threshold")
+		database.Status.StorageUsageCheckTime = &metav1.Time{Time: time.Now().Add(-storagePressureCheckInterval)}
+		_, err = reconciler.ReconcileDatabaseStoragePressure(ctx, database)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(meta.IsStatusConditionFalse(database.Status.Conditions, typeStoragePressureDatabase)).Should(BeTrue())
+
+		By("Removing the condition and the checks without a threshold")
+		database.Spec.Storage.PressureThreshold = nil
+		checkAfter, err = reconciler.ReconcileDatabaseStoragePressure(ctx, database)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checkAfter).Should(BeZero())
+		Expect(meta.FindStatusCondition(database.Status.Conditions, typeStoragePressureDatabase)).Should(BeNil())
+		Expect(database.Status.StorageUsageCheckTime).Should(BeNil())
+	})
+})
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+)
+
+// The prometheus-operator, Istio and VolumeSnapshot CRDs are not installed in the test environment.
+var _ = Describe("Database features of missing CRDs", func() {
+	ctx := context.Background()
+
+	DescribeTable("should report the missing CRDs once through a condition",
+		func(conditionType string, enable func(*libsqlv1.Database), reconcile func(*DatabaseReconciler, *libsqlv1.Database) error) {
+			database := &libsqlv1.Database{ObjectMeta: metav1.ObjectMeta{Name: "unsupported-database", Namespace: "default"}}
+			enable(database)
+			recorder := record.NewFakeRecorder(10)
+			reconciler := &DatabaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: recorder}
+
+			Expect(reconcile(reconciler, database)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(database.Status.Conditions, conditionType)).Should(BeTrue())
+			Expect(recorder.Events).Should(Receive(ContainSubstring(conditionType)))
+
+			By("Reconciling again, nothing is reported")
+			Expect(reconcile(reconciler, database)).To(Succeed())
+			Expect(recorder.Events).ShouldNot(Receive())
+
+			By("Removing the condition once the feature is turned off")
+			database.Spec = libsqlv1.DatabaseSpec{}
+			database.Annotations = nil
+			Expect(reconcile(reconciler, database)).To(Succeed())
+			Expect(meta.FindStatusCondition(database.Status.Conditions, conditionType)).Should(BeNil())
+		},
+		Entry("ServiceMonitor", typeServiceMonitorUnsupported, func(database *libsqlv1.Database) {
+			database.Spec.Monitoring = &libsqlv1.DatabaseMonitoring{MetricsExporter: &libsqlv1.DatabaseMetricsExporter{Image: "exporter"}}
+		}, func(r *DatabaseReconciler, database *libsqlv1.Database) error {
+			return r.ReconcileDatabaseServiceMonitor(ctx, database)
+		}),
+		Entry("PrometheusRule", typePrometheusRuleUnsupported, func(database *libsqlv1.Database) {
+			database.Spec.Monitoring = &libsqlv1.DatabaseMonitoring{PrometheusRule: &libsqlv1.DatabasePrometheusRule{}}
+		}, func(r *DatabaseReconciler, database *libsqlv1.Database) error {
+			return r.ReconcileDatabasePrometheusRule(ctx, database)
+		}),
+		Entry("PeerAuthentication", typePeerAuthenticationUnsupported, func(database *libsqlv1.Database) {
+			database.Spec.Istio = &libsqlv1.DatabaseIstio{PeerAuthentication: &libsqlv1.DatabasePeerAuthentication{Mode: "STRICT"}}
+		}, func(r *DatabaseReconciler, database *libsqlv1.Database) error {
+			return r.ReconcileDatabasePeerAuthentication(ctx, database)
+		}),
+		Entry("VolumeSnapshot", typeVolumeSnapshotUnsupported, func(database *libsqlv1.Database) {
+			database.Annotations = map[string]string{databaseSnapshotAnnotation: "unsupported-snapshot"}
+		}, func(r *DatabaseReconciler, database *libsqlv1.Database) error {
+			return r.ReconcileDatabaseSnapshot(ctx, database)
+		}),
+	)
+})
@@ -0,0 +1,246 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/ahti-database/operator/internal/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ValidateDatabase checks the parts of the Database spec that the CRD schema cannot express.
+// An error means the spec has to be fixed by the user, so it should not be retried.
+func (r *DatabaseReconciler) ValidateDatabase(database *libsqlv1.Database) error {
+	validators := []func(*libsqlv1.Database) error{
+		r.validateDatabaseImage,
+		r.validateDatabaseImagePullPolicy,
+		r.validateDatabaseAuth,
+		r.validateDatabaseStorage,
+		r.validateDatabaseProbe,
+		r.validateDatabaseDrain,
+		r.validateDatabaseService,
+		r.validateDatabaseContainerName,
+		r.validateDatabaseMetricsExporter,
+		r.validateDatabasePodAntiAffinity,
+		r.validateDatabasePodLabels,
+		r.validateDatabasePodTemplateOverrides,
+	}
+	for _, validate := range validators {
+		if err := validate(database); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *DatabaseReconciler) validateDatabaseImage(database *libsqlv1.Database) error {
+	if r.GetDatabaseImage(database) == "" {
+		return fmt.Errorf("spec.image is required because the operator has no default image configured")
+	}
+	return nil
+}
+
+// clearDatabaseInvalidStorageSizeCondition clears the Degraded condition set for a milli unit
+// storage size once the size is fixed.
+func clearDatabaseInvalidStorageSizeCondition(database *libsqlv1.Database) {
+	condition := meta.FindStatusCondition(database.Status.Conditions, typeDegradedDatabase)
+	if condition == nil || condition.Reason != reasonInvalidStorageSize {
+		return
+	}
+	meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{Type: typeDegradedDatabase,
+		Status: metav1.ConditionFalse, Reason: reasonStorageSizeValid, Message: "spec.storage.size is a whole number of bytes"})
+}
+
+// validateDatabaseImagePullPolicy rejects the pull policies of Databases stored before the
+// CRD schema restricted them, e.g. a lowercase "always".
+func (r *DatabaseReconciler) validateDatabaseImagePullPolicy(database *libsqlv1.Database) error {
+	switch database.Spec.ImagePullPolicy {
+	case "", corev1.PullAlways, corev1.PullIfNotPresent, corev1.PullNever:
+		return nil
+	}
+	return fmt.Errorf("spec.imagePullPolicy %q is invalid, it must be one of %s, %s or %s",
+		database.Spec.ImagePullPolicy, corev1.PullAlways, corev1.PullIfNotPresent, corev1.PullNever)
+}
+
+func (r *DatabaseReconciler) validateDatabaseAuth(database *libsqlv1.Database) error {
+	sharedAuthSecretRef := database.Spec.SharedAuthSecretRef
+	if sharedAuthSecretRef == nil {
+		return nil
+	}
+	if database.Spec.ExternalAuthSecret != nil {
+		return fmt.Errorf("spec.sharedAuthSecretRef and spec.externalAuthSecret cannot be set together")
+	}
+	if sharedAuthSecretRef.Name == "" {
+		return fmt.Errorf("spec.sharedAuthSecretRef.name is required")
+	}
+	return nil
+}
+
+func (r *DatabaseReconciler) validateDatabaseStorage(database *libsqlv1.Database) error {
+	size := database.Spec.Storage.Size
+	if size.MilliValue()%1000 != 0 {
+		return &ReconcileError{Reason: reasonInvalidStorageSize,
+			Err: fmt.Errorf("spec.storage.size %q is not a whole number of bytes, use a unit such as Mi or Gi instead of milli units", size.String())}
+	}
+	if database.Spec.Storage.CloneFrom != "" && database.Spec.Storage.RestoreFromSnapshot != "" {
+		return fmt.Errorf("spec.storage.cloneFrom and spec.storage.restoreFromSnapshot cannot be set together")
+	}
+	if database.Spec.Storage.CloneFrom == database.Name && database.Name != "" {
+		return fmt.Errorf("spec.storage.cloneFrom cannot reference the Database itself")
+	}
+	if err := validateDatabaseStorageBinding(database.Spec.Storage); err != nil {
+		return err
+	}
+	if !r.MinStorageSize.IsZero() && size.Cmp(r.MinStorageSize) < 0 {
+		return fmt.Errorf("spec.storage.size %q is smaller than the minimum allowed size %q", size.String(), r.MinStorageSize.String())
+	}
+	return nil
+}
+
+// validateDatabaseStorageBinding checks the binding of the data volume to a pre-provisioned
+// PersistentVolume. A volume bound by name or selector already holds data, so restoring a
+// snapshot into it is not possible.
+func validateDatabaseStorageBinding(storage libsqlv1.DatabaseStorage) error {
+	if storage.VolumeName == "" && storage.Selector == nil {
+		return nil
+	}
+	if storage.VolumeName != "" && storage.Selector != nil {
+		return fmt.Errorf("spec.storage.volumeName and spec.storage.selector cannot be set together")
+	}
+	if storage.RestoreFromSnapshot != "" || storage.CloneFrom != "" {
+		return fmt.Errorf("spec.storage.volumeName and spec.storage.selector cannot be combined with spec.storage.restoreFromSnapshot or spec.storage.cloneFrom")
+	}
+	if storage.VolumeName != "" {
+		if errs := validation.IsDNS1123Subdomain(storage.VolumeName); len(errs) > 0 {
+			return fmt.Errorf("spec.storage.volumeName %q is invalid: %s", storage.VolumeName, strings.Join(errs, ", "))
+		}
+		return nil
+	}
+	if _, err := metav1.LabelSelectorAsSelector(storage.Selector); err != nil {
+		return fmt.Errorf("spec.storage.selector is invalid: %w", err)
+	}
+	if len(storage.Selector.MatchLabels) == 0 && len(storage.Selector.MatchExpressions) == 0 {
+		return fmt.Errorf("spec.storage.selector must match at least one label, an empty selector matches every PersistentVolume")
+	}
+	return nil
+}
+
+func (r *DatabaseReconciler) validateDatabaseProbe(database *libsqlv1.Database) error {
+	probe := database.Spec.Probe
+	if probe != nil && probe.Type == libsqlv1.DatabaseProbeTypeExec && len(probe.Command) == 0 {
+		return fmt.Errorf("spec.probe.command is required when spec.probe.type is %s", libsqlv1.DatabaseProbeTypeExec)
+	}
+	return nil
+}
+
+// validateDatabaseDrain checks that spec.terminationGracePeriodSeconds leaves time to stop the
+// database after the drain period, the pod is killed once it runs out.
+func (r *DatabaseReconciler) validateDatabaseDrain(database *libsqlv1.Database) error {
+	gracePeriod := database.Spec.TerminationGracePeriodSeconds
+	drainPeriod := getDatabaseDrainPeriodSeconds(database)
+	if drainPeriod > 0 && gracePeriod != nil && *gracePeriod <= int64(drainPeriod) {
+		return fmt.Errorf("spec.terminationGracePeriodSeconds %d must be longer than the drain period of %d seconds",
+			*gracePeriod, drainPeriod)
+	}
+	return nil
+}
+
+// validateDatabaseService checks that spec.service.nameOverride is a DNS-1035 label that does not
+// collide with the other Services generated for the Database.
+func (r *DatabaseReconciler) validateDatabaseService(database *libsqlv1.Database) error {
+	if database.Spec.Service == nil || database.Spec.Service.NameOverride == "" {
+		return nil
+	}
+	name := database.Spec.Service.NameOverride
+	if errs := validation.IsDNS1035Label(name); len(errs) > 0 {
+		return fmt.Errorf("spec.service.nameOverride %q is invalid: %s", name, strings.Join(errs, ", "))
+	}
+	for _, generated := range []string{
+		utils.GetDatabaseServiceName(database, true),
+		utils.GetDatabaseGRPCServiceName(database),
+		utils.GetDatabaseMaintenanceName(database),
+	} {
+		if name == generated {
+			return fmt.Errorf("spec.service.nameOverride %q collides with the generated Service %s", name, generated)
+		}
+	}
+	return nil
+}
+
+// validateDatabaseContainerName rejects the name of the metrics exporter sidecar, the pod
+// template would have two containers of the same name.
+func (r *DatabaseReconciler) validateDatabaseContainerName(database *libsqlv1.Database) error {
+	if database.Spec.ContainerName == databaseMetricsExporterContainerName {
+		return fmt.Errorf("spec.containerName %q is reserved for the metrics exporter", database.Spec.ContainerName)
+	}
+	return nil
+}
+
+// validateDatabaseMetricsExporter checks that the metrics exporter does not take a port of the
+// database container, both share the network of the pod.
+func (r *DatabaseReconciler) validateDatabaseMetricsExporter(database *libsqlv1.Database) error {
+	exporter := getDatabaseMetricsExporter(database)
+	if exporter == nil {
+		return nil
+	}
+	port := getDatabaseMetricsExporterPort(exporter)
+	if port == getDatabaseHTTPPort(database) || port == databaseGRPCPort {
+		return fmt.Errorf("spec.monitoring.metricsExporter.port %d collides with a port of the database container", port)
+	}
+	return nil
+}
+
+// validateDatabasePodAntiAffinity checks that a Database with the Tenant scope has the tenant
+// label, the anti-affinity would otherwise match the pods of every Database without it.
+func (r *DatabaseReconciler) validateDatabasePodAntiAffinity(database *libsqlv1.Database) error {
+	antiAffinity := database.Spec.PodAntiAffinity
+	if antiAffinity == nil || antiAffinity.Scope != libsqlv1.DatabaseAntiAffinityScopeTenant {
+		return nil
+	}
+	if antiAffinity.TenantLabel == "" {
+		return fmt.Errorf("spec.podAntiAffinity.tenantLabel is required with the Tenant scope")
+	}
+	if errs := validation.IsQualifiedName(antiAffinity.TenantLabel); len(errs) > 0 {
+		return fmt.Errorf("spec.podAntiAffinity.tenantLabel %q is invalid: %s", antiAffinity.TenantLabel, strings.Join(errs, ", "))
+	}
+	if _, ok := database.Labels[antiAffinity.TenantLabel]; !ok {
+		return fmt.Errorf("the Database has no %s label to select the Databases of its tenant with", antiAffinity.TenantLabel)
+	}
+	return nil
+}
+
+// validateDatabasePodLabels checks that spec.podLabels are valid labels that leave the labels
+// of the operator alone, the selector labels in particular.
+func (r *DatabaseReconciler) validateDatabasePodLabels(database *libsqlv1.Database) error {
+	reserved := r.databaseSelectorLabels(database)
+	for _, key := range []string{appNameLabel, appInstanceLabel, appManagedByLabel, appPartOfLabel} {
+		reserved[key] = ""
+	}
+	for key, value := range database.Spec.PodLabels {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("spec.podLabels key %q is invalid: %s", key, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return fmt.Errorf("spec.podLabels value %q of %s is invalid: %s", value, key, strings.Join(errs, ", "))
+		}
+		if _, ok := reserved[key]; ok {
+			return fmt.Errorf("spec.podLabels cannot set %s, it is generated by the operator", key)
+		}
+	}
+	return nil
+}
+
+// validateDatabasePodTemplateOverrides checks that spec.podTemplateOverrides applies to the
+// generated pod template and keeps the database container and its data volume mount.
+func (r *DatabaseReconciler) validateDatabasePodTemplateOverrides(database *libsqlv1.Database) error {
+	if database.Spec.PodTemplateOverrides == nil {
+		return nil
+	}
+	_, err := r.ConstructDatabaseStatefulSet(context.Background(), database, nil)
+	return err
+}
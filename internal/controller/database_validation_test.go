@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+)
+
+var _ = Describe("Database validation", func() {
+	validDatabaseSpec := func() libsqlv1.DatabaseSpec {
+		return libsqlv1.DatabaseSpec{
+			Image:   "ghcr.io/tursodatabase/libsql-server:v0.24.21",
+			Storage: libsqlv1.DatabaseStorage{Size: resource.MustParse("1Gi")},
+		}
+	}
+
+	DescribeTable("validating the spec",
+		func(mutate func(*libsqlv1.DatabaseSpec), valid bool) {
+			database := &libsqlv1.Database{ObjectMeta: metav1.ObjectMeta{Name: "database"}, Spec: validDatabaseSpec()}
+			mutate(&database.Spec)
+			reconciler := &DatabaseReconciler{}
+			if valid {
+				Expect(reconciler.ValidateDatabase(database)).To(Succeed())
+			} else {
+				Expect(reconciler.ValidateDatabase(database)).NotTo(Succeed())
+			}
+		},
+		Entry("a valid spec", func(*libsqlv1.DatabaseSpec) {}, true),
+		Entry("an empty pull policy", func(spec *libsqlv1.DatabaseSpec) { spec.ImagePullPolicy = "" }, true),
+		Entry("a lowercase pull policy", func(spec *libsqlv1.DatabaseSpec) { spec.ImagePullPolicy = "always" }, false),
+		Entry("a storage size in milli units", func(spec *libsqlv1.DatabaseSpec) {
+			spec.Storage.Size = resource.MustParse("1500m")
+		}, false),
+		Entry("a shared auth secret", func(spec *libsqlv1.DatabaseSpec) {
+			spec.SharedAuthSecretRef = &corev1.LocalObjectReference{Name: "fleet-auth-key"}
+		}, true),
+		Entry("a shared auth secret synced from a secret store", func(spec *libsqlv1.DatabaseSpec) {
+			spec.SharedAuthSecretRef = &corev1.LocalObjectReference{Name: "fleet-auth-key"}
+			spec.ExternalAuthSecret = &libsqlv1.DatabaseExternalAuthSecret{RemoteKey: "databases/fleet"}
+		}, false),
+		Entry("a clone restored from a snapshot", func(spec *libsqlv1.DatabaseSpec) {
+			spec.Storage.CloneFrom = "source"
+			spec.Storage.RestoreFromSnapshot = "snapshot"
+		}, false),
+		Entry("a data volume bound to a PersistentVolume", func(spec *libsqlv1.DatabaseSpec) {
+			spec.Storage.VolumeName = "vm-disk-orders"
+			spec.Storage.StorageClassName = ptr.To("")
+		}, true),
+		Entry("a data volume bound by name and selector", func(spec *libsqlv1.DatabaseSpec) {
+			spec.Storage.VolumeName = "vm-disk-orders"
+			spec.Storage.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"database": "orders"}}
+		}, false),
+		Entry("a data volume bound by selector and restored from a snapshot", func(spec *libsqlv1.DatabaseSpec) {
+			spec.Storage.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"database": "orders"}}
+			spec.Storage.RestoreFromSnapshot = "orders-snapshot"
+		}, false),
+		Entry("a data volume bound by an empty selector", func(spec *libsqlv1.DatabaseSpec) {
+			spec.Storage.Selector = &metav1.LabelSelector{}
+		}, false),
+		Entry("a drain period within the grace period", func(spec *libsqlv1.DatabaseSpec) {
+			spec.Drain = &libsqlv1.DatabaseDrain{PeriodSeconds: 20}
+			spec.TerminationGracePeriodSeconds = ptr.To(int64(60))
+		}, true),
+		Entry("a drain period outlasting the grace period", func(spec *libsqlv1.DatabaseSpec) {
+			spec.Drain = &libsqlv1.DatabaseDrain{}
+			spec.TerminationGracePeriodSeconds = ptr.To(int64(10))
+		}, false),
+		Entry("a mesh routing pod label", func(spec *libsqlv1.DatabaseSpec) {
+			spec.PodLabels = map[string]string{"version": "v2"}
+		}, true),
+		Entry("a pod label overriding the selector", func(spec *libsqlv1.DatabaseSpec) {
+			spec.PodLabels = map[string]string{"node": "replica"}
+		}, false),
+		Entry("an exec probe without command", func(spec *libsqlv1.DatabaseSpec) {
+			spec.Probe = &libsqlv1.DatabaseProbe{Type: libsqlv1.DatabaseProbeTypeExec}
+		}, false),
+		Entry("a service name override", func(spec *libsqlv1.DatabaseSpec) {
+			spec.Service = &libsqlv1.DatabaseServiceSpec{NameOverride: "legacy-database"}
+		}, true),
+		Entry("a service name override that is not a DNS-1035 label", func(spec *libsqlv1.DatabaseSpec) {
+			spec.Service = &libsqlv1.DatabaseServiceSpec{NameOverride: "1-database"}
+		}, false),
+		Entry("a service name override colliding with the headless service", func(spec *libsqlv1.DatabaseSpec) {
+			spec.Service = &libsqlv1.DatabaseServiceSpec{NameOverride: "database-svc-headless"}
+		}, false),
+		Entry("a container named after the metrics exporter", func(spec *libsqlv1.DatabaseSpec) {
+			spec.ContainerName = "metrics-exporter"
+		}, false),
+		Entry("a metrics exporter on its own port", func(spec *libsqlv1.DatabaseSpec) {
+			spec.Monitoring = &libsqlv1.DatabaseMonitoring{MetricsExporter: &libsqlv1.DatabaseMetricsExporter{Image: "exporter"}}
+		}, true),
+		Entry("a metrics exporter on the HTTP port", func(spec *libsqlv1.DatabaseSpec) {
+			spec.Monitoring = &libsqlv1.DatabaseMonitoring{MetricsExporter: &libsqlv1.DatabaseMetricsExporter{Image: "exporter", Port: 8080}}
+		}, false),
+		Entry("pod template overrides as a strategic merge patch", func(spec *libsqlv1.DatabaseSpec) {
+			spec.PodTemplateOverrides = &runtime.RawExtension{
+				Raw: []byte(`{"spec":{"containers":[{"name":"libsql-server","stdin":true}]}}`),
+			}
+		}, true),
+		Entry("pod template overrides as a JSON patch", func(spec *libsqlv1.DatabaseSpec) {
+			spec.PodTemplateOverrides = &runtime.RawExtension{
+				Raw: []byte(`[{"op":"add","path":"/spec/hostname","value":"database"}]`),
+			}
+		}, true),
+		Entry("pod template overrides removing the database container", func(spec *libsqlv1.DatabaseSpec) {
+			spec.PodTemplateOverrides = &runtime.RawExtension{
+				Raw: []byte(`{"spec":{"containers":[{"name":"libsql-server","$patch":"delete"}]}}`),
+			}
+		}, false),
+		Entry("pod template overrides removing the data volume mount", func(spec *libsqlv1.DatabaseSpec) {
+			spec.PodTemplateOverrides = &runtime.RawExtension{
+				Raw: []byte(`[{"op":"remove","path":"/spec/containers/0/volumeMounts"}]`),
+			}
+		}, false),
+	)
+
+	It("should reject an invalid pull policy at admission", func() {
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "invalid-pull-policy", Namespace: "default"},
+			Spec:       validDatabaseSpec(),
+		}
+		database.Spec.ImagePullPolicy = corev1.PullPolicy("always")
+		err := k8sClient.Create(context.Background(), database)
+		Expect(errors.IsInvalid(err)).Should(BeTrue())
+	})
+
+	It("should reject a container name that is not a DNS-1123 label at admission", func() {
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "invalid-container-name", Namespace: "default"},
+			Spec:       validDatabaseSpec(),
+		}
+		database.Spec.ContainerName = "libsql_server"
+		err := k8sClient.Create(context.Background(), database)
+		Expect(errors.IsInvalid(err)).Should(BeTrue())
+	})
+
+	It("should mark a Database with a milli unit storage size Degraded without creating it", func() {
+		ctx := context.Background()
+		database := &libsqlv1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "milli-storage", Namespace: "default"},
+			Spec:       validDatabaseSpec(),
+		}
+		database.Spec.Storage.Size = resource.MustParse("500m")
+		Expect(k8sClient.Create(ctx, database)).To(Succeed())
+		namespacedName := types.NamespacedName{Name: database.Name, Namespace: database.Namespace}
+
+		reconciler := &DatabaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: MockEventRecorder{}}
+		for i := 0; i < 2; i++ {
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: namespacedName})
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(k8sClient.Get(ctx, namespacedName, database)).To(Succeed())
+		degraded := meta.FindStatusCondition(database.Status.Conditions, typeDegradedDatabase)
+		Expect(degraded).NotTo(BeNil())
+		Expect(degraded.Status).Should(Equal(metav1.ConditionTrue))
+		Expect(degraded.Reason).Should(Equal(reasonInvalidStorageSize))
+		Expect(database.Status.Phase).Should(Equal(libsqlv1.DatabasePhaseDegraded))
+		Expect(errors.IsNotFound(k8sClient.Get(ctx, namespacedName, &appsv1.StatefulSet{}))).Should(BeTrue())
+
+		controllerutil.RemoveFinalizer(database, reconciler.GetFinalizerName())
+		Expect(k8sClient.Update(ctx, database)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, database)).To(Succeed())
+	})
+})
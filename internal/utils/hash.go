@@ -0,0 +1,13 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// HashValue returns a short hex encoded sha256 of value, to detect changes of values that
+// cannot be stored as is, e.g. secrets in pod template annotations.
+func HashValue(value []byte) string {
+	sum := sha256.Sum256(value)
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// defaultRegistry is the registry of images that do not name one
+const defaultRegistry = "docker.io"
+
+// ParseImageRegistryRewrites parses comma separated from=to registry prefix rewrites.
+func ParseImageRegistryRewrites(value string) (map[string]string, error) {
+	rewrites := map[string]string{}
+	for _, rule := range strings.Split(value, ",") {
+		if rule = strings.TrimSpace(rule); rule == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(rule, "=")
+		from, to = strings.TrimSuffix(strings.TrimSpace(from), "/"), strings.TrimSuffix(strings.TrimSpace(to), "/")
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid image registry rewrite %q, expected <from>=<to>", rule)
+		}
+		rewrites[from] = to
+	}
+	return rewrites, nil
+}
+
+// RewriteImageRegistry replaces the longest prefix of image that has a rewrite. Prefixes match
+// whole path components, e.g. a rewrite of ghcr.io/tursodatabase applies to
+// ghcr.io/tursodatabase/libsql-server:latest but not to ghcr.io/tursodatabase-fork/libsql-server.
+// Images that do not name a registry match rewrites of docker.io.
+func RewriteImageRegistry(image string, rewrites map[string]string) string {
+	if len(rewrites) == 0 {
+		return image
+	}
+	named := image
+	if !hasRegistry(image) {
+		named = defaultRegistry + "/" + image
+	}
+	from := ""
+	for prefix := range rewrites {
+		if (named == prefix || strings.HasPrefix(named, prefix+"/")) && len(prefix) > len(from) {
+			from = prefix
+		}
+	}
+	if from == "" {
+		return image
+	}
+	return rewrites[from] + strings.TrimPrefix(named, from)
+}
+
+// hasRegistry reports whether the first component of image is a registry host, following the
+// rules of the docker reference format.
+func hasRegistry(image string) bool {
+	first, _, found := strings.Cut(image, "/")
+	if !found {
+		return false
+	}
+	return strings.ContainsAny(first, ".:") || first == "localhost"
+}
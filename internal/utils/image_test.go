@@ -0,0 +1,30 @@
+package utils
+
+import "testing"
+
+func TestRewriteImageRegistry(t *testing.T) {
+	rewrites, err := ParseImageRegistryRewrites("ghcr.io=mirror.ahti.io/ghcr, ghcr.io/tursodatabase=mirror.ahti.io/turso/,docker.io=mirror.ahti.io/hub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for image, expected := range map[string]string{
+		"ghcr.io/tursodatabase/libsql-server:v0.24.21": "mirror.ahti.io/turso/libsql-server:v0.24.21",
+		"ghcr.io/ahti-database/operator:latest":        "mirror.ahti.io/ghcr/ahti-database/operator:latest",
+		"ghcr.io.example.com/libsql-server":            "ghcr.io.example.com/libsql-server",
+		"hashicorp/http-echo:1.0":                      "mirror.ahti.io/hub/hashicorp/http-echo:1.0",
+		"docker.io/curlimages/curl:8.7.1":              "mirror.ahti.io/hub/curlimages/curl:8.7.1",
+		"localhost:5000/libsql-server":                 "localhost:5000/libsql-server",
+	} {
+		if rewritten := RewriteImageRegistry(image, rewrites); rewritten != expected {
+			t.Errorf("RewriteImageRegistry(%q) = %q, expected %q", image, rewritten, expected)
+		}
+	}
+}
+
+func TestParseImageRegistryRewritesRejectsInvalidRules(t *testing.T) {
+	for _, value := range []string{"ghcr.io", "=mirror.ahti.io", "ghcr.io="} {
+		if _, err := ParseImageRegistryRewrites(value); err == nil {
+			t.Errorf("ParseImageRegistryRewrites(%q) succeeded, expected an error", value)
+		}
+	}
+}
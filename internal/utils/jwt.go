@@ -3,7 +3,13 @@ package utils
 import (
 	"crypto/ed25519"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
 
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -13,8 +19,75 @@ func GenerateAsymmetricKeys() (ed25519.PublicKey, ed25519.PrivateKey, error) {
 	return publicKey, privateKey, err
 }
 
-func GenerateJWT(key ed25519.PrivateKey) (string, error) {
-	t := jwt.New(jwt.SigningMethodEdDSA)
+// ParsePrivateKey decodes a PRIVATE_KEY value of the auth secret back into an ed25519 key.
+func ParsePrivateKey(encoded []byte) (ed25519.PrivateKey, error) {
+	key, err := base64.URLEncoding.WithPadding(base64.NoPadding).DecodeString(string(encoded))
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid ed25519 private key size %d", len(key))
+	}
+	return ed25519.PrivateKey(key), nil
+}
+
+// ParsePublicKey decodes a PUBLIC_KEY value of the auth secret back into an ed25519 key.
+func ParsePublicKey(encoded []byte) (ed25519.PublicKey, error) {
+	key, err := base64.URLEncoding.WithPadding(base64.NoPadding).DecodeString(string(encoded))
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid ed25519 public key size %d", len(key))
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// NewJWKS returns the JSON Web Key Set (RFC 8037) of the public key tokens are verified with.
+// The key ID is the RFC 7638 thumbprint of the key, so it changes along with the key.
+func NewJWKS(key ed25519.PublicKey) ([]byte, error) {
+	x := base64.URLEncoding.WithPadding(base64.NoPadding).EncodeToString(key)
+	// the thumbprint hashes the required members in lexicographic order without whitespace
+	thumbprint := sha256.Sum256([]byte(fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":"%s"}`, x)))
+	return json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"x":   x,
+			"kid": base64.URLEncoding.WithPadding(base64.NoPadding).EncodeToString(thumbprint[:]),
+			"alg": jwt.SigningMethodEdDSA.Alg(),
+			"use": "sig",
+		}},
+	})
+}
+
+// NewJWTClaims builds the claims of a token minted for the Database from its spec.tokenClaims.
+func NewJWTClaims(database *libsqlv1.Database) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{
+		"iat": time.Now().Unix(),
+	}
+	tokenClaims := database.Spec.TokenClaims
+	if tokenClaims == nil {
+		return claims, nil
+	}
+	if tokenClaims.Issuer != "" {
+		claims["iss"] = tokenClaims.Issuer
+	}
+	if len(tokenClaims.Audience) > 0 {
+		claims["aud"] = tokenClaims.Audience
+	}
+	if tokenClaims.Permissions != nil {
+		var permissions interface{}
+		if err := json.Unmarshal(tokenClaims.Permissions.Raw, &permissions); err != nil {
+			return nil, err
+		}
+		claims["p"] = permissions
+	}
+	return claims, nil
+}
+
+func GenerateJWT(key ed25519.PrivateKey, claims jwt.Claims) (string, error) {
+	t := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
 	jwt, err := t.SignedString(key)
 	return jwt, err
 }
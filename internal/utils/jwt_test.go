@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	libsqlv1 "github.com/ahti-database/operator/api/v1"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestGenerateJWTVerifiesAgainstPublicKey(t *testing.T) {
+	publicKey, privateKey, err := GenerateAsymmetricKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	database := &libsqlv1.Database{Spec: libsqlv1.DatabaseSpec{
+		TokenClaims: &libsqlv1.DatabaseTokenClaims{Issuer: "ahti-operator", Audience: []string{"sqld"}},
+	}}
+	claims, err := NewJWTClaims(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := GenerateJWT(privateKey, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := jwt.Parse(token, func(*jwt.Token) (interface{}, error) {
+		return publicKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}), jwt.WithAudience("sqld"), jwt.WithIssuer("ahti-operator"))
+	if err != nil {
+		t.Fatalf("token does not verify against the public key: %v", err)
+	}
+	if !parsed.Valid {
+		t.Fatal("token is not valid")
+	}
+
+	otherPublicKey, _, err := GenerateAsymmetricKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwt.Parse(token, func(*jwt.Token) (interface{}, error) {
+		return otherPublicKey, nil
+	}); err == nil {
+		t.Fatal("token verifies against an unrelated public key")
+	}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	publicKey, privateKey, err := GenerateAsymmetricKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := base64.URLEncoding.WithPadding(base64.NoPadding).EncodeToString(privateKey)
+	parsed, err := ParsePrivateKey([]byte(encoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.Public().(ed25519.PublicKey).Equal(publicKey) {
+		t.Fatal("parsed private key does not match the public key")
+	}
+	if _, err := ParsePrivateKey([]byte("dG9vLXNob3J0")); err == nil {
+		t.Fatal("expected an error for a short key")
+	}
+}
+
+func TestNewJWKS(t *testing.T) {
+	publicKey, _, err := GenerateAsymmetricKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := base64.URLEncoding.WithPadding(base64.NoPadding).EncodeToString(publicKey)
+	parsed, err := ParsePublicKey([]byte(encoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks, err := NewJWKS(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keySet := struct {
+		Keys []map[string]string `json:"keys"`
+	}{}
+	if err := json.Unmarshal(jwks, &keySet); err != nil {
+		t.Fatal(err)
+	}
+	if len(keySet.Keys) != 1 {
+		t.Fatalf("expected a single key, got %d", len(keySet.Keys))
+	}
+	key := keySet.Keys[0]
+	if key["kty"] != "OKP" || key["crv"] != "Ed25519" || key["alg"] != "EdDSA" || key["x"] != encoded {
+		t.Fatalf("unexpected key %v", key)
+	}
+	if key["kid"] == "" {
+		t.Fatal("expected a key ID")
+	}
+
+	otherPublicKey, _, err := GenerateAsymmetricKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherJWKS, err := NewJWKS(otherPublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(otherJWKS) == string(jwks) {
+		t.Fatal("expected the key set to change along with the key")
+	}
+	if _, err := ParsePublicKey([]byte("dG9vLXNob3J0")); err == nil {
+		t.Fatal("expected an error for a short key")
+	}
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// MergeLabels returns a new map holding the entries of all the given maps.
+// Later maps take precedence, so managed labels should be passed last.
+func MergeLabels(labels ...map[string]string) map[string]string {
+	merged := map[string]string{}
+	for _, l := range labels {
+		for key, value := range l {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// ReplaceAnnotations returns the annotations of an existing object with the managed keys
+// replaced by desired, keeping the annotations other controllers or users have set.
+// Managed keys that are missing from desired are removed.
+func ReplaceAnnotations(existing map[string]string, managed []string, desired map[string]string) map[string]string {
+	replaced := MergeLabels(existing)
+	for _, key := range managed {
+		delete(replaced, key)
+	}
+	return MergeLabels(replaced, desired)
+}
+
+// ParseLabelMappings parses comma separated <namespace label>[=<resource label>] mappings of the
+// labels of a namespace to the labels set on the resources generated in it. A label without
+// target keeps its key.
+func ParseLabelMappings(value string) (map[string]string, error) {
+	mappings := map[string]string{}
+	for _, rule := range strings.Split(value, ",") {
+		if rule = strings.TrimSpace(rule); rule == "" {
+			continue
+		}
+		from, to, hasTarget := strings.Cut(rule, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !hasTarget {
+			to = from
+		}
+		for _, key := range []string{from, to} {
+			if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+				return nil, fmt.Errorf("invalid label mapping %q, %q is not a label key: %s", rule, key, strings.Join(errs, ", "))
+			}
+		}
+		mappings[from] = to
+	}
+	return mappings, nil
+}
+
+// MapLabels returns the labels of mappings found in labels, under their mapped keys.
+func MapLabels(labels map[string]string, mappings map[string]string) map[string]string {
+	mapped := map[string]string{}
+	for from, to := range mappings {
+		if value, ok := labels[from]; ok {
+			mapped[to] = value
+		}
+	}
+	return mapped
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLabelMappings(t *testing.T) {
+	mappings, err := ParseLabelMappings("team, cost-center=billing.ahti.io/cost-center")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"team": "team", "cost-center": "billing.ahti.io/cost-center"}
+	if !reflect.DeepEqual(mappings, expected) {
+		t.Errorf("ParseLabelMappings() = %v, expected %v", mappings, expected)
+	}
+	for _, value := range []string{"team=", "=team", "cost center"} {
+		if _, err := ParseLabelMappings(value); err == nil {
+			t.Errorf("ParseLabelMappings(%q) succeeded, expected an error", value)
+		}
+	}
+}
+
+func TestMapLabels(t *testing.T) {
+	mapped := MapLabels(
+		map[string]string{"team": "storage", "kubernetes.io/metadata.name": "databases"},
+		map[string]string{"team": "ahti.io/team", "cost-center": "ahti.io/cost-center"},
+	)
+	expected := map[string]string{"ahti.io/team": "storage"}
+	if !reflect.DeepEqual(mapped, expected) {
+		t.Errorf("MapLabels() = %v, expected %v", mapped, expected)
+	}
+}
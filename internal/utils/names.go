@@ -9,23 +9,119 @@ import (
 const (
 	EventNormal  string = "Normal"
 	EventWarning string = "Warning"
+
+	DefaultContainerName string = "libsql-server"
+	// ClusterDomain is the DNS domain of the cluster services are resolved in
+	ClusterDomain string = "cluster.local"
 )
 
+func GetDatabaseContainerName(database *libsqlv1.Database) string {
+	if database.Spec.ContainerName == "" {
+		return DefaultContainerName
+	}
+	return database.Spec.ContainerName
+}
+
 func GetAuthSecretName(database *libsqlv1.Database) string {
 	return fmt.Sprintf("%v-auth-key", database.Name)
 }
 
+func GetAuthPublicKeySecretName(database *libsqlv1.Database) string {
+	return fmt.Sprintf("%v-auth-public-key", database.Name)
+}
+
+// GetAuthPublicKeySecretRef returns the name of the secret the database pods read
+// PUBLIC_KEY from.
+func GetAuthPublicKeySecretRef(database *libsqlv1.Database) string {
+	if database.Spec.SeparatePublicKeySecret {
+		return GetAuthPublicKeySecretName(database)
+	}
+	if database.Spec.SharedAuthSecretRef != nil {
+		return database.Spec.SharedAuthSecretRef.Name
+	}
+	return GetAuthSecretName(database)
+}
+
+// GetDatabasePVCName returns the name of the volume claim template of the StatefulSet, which is
+// also the name of the data volume in the pod spec. It is not the name of the PVCs themselves,
+// see GetDatabaseStatefulSetPVCName.
 func GetDatabasePVCName(database *libsqlv1.Database) string {
 	return fmt.Sprintf("%v-pvc", database.Name)
 }
 
+// GetDatabasePodName returns the name of the pod of the StatefulSet with the given ordinal.
+func GetDatabasePodName(database *libsqlv1.Database, ordinal int) string {
+	return fmt.Sprintf("%v-%d", database.Name, ordinal)
+}
+
+// GetDatabaseStatefulSetPVCName returns the name of the PVC the StatefulSet creates from its
+// volume claim template for the pod with the given ordinal.
+func GetDatabaseStatefulSetPVCName(database *libsqlv1.Database, ordinal int) string {
+	return fmt.Sprintf("%v-%v-%d", GetDatabasePVCName(database), database.Name, ordinal)
+}
+
+// GetDatabaseServiceName returns the name of the headless service or of the service of the
+// database, which can be overridden with spec.service.nameOverride.
 func GetDatabaseServiceName(database *libsqlv1.Database, headless bool) string {
 	if headless {
 		return fmt.Sprintf("%v-svc-headless", database.Name)
 	}
+	if database.Spec.Service != nil && database.Spec.Service.NameOverride != "" {
+		return database.Spec.Service.NameOverride
+	}
 	return fmt.Sprintf("%v-svc", database.Name)
 }
 
+// GetDatabaseGRPCServiceName returns the name of the separate gRPC service of the database.
+func GetDatabaseGRPCServiceName(database *libsqlv1.Database) string {
+	return fmt.Sprintf("%v-svc-grpc", database.Name)
+}
+
+// GetDatabaseServiceFQDN returns the fully qualified domain name of the service of the database.
+func GetDatabaseServiceFQDN(database *libsqlv1.Database, headless bool) string {
+	return fmt.Sprintf("%v.%v.svc.%v", GetDatabaseServiceName(database, headless), database.Namespace, ClusterDomain)
+}
+
 func GetDatabaseIngressName(database *libsqlv1.Database) string {
 	return fmt.Sprintf("%v-ingress", database.Name)
 }
+
+// GetDatabaseAdditionalIngressName returns the name of the Ingress of an entry of
+// spec.additionalIngresses.
+func GetDatabaseAdditionalIngressName(database *libsqlv1.Database, name string) string {
+	return fmt.Sprintf("%v-ingress-%v", database.Name, name)
+}
+
+func GetDatabasePrometheusRuleName(database *libsqlv1.Database) string {
+	return fmt.Sprintf("%v-rules", database.Name)
+}
+
+func GetDatabaseServiceMonitorName(database *libsqlv1.Database) string {
+	return fmt.Sprintf("%v-metrics", database.Name)
+}
+
+func GetDatabaseGrafanaDashboardName(database *libsqlv1.Database) string {
+	return fmt.Sprintf("%v-dashboard", database.Name)
+}
+
+func GetDatabaseConnectionConfigMapName(database *libsqlv1.Database) string {
+	return fmt.Sprintf("%v-connection", database.Name)
+}
+
+func GetDatabaseJWKSConfigMapName(database *libsqlv1.Database) string {
+	return fmt.Sprintf("%v-jwks", database.Name)
+}
+
+// GetDatabaseCloneSnapshotName returns the name of the VolumeSnapshot a Database created with
+// spec.storage.cloneFrom is restored from.
+func GetDatabaseCloneSnapshotName(database *libsqlv1.Database) string {
+	return fmt.Sprintf("%v-clone", database.Name)
+}
+
+func GetDatabaseMaintenanceName(database *libsqlv1.Database) string {
+	return fmt.Sprintf("%v-maintenance", database.Name)
+}
+
+func GetDatabaseInitSQLName(database *libsqlv1.Database) string {
+	return fmt.Sprintf("%v-init-sql", database.Name)
+}
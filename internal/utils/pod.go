@@ -0,0 +1,16 @@
+package utils
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// GetContainer looks up a container of the pod spec by name, so callers do not
+// depend on the order of containers. It returns nil if no container matches.
+func GetContainer(podSpec *corev1.PodSpec, name string) *corev1.Container {
+	for i := range podSpec.Containers {
+		if podSpec.Containers[i].Name == name {
+			return &podSpec.Containers[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ParseTolerations parses comma separated tolerations in the taint syntax of kubectl,
+// key[=value][:effect]. A toleration without value tolerates every value of the key and one
+// without effect tolerates every effect.
+func ParseTolerations(value string) ([]corev1.Toleration, error) {
+	tolerations := []corev1.Toleration{}
+	for _, rule := range strings.Split(value, ",") {
+		if rule = strings.TrimSpace(rule); rule == "" {
+			continue
+		}
+		toleration := corev1.Toleration{Operator: corev1.TolerationOpExists}
+		taint, effect, _ := strings.Cut(rule, ":")
+		key, value, hasValue := strings.Cut(taint, "=")
+		toleration.Key = key
+		if hasValue {
+			toleration.Operator = corev1.TolerationOpEqual
+			toleration.Value = value
+		}
+		switch taintEffect := corev1.TaintEffect(effect); taintEffect {
+		case "", corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+			toleration.Effect = taintEffect
+		default:
+			return nil, fmt.Errorf("invalid toleration %q, unknown effect %s", rule, effect)
+		}
+		if key == "" {
+			return nil, fmt.Errorf("invalid toleration %q, expected key[=value][:effect]", rule)
+		}
+		tolerations = append(tolerations, toleration)
+	}
+	return tolerations, nil
+}
+
+// MergeTolerations returns the tolerations followed by the defaults that do not share the key
+// and effect of one of them, a toleration of the same taint overrides its default.
+func MergeTolerations(tolerations []corev1.Toleration, defaults []corev1.Toleration) []corev1.Toleration {
+	merged := append([]corev1.Toleration{}, tolerations...)
+	for _, toleration := range defaults {
+		if !slices.ContainsFunc(tolerations, func(override corev1.Toleration) bool {
+			return override.Key == toleration.Key && override.Effect == toleration.Effect
+		}) {
+			merged = append(merged, toleration)
+		}
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
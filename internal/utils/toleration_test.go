@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestParseTolerations(t *testing.T) {
+	tolerations, err := ParseTolerations("dedicated=databases:NoSchedule, node.ahti.io/pool, spot:NoExecute")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []corev1.Toleration{
+		{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "databases", Effect: corev1.TaintEffectNoSchedule},
+		{Key: "node.ahti.io/pool", Operator: corev1.TolerationOpExists},
+		{Key: "spot", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute},
+	}
+	if !reflect.DeepEqual(tolerations, expected) {
+		t.Errorf("ParseTolerations() = %v, expected %v", tolerations, expected)
+	}
+}
+
+func TestParseTolerationsRejectsInvalidRules(t *testing.T) {
+	for _, value := range []string{"=databases", "dedicated:NoShedule", ":NoSchedule"} {
+		if _, err := ParseTolerations(value); err == nil {
+			t.Errorf("ParseTolerations(%q) succeeded, expected an error", value)
+		}
+	}
+}
+
+func TestMergeTolerations(t *testing.T) {
+	defaults := []corev1.Toleration{
+		{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "databases", Effect: corev1.TaintEffectNoSchedule},
+		{Key: "spot", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute},
+	}
+	override := corev1.Toleration{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "analytics", Effect: corev1.TaintEffectNoSchedule}
+	merged := MergeTolerations([]corev1.Toleration{override}, defaults)
+	expected := []corev1.Toleration{override, defaults[1]}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("MergeTolerations() = %v, expected %v", merged, expected)
+	}
+	if merged := MergeTolerations(nil, nil); merged != nil {
+		t.Errorf("MergeTolerations(nil, nil) = %v, expected nil", merged)
+	}
+}
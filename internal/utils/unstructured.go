@@ -0,0 +1,35 @@
+package utils
+
+import "k8s.io/apimachinery/pkg/api/equality"
+
+// ContainsFields reports whether every field of desired is set to the same value in found.
+// Fields only present in found, like the ones defaulted by the API server, are ignored, so
+// desired and found unstructured content only differ when the desired state changed.
+func ContainsFields(found, desired interface{}) bool {
+	switch desired := desired.(type) {
+	case map[string]interface{}:
+		found, ok := found.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, value := range desired {
+			if !ContainsFields(found[key], value) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		found, ok := found.([]interface{})
+		if !ok || len(found) != len(desired) {
+			return false
+		}
+		for i := range desired {
+			if !ContainsFields(found[i], desired[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return equality.Semantic.DeepEqual(found, desired)
+	}
+}